@@ -17,6 +17,7 @@ import (
 	"os/signal"
 	"path/filepath"
 	"regexp"
+	"sort"
 	"strings"
 	"sync"
 	"syscall"
@@ -26,14 +27,20 @@ import (
 	"github.com/caffix/stringset"
 	"github.com/fatih/color"
 	"github.com/owasp-amass/amass/v4/datasrcs"
+	"github.com/owasp-amass/amass/v4/datasrcs/scripting"
 	"github.com/owasp-amass/amass/v4/enum"
 	"github.com/owasp-amass/amass/v4/format"
+	amasshttp "github.com/owasp-amass/amass/v4/net/http"
 	"github.com/owasp-amass/amass/v4/resources"
 	"github.com/owasp-amass/amass/v4/systems"
 	"github.com/owasp-amass/config/config"
 )
 
-const enumUsageMsg = "enum [options] -d DOMAIN"
+const (
+	enumUsageMsg = "enum [options] -d DOMAIN"
+	// Number of minutes the responses are kept for conditional requests by default
+	defaultResponseCacheTTL = 60
+)
 
 type enumArgs struct {
 	Addresses         format.ParseIPs
@@ -46,9 +53,12 @@ type enumArgs struct {
 	Blacklist         *stringset.Set
 	Domains           *stringset.Set
 	Excluded          *stringset.Set
+	GraphTypes        format.ParseStrings
 	Included          *stringset.Set
 	Interface         string
+	MaxBodySize       int
 	MaxDNSQueries     int
+	MaxDownloadRate   int
 	ResolverQPS       int
 	TrustedQPS        int
 	MaxDepth          int
@@ -56,14 +66,18 @@ type enumArgs struct {
 	Names             *stringset.Set
 	Ports             format.ParseInts
 	Resolvers         *stringset.Set
+	StoreQueueSize    int
 	Trusted           *stringset.Set
 	Timeout           int
 	Options           struct {
 		Active       bool
+		ApexFirst    bool
 		Alterations  bool
 		BruteForcing bool
 		DemoMode     bool
+		DryRun       bool
 		ListSources  bool
+		Malformed    bool
 		NoAlts       bool
 		NoColor      bool
 		NoRecursive  bool
@@ -80,6 +94,7 @@ type enumArgs struct {
 		Directory        string
 		Domains          format.ParseStrings
 		ExcludedSrcs     string
+		Graph            string
 		IncludedSrcs     string
 		JSONOutput       string
 		LogFile          string
@@ -87,6 +102,7 @@ type enumArgs struct {
 		Resolvers        format.ParseStrings
 		Trusted          format.ParseStrings
 		ScriptsDirectory string
+		SubfinderConfig  string
 		TermOut          string
 	}
 }
@@ -99,26 +115,33 @@ func defineEnumArgumentFlags(enumFlags *flag.FlagSet, args *enumArgs) {
 	enumFlags.Var(args.Blacklist, "bl", "Blacklist of subdomain names that will not be investigated")
 	enumFlags.Var(args.BruteWordListMask, "wm", "\"hashcat-style\" wordlist masks for DNS brute forcing")
 	enumFlags.Var(args.Domains, "d", "Domain names separated by commas (can be used multiple times)")
-	enumFlags.Var(args.Excluded, "exclude", "Data source names separated by commas to be excluded")
-	enumFlags.Var(args.Included, "include", "Data source names separated by commas to be included")
+	enumFlags.Var(args.Excluded, "exclude", "Data source names or types separated by commas to be excluded")
+	enumFlags.Var(&args.GraphTypes, "graph-types", "Asset types separated by commas to be exported with the graph")
+	enumFlags.Var(args.Included, "include", "Data source names or types separated by commas to be included")
 	enumFlags.StringVar(&args.Interface, "iface", "", "Provide the network interface to send traffic through")
 	enumFlags.IntVar(&args.MaxDNSQueries, "max-dns-queries", 0, "Deprecated flag to be replaced by dns-qps in version 4.0")
 	enumFlags.IntVar(&args.MaxDNSQueries, "dns-qps", 0, "Maximum number of DNS queries per second across all resolvers")
 	enumFlags.IntVar(&args.ResolverQPS, "rqps", 0, "Maximum number of DNS queries per second for each untrusted resolver")
 	enumFlags.IntVar(&args.TrustedQPS, "trqps", 0, "Maximum number of DNS queries per second for each trusted resolver")
+	enumFlags.IntVar(&args.MaxBodySize, "max-body-size", 0, "Maximum number of kilobytes read from a data source response (Default: 51200)")
+	enumFlags.IntVar(&args.MaxDownloadRate, "max-download-rate", 0, "Maximum number of kilobytes per second downloaded across all data sources")
 	enumFlags.IntVar(&args.MaxDepth, "max-depth", 0, "Maximum number of subdomain labels for brute forcing")
 	enumFlags.IntVar(&args.MinForRecursive, "min-for-recursive", 1, "Subdomain labels seen before recursive brute forcing (Default: 1)")
 	enumFlags.Var(&args.Ports, "p", "Ports separated by commas (default: 80, 443)")
 	enumFlags.Var(args.Resolvers, "r", "IP addresses of untrusted DNS resolvers (can be used multiple times)")
 	enumFlags.Var(args.Resolvers, "tr", "IP addresses of trusted DNS resolvers (can be used multiple times)")
+	enumFlags.IntVar(&args.StoreQueueSize, "store-queue", 0, "Maximum number of address lookups waiting to be stored (Default: unbounded)")
 	enumFlags.IntVar(&args.Timeout, "timeout", 0, "Number of minutes to let enumeration run before quitting")
 }
 
 func defineEnumOptionFlags(enumFlags *flag.FlagSet, args *enumArgs) {
 	enumFlags.BoolVar(&args.Options.Active, "active", false, "Attempt zone transfers and certificate name grabs")
+	enumFlags.BoolVar(&args.Options.ApexFirst, "apex-first", false, "Process the root domains and their immediate subdomains before deeper names")
 	enumFlags.BoolVar(&args.Options.BruteForcing, "brute", false, "Execute brute forcing after searches")
 	enumFlags.BoolVar(&args.Options.DemoMode, "demo", false, "Censor output to make it suitable for demonstrations")
+	enumFlags.BoolVar(&args.Options.DryRun, "dry-run", false, "Log the data source requests without sending them")
 	enumFlags.BoolVar(&args.Options.ListSources, "list", false, "Print the names of all available data sources")
+	enumFlags.BoolVar(&args.Options.Malformed, "allow-malformed", false, "Store the names that fail the hostname validation")
 	enumFlags.BoolVar(&args.Options.Alterations, "alts", false, "Enable generation of altered names")
 	enumFlags.BoolVar(&args.Options.NoColor, "nocolor", false, "Disable colorized output")
 	enumFlags.BoolVar(&args.Options.NoRecursive, "norecursive", false, "Turn off recursive brute forcing")
@@ -136,12 +159,15 @@ func defineEnumFilepathFlags(enumFlags *flag.FlagSet, args *enumArgs) {
 	enumFlags.StringVar(&args.Filepaths.Directory, "dir", "", "Path to the directory containing the output files")
 	enumFlags.Var(&args.Filepaths.Domains, "df", "Path to a file providing root domain names")
 	enumFlags.StringVar(&args.Filepaths.ExcludedSrcs, "ef", "", "Path to a file providing data sources to exclude")
+	enumFlags.StringVar(&args.Filepaths.Graph, "graph", "", "Path to the DOT (.dot, .gv), GraphML (.graphml) or Maltego CSV (.csv) file exporting the graph")
 	enumFlags.StringVar(&args.Filepaths.IncludedSrcs, "if", "", "Path to a file providing data sources to include")
+	enumFlags.StringVar(&args.Filepaths.JSONOutput, "json", "", "Path to the JSON Lines file streaming each finding ('-' for stdout)")
 	enumFlags.StringVar(&args.Filepaths.LogFile, "log", "", "Path to the log file where errors will be written")
 	enumFlags.Var(&args.Filepaths.Names, "nf", "Path to a file providing already known subdomain names (from other tools/sources)")
 	enumFlags.Var(&args.Filepaths.Resolvers, "rf", "Path to a file providing untrusted DNS resolvers")
 	enumFlags.Var(&args.Filepaths.Trusted, "trf", "Path to a file providing trusted DNS resolvers")
 	enumFlags.StringVar(&args.Filepaths.ScriptsDirectory, "scripts", "", "Path to a directory containing ADS scripts")
+	enumFlags.StringVar(&args.Filepaths.SubfinderConfig, "subfinder-config", "", "Path to a subfinder provider configuration file providing data source keys")
 	enumFlags.StringVar(&args.Filepaths.TermOut, "o", "", "Path to the text file containing terminal stdout/stderr")
 }
 
@@ -163,6 +189,17 @@ func runEnumCommand(clArgs []string) {
 	}
 	// Start handling the log messages
 	go writeLogsAndMessages(rLog, logfile, args.Options.Verbose)
+	// The download rate is shared by the requests of every data source
+	amasshttp.SetDownloadRate(numberOption(cfg, "max_download_rate") * 1024)
+	setMaxBodyBytes(cfg)
+	// The responses are kept for conditional requests when the cache size is provided
+	if size := numberOption(cfg, "response_cache"); size > 0 {
+		ttl := numberOption(cfg, "response_cache_ttl")
+		if ttl <= 0 {
+			ttl = defaultResponseCacheTTL
+		}
+		amasshttp.Cache = amasshttp.NewMemoryCache(size, time.Duration(ttl)*time.Minute)
+	}
 	// Create the System that will provide architecture to this enumeration
 	sys, err := systems.NewLocalSystem(cfg)
 	if err != nil {
@@ -183,6 +220,20 @@ func runEnumCommand(clArgs []string) {
 		os.Exit(1)
 	}
 
+	e.StoreQueueSize = args.StoreQueueSize
+	e.ApexFirst = args.Options.ApexFirst
+	e.AllowMalformedNames = args.Options.Malformed
+
+	if args.Filepaths.JSONOutput != "" {
+		findings, err := openFindingsFile(args.Filepaths.JSONOutput)
+		if err != nil {
+			r.Fprintf(color.Error, "Failed to open the JSON findings file: %v\n", err)
+			os.Exit(1)
+		}
+		defer func() { _ = findings.Close() }()
+		e.Findings = findings
+	}
+
 	var wg sync.WaitGroup
 	var outChans []chan string
 	// This channel sends the signal for goroutines to terminate
@@ -234,6 +285,18 @@ func runEnumCommand(clArgs []string) {
 	// Let all the output goroutines know that the enumeration has finished
 	close(done)
 	wg.Wait()
+
+	if args.Options.Verbose {
+		printSourceMetrics(color.Error, e.SourceMetrics())
+		if n := e.MalformedNames(); n > 0 {
+			fmt.Fprintf(color.Error, "%d malformed names were dropped before storage\n", n)
+		}
+	}
+	if args.Filepaths.Graph != "" {
+		if err := saveGraph(args.Filepaths.Graph, sys.GraphDatabases()[0], e, args.GraphTypes); err != nil {
+			r.Fprintf(color.Error, "Failed to export the graph: %v\n", err)
+		}
+	}
 	fmt.Fprintf(color.Error, "\n%s\n", green("The enumeration has finished"))
 }
 
@@ -306,6 +369,16 @@ func argsAndConfig(clArgs []string) (*config.Config, *enumArgs) {
 		commandUsage(enumUsageMsg, enumCommand, enumBuf)
 		os.Exit(1)
 	}
+	if args.Filepaths.Graph != "" {
+		if _, err := format.GraphFormatFromPath(args.Filepaths.Graph); err != nil {
+			r.Fprintf(color.Error, "%v\n", err)
+			os.Exit(1)
+		}
+	}
+	if _, err := format.ParseAssetTypes(args.GraphTypes); err != nil {
+		r.Fprintf(color.Error, "%v\n", err)
+		os.Exit(1)
+	}
 	if err := processEnumInputFiles(&args); err != nil {
 		fmt.Fprintf(color.Error, "%v\n", err)
 		os.Exit(1)
@@ -327,6 +400,13 @@ func argsAndConfig(clArgs []string) (*config.Config, *enumArgs) {
 		r.Fprintf(color.Error, "Configuration error: %v\n", err)
 		os.Exit(1)
 	}
+	// The keys from the subfinder configuration are only used for the data sources without credentials
+	if args.Filepaths.SubfinderConfig != "" {
+		if _, err := datasrcs.ImportSubfinderConfig(cfg, args.Filepaths.SubfinderConfig); err != nil {
+			r.Fprintf(color.Error, "%v\n", err)
+			os.Exit(1)
+		}
+	}
 	// Check if the user has requested the data source names
 	if args.Options.ListSources {
 		for _, line := range GetAllSourceInfo(cfg) {
@@ -395,6 +475,85 @@ func saveTextOutput(e *enum.Enumeration, args *enumArgs, output chan string, wg
 	}
 }
 
+// Returns the file that will receive the JSON lines of findings, using stdout when the path is '-'.
+func openFindingsFile(path string) (io.WriteCloser, error) {
+	if path == "-" {
+		return nopCloser{os.Stdout}, nil
+	}
+
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
+	if err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+// Prints a line for each data source that sent requests or provided findings during the enumeration.
+func printSourceMetrics(w io.Writer, metrics map[string]scripting.Metrics) {
+	var names []string
+	for name, m := range metrics {
+		if m.Queries > 0 || m.NamesFound > 0 || m.Assets > 0 || m.Findings > 0 || m.Dropped > 0 {
+			names = append(names, name)
+		}
+	}
+	if len(names) == 0 {
+		return
+	}
+	sort.Strings(names)
+
+	fmt.Fprintf(w, "\n%s\n", green("Data source summary"))
+	for _, name := range names {
+		m := metrics[name]
+		fmt.Fprintf(w, "%s: %d queries, %d HTTP errors, %d names found, %d in scope, %d assets, %d findings, %d dropped\n",
+			name, m.Queries, m.HTTPErrors, m.NamesFound, m.NamesInScope, m.Assets, m.Findings, m.Dropped)
+	}
+}
+
+// numberOption returns the integer value of the named option, or zero when it was not provided.
+func numberOption(cfg *config.Config, name string) int {
+	switch v := cfg.Options[name].(type) {
+	case int:
+		return v
+	case float64:
+		return int(v)
+	}
+	return 0
+}
+
+// setMaxBodyBytes applies the max_body_size option, in kilobytes, to the limit on the response bodies.
+func setMaxBodyBytes(cfg *config.Config) {
+	if size := numberOption(cfg, "max_body_size"); size > 0 {
+		amasshttp.MaxBodyBytes = int64(size) * 1024
+	}
+}
+
+// Exports the assets and relations discovered during the enumeration to the graph file.
+func saveGraph(path string, g *netmap.Graph, e *enum.Enumeration, names []string) error {
+	f, err := format.GraphFormatFromPath(path)
+	if err != nil {
+		return err
+	}
+
+	atypes, err := format.ParseAssetTypes(names)
+	if err != nil {
+		return err
+	}
+
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = file.Close() }()
+
+	return format.WriteGraph(file, g, f, e.Config.CollectionStartTime, atypes)
+}
+
+type nopCloser struct {
+	io.Writer
+}
+
+func (nopCloser) Close() error { return nil }
+
 func processOutput(ctx context.Context, g *netmap.Graph, e *enum.Enumeration, outputs []chan string, done chan struct{}, wg *sync.WaitGroup) {
 	defer wg.Done()
 	defer func() {
@@ -623,6 +782,24 @@ func (e enumArgs) OverrideConfig(conf *config.Config) error {
 	if e.Options.Verbose {
 		conf.Verbose = true
 	}
+	if e.Options.DryRun {
+		if conf.Options == nil {
+			conf.Options = make(map[string]interface{})
+		}
+		conf.Options["dry_run"] = true
+	}
+	if e.MaxDownloadRate > 0 {
+		if conf.Options == nil {
+			conf.Options = make(map[string]interface{})
+		}
+		conf.Options["max_download_rate"] = e.MaxDownloadRate
+	}
+	if e.MaxBodySize > 0 {
+		if conf.Options == nil {
+			conf.Options = make(map[string]interface{})
+		}
+		conf.Options["max_body_size"] = e.MaxBodySize
+	}
 	if e.ResolverQPS > 0 {
 		conf.ResolversQPS = e.ResolverQPS
 	}
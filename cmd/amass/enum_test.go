@@ -0,0 +1,78 @@
+// Copyright © by Jeff Foley 2017-2023. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/caffix/stringset"
+	amasshttp "github.com/owasp-amass/amass/v4/net/http"
+	"github.com/owasp-amass/config/config"
+)
+
+func TestMaxBodySize(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(strings.Repeat("A", 3*1024)))
+	}))
+	defer ts.Close()
+
+	saved := amasshttp.MaxBodyBytes
+	defer func() { amasshttp.MaxBodyBytes = saved }()
+
+	tests := []struct {
+		label    string
+		options  map[string]interface{}
+		flags    []string
+		tooLarge bool
+	}{
+		{"Config", map[string]interface{}{"max_body_size": 2}, nil, true},
+		// The flag replaces the value provided by the configuration file
+		{"Flag", map[string]interface{}{"max_body_size": 2}, []string{"-max-body-size", "4"}, false},
+		{"Flag_Only", nil, []string{"-max-body-size", "2"}, true},
+	}
+
+	for _, test := range tests {
+		amasshttp.MaxBodyBytes = saved
+
+		args := enumArgs{
+			AltWordList:       stringset.New(),
+			AltWordListMask:   stringset.New(),
+			BruteWordList:     stringset.New(),
+			BruteWordListMask: stringset.New(),
+			Blacklist:         stringset.New(),
+			Domains:           stringset.New(),
+			Excluded:          stringset.New(),
+			Included:          stringset.New(),
+			Names:             stringset.New(),
+			Resolvers:         stringset.New(),
+			Trusted:           stringset.New(),
+		}
+		fs := flag.NewFlagSet("enum", flag.ContinueOnError)
+		fs.SetOutput(io.Discard)
+		defineEnumArgumentFlags(fs, &args)
+		if err := fs.Parse(test.flags); err != nil {
+			t.Fatalf("%s: failed to parse the flags: %v", test.label, err)
+		}
+
+		cfg := config.NewConfig()
+		cfg.Options = test.options
+		if err := args.OverrideConfig(cfg); err != nil {
+			t.Fatalf("%s: failed to override the configuration: %v", test.label, err)
+		}
+		setMaxBodyBytes(cfg)
+
+		_, err := amasshttp.RequestWebPage(context.Background(), &amasshttp.Request{URL: ts.URL})
+		if tooLarge := errors.Is(err, amasshttp.ErrBodyTooLarge); tooLarge != test.tooLarge {
+			t.Errorf("%s: expected the response body to exceed the limit: %t, and got the error %v", test.label, test.tooLarge, err)
+		}
+	}
+}
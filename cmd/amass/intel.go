@@ -71,8 +71,8 @@ func defineIntelArgumentFlags(intelFlags *flag.FlagSet, args *intelArgs) {
 	intelFlags.Var(&args.CIDRs, "cidr", "CIDRs separated by commas (can be used multiple times)")
 	intelFlags.StringVar(&args.OrganizationName, "org", "", "Search string provided against AS description information")
 	intelFlags.Var(args.Domains, "d", "Domain names separated by commas (can be used multiple times)")
-	intelFlags.Var(args.Excluded, "exclude", "Data source names separated by commas to be excluded")
-	intelFlags.Var(args.Included, "include", "Data source names separated by commas to be included")
+	intelFlags.Var(args.Excluded, "exclude", "Data source names or types separated by commas to be excluded")
+	intelFlags.Var(args.Included, "include", "Data source names or types separated by commas to be included")
 	intelFlags.IntVar(&args.MaxDNSQueries, "max-dns-queries", 0, "Maximum number of concurrent DNS queries")
 	intelFlags.Var(&args.Ports, "p", "Ports separated by commas (default: 80, 443)")
 	intelFlags.Var(args.Resolvers, "r", "IP addresses of preferred DNS resolvers (can be used multiple times)")
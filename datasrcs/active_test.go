@@ -0,0 +1,83 @@
+// Copyright © by Jeff Foley 2017-2023. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+// SPDX-License-Identifier: Apache-2.0
+
+package datasrcs
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/miekg/dns"
+	"github.com/owasp-amass/config/config"
+)
+
+func TestActiveDNSZoneTransfer(t *testing.T) {
+	allowed := startXfrServer(t, true)
+	refused := startXfrServer(t, false)
+
+	names, logs := runConfiguredScript(t, "scripts/dns/active.ads", func(cfg *config.Config) {
+		cfg.Active = true
+	}, func(string) string {
+		return fmt.Sprintf(`
+			function zone_walk(ctx, name, addr) end
+			function ns_addrs(ctx, name) return {"%s", "%s"} end
+		`, allowed, refused)
+	}, func(w http.ResponseWriter, r *http.Request) {})
+
+	sort.Strings(names)
+	if got := strings.Join(names, ","); got != "dev.owasp.org,owasp.org,www.owasp.org" {
+		t.Errorf("the zone transfer provided the wrong names: %s", got)
+	}
+	if !strings.Contains(logs, "the zone transfer of owasp.org from "+allowed+" provided 5 records") {
+		t.Errorf("the successful zone transfer was not logged: %s", logs)
+	}
+	if !strings.Contains(logs, "the zone transfer of owasp.org was refused by "+refused) {
+		t.Errorf("the refused zone transfer was not logged: %s", logs)
+	}
+}
+
+// Starts a nameserver for owasp.org that allows or refuses zone transfers, and returns its address.
+func startXfrServer(t *testing.T, allow bool) string {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen for the zone transfers: %v", err)
+	}
+
+	mux := dns.NewServeMux()
+	mux.HandleFunc("owasp.org.", func(w dns.ResponseWriter, r *dns.Msg) {
+		if !allow || r.Question[0].Qtype != dns.TypeAXFR {
+			m := new(dns.Msg)
+			m.SetRcode(r, dns.RcodeRefused)
+			_ = w.WriteMsg(m)
+			return
+		}
+
+		var rrs []dns.RR
+		for _, rr := range []string{
+			"owasp.org. 300 IN SOA ns1.owasp.org. admin.owasp.org. 1 3600 600 86400 300",
+			"owasp.org. 300 IN NS ns1.owasp.org.",
+			"www.owasp.org. 300 IN A 93.184.216.34",
+			"dev.owasp.org. 300 IN CNAME www.owasp.org.",
+			"owasp.org. 300 IN SOA ns1.owasp.org. admin.owasp.org. 1 3600 600 86400 300",
+		} {
+			record, _ := dns.NewRR(rr)
+			rrs = append(rrs, record)
+		}
+
+		ch := make(chan *dns.Envelope, 1)
+		ch <- &dns.Envelope{RR: rrs}
+		close(ch)
+		_ = new(dns.Transfer).Out(w, r, ch)
+		w.Hijack()
+	})
+
+	srv := &dns.Server{Listener: l, Handler: mux}
+	go func() { _ = srv.ActivateAndServe() }()
+	t.Cleanup(func() { _ = srv.Shutdown() })
+	return l.Addr().String()
+}
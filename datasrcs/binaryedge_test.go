@@ -0,0 +1,82 @@
+// Copyright © by Jeff Foley 2017-2023. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+// SPDX-License-Identifier: Apache-2.0
+
+package datasrcs
+
+import (
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+)
+
+var binaryEdgeOverrides = scriptOverrides(`
+function api_url(domain, pagenum)
+    return "%[1]s/v2/query/domains/subdomain/" .. domain .. "?page=" .. pagenum
+end
+`)
+
+// Serves the pages of the fixture for owasp.org and records the requested pages.
+func binaryEdgeHandler(pages map[string]string, requested *[]string, lock *sync.Mutex) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Key") != "testing" {
+			w.WriteHeader(http.StatusUnauthorized)
+			_, _ = w.Write([]byte(`{"message":"Unauthorized","title":"Unauthorized","status":401}`))
+			return
+		}
+		if r.URL.Path != "/v2/query/domains/subdomain/owasp.org" {
+			_, _ = w.Write([]byte(`{"query":"example.com","page":1,"pagesize":100,"total":0,"events":[]}`))
+			return
+		}
+
+		page := r.URL.Query().Get("page")
+		lock.Lock()
+		*requested = append(*requested, page)
+		lock.Unlock()
+		_, _ = w.Write([]byte(pages[page]))
+	}
+}
+
+func TestBinaryEdgePagination(t *testing.T) {
+	pages := map[string]string{
+		"1": `{"query":"owasp.org","page":1,"pagesize":3,"total":8,"events":["www.owasp.org","mail.owasp.org","www.example.net"]}`,
+		"2": `{"query":"owasp.org","page":2,"pagesize":3,"total":8,"events":["dev.owasp.org","api.owasp.org",""]}`,
+		"3": `{"query":"owasp.org","page":3,"pagesize":3,"total":8,"events":["vpn.owasp.org","owasp.org"]}`,
+		"4": `{"query":"owasp.org","page":4,"pagesize":3,"total":8,"events":["extra.owasp.org"]}`,
+	}
+
+	tests := []struct {
+		label     string
+		keys      []string
+		names     string
+		requested string
+		logged    string
+	}{
+		{"Paged", []string{"testing"}, "api.owasp.org,dev.owasp.org,mail.owasp.org,owasp.org,vpn.owasp.org,www.owasp.org", "1,2,3", ""},
+		{"Unauthorized", []string{"revoked"}, "", "", "vertical request to service was not authorized: 401 Unauthorized: Unauthorized"},
+	}
+
+	for _, test := range tests {
+		var lock sync.Mutex
+		var requested []string
+		names, logs := runConfiguredScript(t, "scripts/api/binaryedge.ads", withKeys("BinaryEdge", test.keys),
+			binaryEdgeOverrides, binaryEdgeHandler(pages, &requested, &lock))
+
+		sort.Strings(names)
+		if got := strings.Join(names, ","); got != test.names {
+			t.Errorf("%s: expected the names %s and got %s", test.label, test.names, got)
+		}
+		lock.Lock()
+		if got := strings.Join(requested, ","); got != test.requested {
+			t.Errorf("%s: expected the pages %s to be requested and got %s", test.label, test.requested, got)
+		}
+		lock.Unlock()
+		if test.logged == "" && strings.Contains(logs, "vertical request") {
+			t.Errorf("%s: the requests were not successful: %s", test.label, logs)
+		} else if !strings.Contains(logs, test.logged) {
+			t.Errorf("%s: %q was not logged: %s", test.label, test.logged, logs)
+		}
+	}
+}
@@ -0,0 +1,220 @@
+// Copyright © by Jeff Foley 2017-2023. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+// SPDX-License-Identifier: Apache-2.0
+
+package datasrcs
+
+import (
+	"archive/zip"
+	"bytes"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/owasp-amass/config/config"
+)
+
+// Appended to the embedded script to remove the rate limit and send the requests to the test server
+const chaosTestOverrides = `
+function start() end
+
+function datasrc_config()
+    return {['all_credentials']={%s}, ['options']={%s}}
+end
+
+function api_url(domain)
+    return "%[3]s/dns/" .. domain .. "/subdomains"
+end
+
+function programs_url()
+    return "%[3]s/programs.json"
+end
+
+function index_url()
+    return "%[3]s/index.json"
+end
+`
+
+// Runs the embedded chaos script against the handler and returns the discovered names and log output.
+func runChaosScript(t *testing.T, keys []string, opts string, handler http.HandlerFunc) ([]string, string) {
+	return runConfiguredScript(t, "scripts/api/chaos.ads", withKeys("Chaos", keys), chaosOverrides(keys, opts), handler)
+}
+
+// Returns the overrides providing the keys and options to the chaos script.
+func chaosOverrides(keys []string, opts string) func(url string) string {
+	var creds []string
+	for _, key := range keys {
+		creds = append(creds, fmt.Sprintf(`{['key']=%q}`, key))
+	}
+
+	return func(url string) string {
+		return fmt.Sprintf(chaosTestOverrides, strings.Join(creds, ","), opts, url)
+	}
+}
+
+// Responds to queries for owasp.org using the provided function and returns an empty result for other domains.
+func chaosHandler(fn func(w http.ResponseWriter, key string)) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !strings.Contains(r.URL.Path, "owasp.org") {
+			_, _ = w.Write([]byte(`{}`))
+			return
+		}
+		fn(w, r.Header.Get("Authorization"))
+	}
+}
+
+func TestChaosErrorBodies(t *testing.T) {
+	errorBody := func(code int, body string) http.HandlerFunc {
+		return chaosHandler(func(w http.ResponseWriter, key string) {
+			w.Header().Set("Retry-After", "1")
+			w.WriteHeader(code)
+			_, _ = w.Write([]byte(body))
+		})
+	}
+
+	keys := []string{"testing"}
+	runResponseTests(t, "scripts/api/chaos.ads", withKeys("Chaos", keys), chaosOverrides(keys, ""), []responseTest{
+		{"Unauthorized", errorBody(http.StatusUnauthorized, `{"error":"unauthorized"}`), "", "unauthorized"},
+		{"Rate_Limited", errorBody(http.StatusTooManyRequests, `{"message":"rate limit exceeded"}`), "", "rate limit exceeded"},
+	})
+}
+
+func TestChaosKeyRotation(t *testing.T) {
+	var lock sync.Mutex
+	counts := make(map[string]int)
+
+	names, _ := runChaosScript(t, []string{"bad", "good", "unused"}, "", chaosHandler(func(w http.ResponseWriter, key string) {
+		lock.Lock()
+		counts[key]++
+		lock.Unlock()
+
+		if key == "bad" {
+			w.WriteHeader(http.StatusUnauthorized)
+			_, _ = w.Write([]byte(`{"error":"unauthorized"}`))
+			return
+		}
+		_, _ = w.Write([]byte(`{"domain":"owasp.org","subdomains":["www"]}`))
+	}))
+
+	if len(names) != 1 || names[0] != "www.owasp.org" {
+		t.Errorf("the script returned the wrong names: %v", names)
+	}
+
+	lock.Lock()
+	defer lock.Unlock()
+	if counts["bad"] != 1 || counts["good"] != 1 || counts["unused"] != 0 {
+		t.Errorf("the keys were not rotated as expected: %v", counts)
+	}
+}
+
+func TestChaosRetryAfter(t *testing.T) {
+	var lock sync.Mutex
+	var count int
+
+	names, _ := runChaosScript(t, []string{"testing"}, "", chaosHandler(func(w http.ResponseWriter, key string) {
+		lock.Lock()
+		count++
+		first := count == 1
+		lock.Unlock()
+
+		if first {
+			w.Header().Set("Retry-After", "1")
+			w.WriteHeader(http.StatusTooManyRequests)
+			_, _ = w.Write([]byte(`{"message":"rate limit exceeded"}`))
+			return
+		}
+		_, _ = w.Write([]byte(`{"domain":"owasp.org","subdomains":["www"]}`))
+	}))
+
+	if len(names) != 1 || names[0] != "www.owasp.org" {
+		t.Errorf("the script returned the wrong names: %v", names)
+	}
+
+	lock.Lock()
+	defer lock.Unlock()
+	if count != 2 {
+		t.Errorf("expected 2 requests and the service received %d", count)
+	}
+}
+
+func TestChaosDataset(t *testing.T) {
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	w, err := zw.Create("owasp.org.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, _ = w.Write([]byte("www.owasp.org\ndev.owasp.org\n"))
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// The datasets are served apart, since the index kept on disk provides the URL to the following runs
+	zipSrv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write(buf.Bytes())
+	}))
+	defer zipSrv.Close()
+
+	var lock sync.Mutex
+	var apiQueries, indexQueries int
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/programs.json":
+			lock.Lock()
+			indexQueries++
+			lock.Unlock()
+			_, _ = w.Write([]byte(`{"programs":[{"name":"OWASP","domains":["owasp.org"]}]}`))
+		case "/index.json":
+			lock.Lock()
+			indexQueries++
+			lock.Unlock()
+			_, _ = fmt.Fprintf(w, `[{"name":"OWASP","URL":"%s/owasp.zip"}]`, zipSrv.URL)
+		default:
+			if strings.Contains(r.URL.Path, "owasp.org") {
+				lock.Lock()
+				apiQueries++
+				lock.Unlock()
+			}
+			_, _ = w.Write([]byte(`{}`))
+		}
+	}
+
+	dir := t.TempDir()
+	keys := []string{"testing"}
+	setup := withSetup(withKeys("Chaos", keys), func(cfg *config.Config) { cfg.Dir = dir })
+	// The second enumeration reads the program list and the index kept in the output directory,
+	// and the third requests them again after the files have expired
+	for i, expected := range []int{2, 2, 4} {
+		if i == 2 {
+			old := time.Now().Add(-48 * time.Hour)
+			for _, file := range []string{"chaos_programs.json", "chaos_index.json"} {
+				if err := os.Chtimes(filepath.Join(dir, file), old, old); err != nil {
+					t.Fatal(err)
+				}
+			}
+		}
+
+		names, _ := runConfiguredScript(t, "scripts/api/chaos.ads", setup, chaosOverrides(keys, "['dataset']=true"), handler)
+		if len(names) != 2 || names[0] != "www.owasp.org" || names[1] != "dev.owasp.org" {
+			t.Errorf("run %d: the script returned the wrong names: %v", i+1, names)
+		}
+
+		lock.Lock()
+		if indexQueries != expected {
+			t.Errorf("run %d: expected %d index requests and the service received %d", i+1, expected, indexQueries)
+		}
+		lock.Unlock()
+	}
+
+	lock.Lock()
+	defer lock.Unlock()
+	if apiQueries != 0 {
+		t.Errorf("the API was queried %d times after the dataset was processed", apiQueries)
+	}
+}
@@ -0,0 +1,185 @@
+// Copyright © by Jeff Foley 2017-2023. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+// SPDX-License-Identifier: Apache-2.0
+
+package datasrcs
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	amasshttp "github.com/owasp-amass/amass/v4/net/http"
+	"github.com/owasp-amass/amass/v4/requests"
+)
+
+const crtshLargeEntries = 20000
+
+// Writes a crt.sh response with an entry for each of the hosts, without building it in memory.
+func writeCrtshEntries(w io.Writer, num int) int64 {
+	var written int64
+
+	n, _ := io.WriteString(w, "[")
+	written += int64(n)
+	for i := 0; i < num; i++ {
+		sep := ","
+		if i == 0 {
+			sep = ""
+		}
+
+		n, _ = fmt.Fprintf(w, `%s{"issuer_ca_id":16418,"issuer_name":"C=US, O=Let's Encrypt, CN=R3","common_name":"host%[2]d.owasp.org",`+
+			`"name_value":"host%[2]d.owasp.org","id":%[2]d,"entry_timestamp":"2023-09-01T00:00:00","not_before":"2023-09-01T00:00:00",`+
+			`"not_after":"2023-12-01T00:00:00","serial_number":"04a5bf3e1c2d"}`, sep, i)
+		written += int64(n)
+	}
+	n, _ = io.WriteString(w, "]")
+	return written + int64(n)
+}
+
+// Runs the crt.sh script against the large response and returns the number of names and the log output.
+func runCrtshLarge(t *testing.T, opts string) (int, string, int64) {
+	var size atomic.Int64
+
+	res := scriptRun{
+		path: "scripts/cert/crtsh.ads",
+		overrides: func(url string) string {
+			return fmt.Sprintf(`
+function start() end
+
+function datasrc_config()
+    return {['options']={%s}}
+end
+
+function build_url(q, expired)
+    return "%s/?q=" .. q .. "&output=json"
+end
+`, opts, url)
+		},
+		handler: func(w http.ResponseWriter, r *http.Request) {
+			if !strings.Contains(r.URL.RawQuery, "owasp.org") {
+				_, _ = w.Write([]byte(`[]`))
+				return
+			}
+			size.Store(writeCrtshEntries(w, crtshLargeEntries))
+		},
+	}.run(t)
+	return len(res.names()), res.logs, size.Load()
+}
+
+func TestCrtshLargeResponse(t *testing.T) {
+	// The response is much larger than the limit placed on the bodies held in memory
+	max := amasshttp.MaxBodyBytes
+	amasshttp.MaxBodyBytes = 512 * 1024
+	defer func() { amasshttp.MaxBodyBytes = max }()
+
+	count, logs, size := runCrtshLarge(t, "")
+	if size <= amasshttp.MaxBodyBytes {
+		t.Fatalf("the response of %d bytes did not exceed the limit", size)
+	}
+	if count != crtshLargeEntries {
+		t.Errorf("expected %d names and the script provided %d: %s", crtshLargeEntries, count, logs)
+	}
+	if strings.Contains(logs, "failed") {
+		t.Errorf("the large response was not processed: %s", logs)
+	}
+}
+
+func TestCrtshMaxEntries(t *testing.T) {
+	count, logs, _ := runCrtshLarge(t, "['max_entries']=100")
+	if count != 100 {
+		t.Errorf("expected 100 names and the script provided %d", count)
+	}
+	if !strings.Contains(logs, "reached the maximum of 100 processed certificate entries") {
+		t.Errorf("the truncation was not logged: %s", logs)
+	}
+}
+
+func TestCrtshExpiredCertificates(t *testing.T) {
+	tests := []struct {
+		opts    string
+		exclude bool
+	}{
+		{"", false},
+		{"['include_expired']=true", false},
+		{"['include_expired']=false", true},
+	}
+	for _, test := range tests {
+		overrides := func(url string) string {
+			return fmt.Sprintf(`
+function start() end
+
+function datasrc_config()
+    return {['options']={%s}}
+end
+
+local crtsh_url = build_url
+function build_url(q, expired)
+    return (string.gsub(crtsh_url(q, expired), "^https://crt.sh", "%s"))
+end
+`, test.opts, url)
+		}
+
+		var lock sync.Mutex
+		var queries []string
+		names, _ := runScript(t, "scripts/cert/crtsh.ads", overrides, func(w http.ResponseWriter, r *http.Request) {
+			lock.Lock()
+			queries = append(queries, r.URL.RawQuery)
+			lock.Unlock()
+
+			_, _ = w.Write([]byte(`[{"id":1,"common_name":"www.owasp.org","name_value":"www.owasp.org"}]`))
+		})
+		if len(names) != 1 || names[0] != "www.owasp.org" {
+			t.Errorf("options {%s}: the script returned the wrong names: %v", test.opts, names)
+		}
+
+		lock.Lock()
+		if len(queries) != 2 {
+			t.Errorf("options {%s}: expected 2 queries and the service received %d", test.opts, len(queries))
+		}
+		for _, q := range queries {
+			if strings.Contains(q, "exclude=expired") != test.exclude {
+				t.Errorf("options {%s}: the query %s was not built as expected", test.opts, q)
+			}
+		}
+		lock.Unlock()
+	}
+}
+
+func TestCrtshExpiredCertificateTags(t *testing.T) {
+	res := scriptRun{
+		path: "scripts/cert/crtsh.ads",
+		overrides: func(url string) string {
+			return fmt.Sprintf(`
+function start() end
+
+local crtsh_url = build_url
+function build_url(q, expired)
+    return (string.gsub(crtsh_url(q, expired), "^https://crt.sh", "%s"))
+end
+`, url)
+		},
+		// The names of the expired certificates are only tagged when no valid certificate provided them
+		handler: func(w http.ResponseWriter, r *http.Request) {
+			_, _ = w.Write([]byte(`[
+{"id":1,"common_name":"old.owasp.org","name_value":"old.owasp.org\nwww.owasp.org","not_after":"2019-06-30T23:59:59"},
+{"id":2,"common_name":"www.owasp.org","name_value":"www.owasp.org\nmail.owasp.org","not_after":"2999-06-30T23:59:59"},
+{"id":3,"common_name":"api.owasp.org"}]`))
+		},
+	}.run(t)
+
+	var got []string
+	for _, req := range res.out {
+		if d, ok := req.(*requests.DNSRequest); ok {
+			got = append(got, d.Name+":"+d.Tag)
+		}
+	}
+	sort.Strings(got)
+	if expected := "api.owasp.org:,mail.owasp.org:,old.owasp.org:expired_certificate,www.owasp.org:"; strings.Join(got, ",") != expected {
+		t.Errorf("expected the names %s and got %s", expected, strings.Join(got, ","))
+	}
+}
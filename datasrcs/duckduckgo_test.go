@@ -0,0 +1,171 @@
+// Copyright © by Jeff Foley 2017-2023. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+// SPDX-License-Identifier: Apache-2.0
+
+package datasrcs
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/owasp-amass/amass/v4/requests"
+	"github.com/owasp-amass/config/config"
+)
+
+// Saved from the DuckDuckGo HTML results, with the result entries trimmed
+const duckDuckGoPage = `<div class="results">
+<div class="result results_links results_links_deep web-result">
+<h2 class="result__title">
+<a rel="nofollow" class="result__a" href="//duckduckgo.com/l/?uddg=https%%3A%%2F%%2F%s%%2Fabout%%2F&amp;rut=8f1c">About</a>
+</h2>
+</div>
+<div class="result results_links results_links_deep web-result">
+<h2 class="result__title">
+<a rel="nofollow" class="result__a" href="//duckduckgo.com/l/?uddg=https%%3A%%2F%%2Fwww.example.com%%2F&amp;rut=9a2d">Example</a>
+</h2>
+</div>
+<div class="nav-link">
+<form action="/html/" method="post">
+<input type="submit" class="btn btn--alt" value="Next" />
+<input type="hidden" name="q" value="site:owasp.org -site:www.owasp.org" />
+<input type="hidden" name="s" value="%d" />
+<input type="hidden" name="nextParams" value="" />
+<input type="hidden" name="v" value="l" />
+<input type="hidden" name="o" value="json" />
+<input type="hidden" name="dc" value="%d" />
+<input type="hidden" name="api" value="d.js" />
+<input type="hidden" name="vqd" value="4-%d" />
+<input name="kl" value="wt-wt" type="hidden">
+</form>
+</div>
+</div>`
+
+func TestDuckDuckGoPagination(t *testing.T) {
+	overrides := scriptOverrides(`
+function build_url(domain)
+    return "%[1]s/html/?q=site:" .. domain
+end
+
+function form_url()
+    return "%[1]s/html/"
+end
+`)
+
+	tests := []struct {
+		desc     string
+		pages    []string
+		expected []string
+		requests int
+	}{
+		{
+			desc: "pages until no new names",
+			pages: []string{
+				fmt.Sprintf(duckDuckGoPage, "dev.owasp.org", 30, 31, 1),
+				fmt.Sprintf(duckDuckGoPage, "api.owasp.org", 60, 61, 2),
+				fmt.Sprintf(duckDuckGoPage, "api.owasp.org", 90, 91, 3),
+				fmt.Sprintf(duckDuckGoPage, "ftp.owasp.org", 120, 121, 4),
+			},
+			expected: []string{"dev.owasp.org", "api.owasp.org"},
+			requests: 3,
+		},
+		{
+			desc:     "missing form",
+			pages:    []string{strings.Split(fmt.Sprintf(duckDuckGoPage, "dev.owasp.org", 30, 31, 1), `<div class="nav-link">`)[0]},
+			expected: []string{"dev.owasp.org"},
+			requests: 1,
+		},
+		{
+			desc:     "truncated form",
+			pages:    []string{strings.Split(fmt.Sprintf(duckDuckGoPage, "dev.owasp.org", 30, 31, 1), "</form>")[0]},
+			expected: []string{"dev.owasp.org"},
+			requests: 1,
+		},
+	}
+
+	for _, test := range tests {
+		var lock sync.Mutex
+		var count int
+		var vqds []string
+
+		names, _ := runScript(t, "scripts/scrape/duckduckgo.ads", overrides, func(w http.ResponseWriter, r *http.Request) {
+			if strings.Contains(r.URL.RawQuery, "example.com") {
+				return
+			}
+
+			lock.Lock()
+			defer lock.Unlock()
+
+			if r.Method == http.MethodPost {
+				_ = r.ParseForm()
+				vqds = append(vqds, r.PostForm.Get("vqd"))
+			}
+			if count < len(test.pages) {
+				_, _ = w.Write([]byte(test.pages[count]))
+			}
+			count++
+		})
+
+		if strings.Join(names, ",") != strings.Join(test.expected, ",") {
+			t.Errorf("%s: expected %v and got %v", test.desc, test.expected, names)
+		}
+
+		lock.Lock()
+		if count != test.requests {
+			t.Errorf("%s: expected %d requests and the service received %d", test.desc, test.requests, count)
+		}
+		for i, vqd := range vqds {
+			if expected := fmt.Sprintf("4-%d", i+1); vqd != expected {
+				t.Errorf("%s: request %d provided the vqd token %s instead of %s", test.desc, i+2, vqd, expected)
+			}
+		}
+		lock.Unlock()
+	}
+}
+
+func TestDuckDuckGoSearchQuery(t *testing.T) {
+	var lock sync.Mutex
+	queries := make(map[string]string)
+
+	scriptRun{
+		path: "scripts/scrape/duckduckgo.ads",
+		setup: func(cfg *config.Config) {
+			cfg.AddDomains("dev.owasp.org", "owasp.co.uk")
+		},
+		overrides: scriptOverrides(`
+function build_url(domain)
+    return "%[1]s/html/?q=" .. url_encode(search_query(domain))
+end
+`),
+		handler: func(w http.ResponseWriter, r *http.Request) {
+			q := r.URL.Query().Get("q")
+
+			lock.Lock()
+			queries[strings.Fields(q)[0]] = q
+			lock.Unlock()
+		},
+		inputs: []interface{}{
+			&requests.DNSRequest{Name: "owasp.org", Domain: "owasp.org"},
+			&requests.DNSRequest{Name: "dev.owasp.org", Domain: "dev.owasp.org"},
+			&requests.DNSRequest{Name: "owasp.co.uk", Domain: "owasp.co.uk"},
+			&requests.DNSRequest{Name: "example.com", Domain: "example.com"},
+			&requests.DNSRequest{Name: "example.org", Domain: "example.org"},
+		},
+	}.run(t)
+
+	expected := map[string]string{
+		"site:owasp.org":     "site:owasp.org -site:www.owasp.org",
+		"site:dev.owasp.org": "site:dev.owasp.org",
+		"site:owasp.co.uk":   "site:owasp.co.uk -site:www.owasp.co.uk",
+	}
+
+	lock.Lock()
+	defer lock.Unlock()
+	for site, query := range expected {
+		if got := queries[site]; got != query {
+			t.Errorf("expected the search query %q and got %q", query, got)
+		}
+	}
+}
@@ -0,0 +1,54 @@
+// Copyright © by Jeff Foley 2017-2023. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+// SPDX-License-Identifier: Apache-2.0
+
+package datasrcs
+
+import (
+	"fmt"
+	"net/http"
+	"sync"
+	"testing"
+)
+
+func TestFacebookCTResponses(t *testing.T) {
+	overrides := scriptOverrides(`
+function datasrc_config()
+    return {['credentials']={['key']="1234",['secret']="s3cr3t"}}
+end
+
+function query_url(domain, token)
+    if token == "" then
+        return ""
+    end
+    return "%[1]s/certificates?fields=domains&access_token=" .. url_encode(token) .. "&query=*." .. domain
+end
+`)
+
+	var lock sync.Mutex
+	var tokens []string
+	runResponseTests(t, "scripts/cert/facebookct.ads", nil, overrides, []responseTest{
+		{"Pages", func(w http.ResponseWriter, r *http.Request) {
+			lock.Lock()
+			tokens = append(tokens, r.URL.Query().Get("access_token"))
+			lock.Unlock()
+
+			if r.URL.Query().Get("after") == "" {
+				_, _ = fmt.Fprintf(w, `{"data":[{"domains":["www.owasp.org","api.owasp.org"],"id":"1"},{"id":"2"}],
+					"paging":{"cursors":{"after":"abc"},"next":"http://%s/certificates?after=abc&access_token=1234%%7Cs3cr3t"}}`, r.Host)
+				return
+			}
+			_, _ = w.Write([]byte(`{"data":[{"domains":["mail.owasp.org","www.example.com"],"id":"3"}],"paging":{"cursors":{"after":"def"}}}`))
+		}, "www.owasp.org,api.owasp.org,mail.owasp.org", ""},
+		{"OAuth_Error", func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = w.Write([]byte(`{"error":{"message":"Invalid OAuth access token signature.","type":"OAuthException","code":190,"fbtrace_id":"A1b2"}}`))
+		}, "", "OAuthException: Invalid OAuth access token signature. (code 190)"},
+	})
+
+	lock.Lock()
+	defer lock.Unlock()
+	if len(tokens) != 2 || tokens[0] != "1234|s3cr3t" || tokens[1] != "1234|s3cr3t" {
+		t.Errorf("the requests did not provide the app access token: %v", tokens)
+	}
+}
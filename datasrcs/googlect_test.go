@@ -0,0 +1,64 @@
+// Copyright © by Jeff Foley 2017-2023. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+// SPDX-License-Identifier: Apache-2.0
+
+package datasrcs
+
+import (
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// Trimmed from the certsearch responses of the transparency report, keyed by the page token
+var googleCTPages = map[string]string{
+	"": `)]}'
+
+[["https.ct.cdsr",[[null,"Qm9vZ2xlQ1QxMjM=",null,"CN=R3,O=Let's Encrypt,C=US",1704067200000,1711843199000,["owasp.org","www.owasp.org"],3,null,1,"J1uT1nJ+zEqXtvYhD0lbVm1aRTtJtC0cYbVd/4ZDDGk="],
+[null,"Qm9vZ2xlQ1Q0NTY=",null,"CN=Cloudflare Inc ECC CA-3,O=Cloudflare\\u003d Inc.,C=US",1698796800000,1730419199000,["sni.cloudflaressl.com","owasp.org","*.owasp.org"],1,null,1,"9Uq8ZT+gGLsDHrEyBHDp4ZtqxnX1UGVk7y6S7HcJBj4="]],
+[["CN=R3,O=Let's Encrypt,C=US","Zm9v",312],["CN=Cloudflare Inc ECC CA-3,O=Cloudflare\\u003d Inc.,C=US","YmFy",1]],[null,"CAEaDxIN",null,1,3]]]`,
+	"CAEaDxIN": `)]}'
+
+[["https.ct.cdsr",[[null,"Qm9vZ2xlQ1Q3ODk=",null,"CN=R3,O=Let's Encrypt,C=US",1696118400000,1703980799000,["mail.owasp.org","lists.owasp.org"],3,null,1,"s9qzEm5xZ0e7f9N6n4mH3hXy0Lh0d0n3y7Q9o1Wm0iU="]],
+[["CN=R3,O=Let's Encrypt,C=US","Zm9v",312]],[null,"CAIaDxIN",null,2,3]]]`,
+	"CAIaDxIN": `)]}'
+
+[["https.ct.cdsr",[[null,"Qm9vZ2xlQ1QwMTI=",null,"CN=DigiCert TLS RSA SHA256 2020 CA1,O=DigiCert Inc,C=US",1672531200000,1704067199000,["owasp.org","owasp.net","wiki.owasp.org"],1,null,1,"kq2ZcH3rT0ZcJ8XqvZrR0b1f8W8w4bRZ9gCq3p5n3ls="]],
+[["CN=DigiCert TLS RSA SHA256 2020 CA1,O=DigiCert Inc,C=US","YmF6",9]],[null,"CAMaDxIN",null,3,3]]]`,
+}
+
+func TestGoogleCTPages(t *testing.T) {
+	overrides := scriptOverrides(`
+function build_url(domain, token)
+    if (token ~= nil and token ~= "") then
+        return "%[1]s/certsearch/page?p=" .. token
+    end
+    return "%[1]s/certsearch?domain=" .. domain
+end
+`)
+
+	var lock sync.Mutex
+	var tokens []string
+	runResponseTests(t, "scripts/cert/googlect.ads", nil, overrides, []responseTest{
+		// The names outside of the scope are not sent, and the last page provides a token that is not followed
+		{"Pages", func(w http.ResponseWriter, r *http.Request) {
+			token := r.URL.Query().Get("p")
+			if token == "" && r.URL.Query().Get("domain") != "owasp.org" {
+				_, _ = w.Write([]byte(")]}'\n\n[[\"https.ct.cdsr\",[],[],[null,null,null,1,1]]]"))
+				return
+			}
+
+			lock.Lock()
+			tokens = append(tokens, token)
+			lock.Unlock()
+			_, _ = w.Write([]byte(googleCTPages[token]))
+		}, "owasp.org,www.owasp.org,mail.owasp.org,lists.owasp.org,owasp.org,wiki.owasp.org", ""},
+	})
+
+	lock.Lock()
+	defer lock.Unlock()
+	if got := strings.Join(tokens, ","); got != ",CAEaDxIN,CAIaDxIN" {
+		t.Errorf("the page tokens were not followed until the last page: %q", got)
+	}
+}
@@ -0,0 +1,56 @@
+// Copyright © by Jeff Foley 2017-2023. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+// SPDX-License-Identifier: Apache-2.0
+
+package datasrcs
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/owasp-amass/amass/v4/requests"
+)
+
+func TestHackerTargetHostRecords(t *testing.T) {
+	res := scriptRun{
+		path: "scripts/api/hackertarget.ads",
+		overrides: scriptOverrides(`
+function build_url(domain, key)
+    return "%[1]s/?q=" .. domain
+end
+`),
+		handler: func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Query().Get("q") != "owasp.org" {
+				_, _ = w.Write([]byte("API count exceeded - Increase Quota with Membership"))
+				return
+			}
+			_, _ = w.Write([]byte("www.owasp.org,104.16.132.229\nv6.owasp.org,2606:4700::6810:84e5\r\nmail.owasp.org,\nwww.example.com,93.184.216.34\n"))
+		},
+		inputs: []interface{}{
+			&requests.DNSRequest{Name: "example.com", Domain: "example.com"},
+			&requests.DNSRequest{Name: "owasp.org", Domain: "owasp.org"},
+			&requests.DNSRequest{Name: "example.org", Domain: "example.org"},
+		},
+	}.run(t)
+
+	var got []string
+	for _, req := range res.out {
+		if d, ok := req.(*requests.DNSRequest); ok {
+			entry := d.Name
+			for _, rr := range d.Records {
+				entry += fmt.Sprintf(":%d:%s", rr.Type, rr.Data)
+			}
+			got = append(got, entry)
+		}
+	}
+
+	expected := "www.owasp.org:1:104.16.132.229,v6.owasp.org:28:2606:4700::6810:84e5,mail.owasp.org"
+	if strings.Join(got, ",") != expected {
+		t.Errorf("expected %s and got %s", expected, strings.Join(got, ","))
+	}
+	if !strings.Contains(res.logs, "API count exceeded") {
+		t.Errorf("the service message was not logged: %s", res.logs)
+	}
+}
@@ -0,0 +1,147 @@
+// Copyright © by Jeff Foley 2017-2023. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+// SPDX-License-Identifier: Apache-2.0
+
+package datasrcs
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/owasp-amass/amass/v4/datasrcs/scripting"
+	"github.com/owasp-amass/amass/v4/requests"
+	"github.com/owasp-amass/amass/v4/systems"
+	"github.com/owasp-amass/config/config"
+)
+
+func TestHaveIBeenPwnedBreaches(t *testing.T) {
+	overrides := scriptOverrides(`
+function datasrc_config()
+    return {['credentials']={['key']="testing"}}
+end
+
+function build_url(domain)
+    return "%[1]s/api/v3/breaches?domain=" .. domain
+end
+`)
+
+	var lock sync.Mutex
+	var keys []string
+	names, logs := runScript(t, "scripts/api/haveibeenpwned.ads", overrides, func(w http.ResponseWriter, r *http.Request) {
+		lock.Lock()
+		keys = append(keys, r.Header.Get("hibp-api-key"))
+		lock.Unlock()
+
+		if r.URL.Query().Get("domain") != "owasp.org" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		_, _ = w.Write([]byte(`[
+			{"Name":"Forum","Title":"OWASP Forum","Domain":"owasp.org","BreachDate":"2019-03-01",
+			 "PwnCount":152445,"DataClasses":["Email addresses","Passwords"]},
+			{"Name":"Wiki","Title":"OWASP Wiki","Domain":"owasp.org","BreachDate":"2021-07-15","PwnCount":3200}
+		]`))
+	})
+	if len(names) > 0 {
+		t.Errorf("the script returned names: %v", names)
+	}
+
+	for _, msg := range []string{
+		"owasp.org appeared in the Forum breach on 2019-03-01, affecting 152445 accounts: Email addresses, Passwords",
+		"owasp.org appeared in the Wiki breach on 2021-07-15, affecting 3200 accounts",
+	} {
+		if !strings.Contains(logs, msg) {
+			t.Errorf("the breach was not recorded as expected: %s", logs)
+		}
+	}
+
+	lock.Lock()
+	if len(keys) != 1 || keys[0] != "testing" {
+		t.Errorf("the requests did not provide the API key: %v", keys)
+	}
+	lock.Unlock()
+
+	_, logs = runScript(t, "scripts/api/haveibeenpwned.ads", overrides, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	})
+	if strings.Contains(logs, "breach") || strings.Contains(logs, "status") {
+		t.Errorf("the missing breaches were logged: %s", logs)
+	}
+}
+
+func TestHaveIBeenPwnedRateLimit(t *testing.T) {
+	data := loadScript(t, "scripts/api/haveibeenpwned.ads")
+
+	var lock sync.Mutex
+	var times []time.Time
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		lock.Lock()
+		times = append(times, time.Now())
+		lock.Unlock()
+		_, _ = w.Write([]byte(`[]`))
+	}))
+	defer srv.Close()
+
+	domains := []string{"owasp.org", "example.com"}
+	for _, keys := range [][]string{nil, {"testing"}} {
+		lock.Lock()
+		times = nil
+		lock.Unlock()
+
+		cfg := config.NewConfig()
+		for _, d := range domains {
+			cfg.AddDomain(d)
+		}
+		if keys != nil {
+			withKeys("HaveIBeenPwned", keys)(cfg)
+		}
+
+		// Only the endpoint is replaced, so the rate limit set by the start callback remains
+		s := scripting.NewScript(data+fmt.Sprintf(`
+function build_url(domain)
+    return "%s/api/v3/breaches?domain=" .. domain
+end
+`, srv.URL), &systems.SimpleSystem{Cfg: cfg})
+		if s == nil {
+			t.Fatal("failed to load the script")
+		}
+		if err := s.Start(); keys == nil {
+			// The script is rejected without an API key
+			if err == nil {
+				t.Error("the script started without an API key")
+				_ = s.Stop()
+			}
+			continue
+		} else if err != nil {
+			t.Fatalf("failed to start the script: %v", err)
+		}
+
+		for _, d := range domains {
+			s.Input() <- &requests.DNSRequest{Name: d, Domain: d}
+		}
+		for i := 0; i < 100; i++ {
+			lock.Lock()
+			n := len(times)
+			lock.Unlock()
+
+			if n >= len(domains) {
+				break
+			}
+			time.Sleep(100 * time.Millisecond)
+		}
+		_ = s.Stop()
+
+		lock.Lock()
+		if len(times) != len(domains) {
+			t.Errorf("expected %d requests and the service received %d", len(domains), len(times))
+		} else if gap := times[1].Sub(times[0]); gap < 1500*time.Millisecond {
+			t.Errorf("the requests were only %v apart", gap)
+		}
+		lock.Unlock()
+	}
+}
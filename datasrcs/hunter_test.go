@@ -0,0 +1,39 @@
+// Copyright © by Jeff Foley 2017-2023. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+// SPDX-License-Identifier: Apache-2.0
+
+package datasrcs
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestHunterResponses(t *testing.T) {
+	overrides := scriptOverrides(`
+function datasrc_config()
+    return {['credentials']={['key']="testing"}}
+end
+
+function build_url(domain, key)
+    return "%[1]s/v2/domain-search?domain=" .. domain .. "&api_key=" .. key
+end
+`)
+
+	runResponseTests(t, "scripts/api/hunter.ads", nil, overrides, []responseTest{
+		{"Emails", func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Query().Get("domain") != "owasp.org" {
+				_, _ = w.Write([]byte(`{"data":{"domain":"example.com","emails":[]}}`))
+				return
+			}
+			_, _ = w.Write([]byte(`{"data":{"domain":"owasp.org","organization":"OWASP","emails":[
+				{"value":"jane@mail.owasp.org","sources":[{"domain":"blog.owasp.org","uri":"http://blog.owasp.org/about"}]},
+				{"value":"john@owasp.org","sources":[{"domain":"www.example.com","uri":"http://www.example.com/"}]}
+			]}}`))
+		}, "mail.owasp.org,blog.owasp.org,owasp.org", ""},
+		{"Quota", func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusTooManyRequests)
+			_, _ = w.Write([]byte(`{"errors":[{"id":"too_many_requests","code":429,"details":"You have reached your monthly quota"}]}`))
+		}, "", "monthly quota"},
+	})
+}
@@ -0,0 +1,123 @@
+// Copyright © by Jeff Foley 2017-2023. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+// SPDX-License-Identifier: Apache-2.0
+
+package datasrcs
+
+import (
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/owasp-amass/amass/v4/requests"
+)
+
+func TestIPinfoAddressInfo(t *testing.T) {
+	tests := []struct {
+		label   string
+		body    string
+		asnBody string
+		name    string
+		asn     int
+		desc    string
+		prefix  string
+	}{
+		{
+			label:  "Org",
+			body:   `{"ip":"104.16.132.229","hostname":"www.owasp.org","org":"AS13335 Cloudflare, Inc."}`,
+			name:   "www.owasp.org",
+			asn:    13335,
+			desc:   "Cloudflare, Inc.",
+			prefix: "104.16.132.229/32",
+		},
+		{
+			label:   "ASN_Details",
+			body:    `{"ip":"104.16.132.229","asn":{"asn":"AS13335","name":"Cloudflare, Inc.","route":"104.16.0.0/13"}}`,
+			asnBody: `{"asn":"AS13335","name":"Cloudflare, Inc.","country":"US","registry":"arin","prefixes":[{"netblock":"104.16.0.0/13"}],"prefixes6":[]}`,
+			asn:     13335,
+			desc:    "Cloudflare, Inc.",
+			prefix:  "104.16.0.0/13",
+		},
+		{
+			label: "Missing_Org",
+			body:  `{"ip":"104.16.132.229","hostname":"www.owasp.org"}`,
+			name:  "www.owasp.org",
+		},
+		{
+			label: "Error",
+			body:  `{"error":{"title":"Wrong ip","message":"Please provide a valid IP address"}}`,
+		},
+	}
+
+	for _, test := range tests {
+		var lock sync.Mutex
+		var asnQueries int
+
+		res := scriptRun{
+			path:  "scripts/api/ipinfo.ads",
+			setup: withKeys("IPinfo", []string{"testing"}),
+			overrides: func(url string) string {
+				return `
+function addr_url(addr, token)
+    return "` + url + `/" .. addr .. "/json?token=" .. token
+end
+
+function asn_url(strasn, token)
+    return "` + url + `/" .. strasn .. "/json?token=" .. token
+end
+`
+			},
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Query().Get("token") != "testing" {
+					w.WriteHeader(http.StatusForbidden)
+					return
+				}
+
+				switch r.URL.Path {
+				case "/104.16.132.229/json":
+					_, _ = w.Write([]byte(test.body))
+				case "/AS13335/json":
+					lock.Lock()
+					asnQueries++
+					lock.Unlock()
+
+					if test.asnBody == "" {
+						w.WriteHeader(http.StatusForbidden)
+						return
+					}
+					_, _ = w.Write([]byte(test.asnBody))
+				default:
+					_, _ = w.Write([]byte(`{"ip":"93.184.216.34","bogon":true}`))
+				}
+			},
+			inputs: []interface{}{
+				&requests.ASNRequest{Address: "104.16.132.229"},
+				&requests.ASNRequest{Address: "93.184.216.34"},
+			},
+		}.run(t)
+
+		if got := strings.Join(res.names(), ","); got != test.name {
+			t.Errorf("%s: expected the names %q and got %q", test.label, test.name, got)
+		}
+		lock.Lock()
+		if test.asnBody == "" && asnQueries != 0 {
+			t.Errorf("%s: the ASN details were requested without access to them", test.label)
+		}
+		lock.Unlock()
+
+		r := res.sys.Cache().AddrSearch("104.16.132.229")
+		if test.asn == 0 {
+			if r != nil {
+				t.Errorf("%s: expected no ASN and got AS%d", test.label, r.ASN)
+			}
+			continue
+		}
+		if r == nil {
+			t.Errorf("%s: expected AS%d and the address was not found in the cache", test.label, test.asn)
+		} else if r.ASN != test.asn || r.Description != test.desc || r.Prefix != test.prefix {
+			t.Errorf("%s: expected AS%d %q %s and got AS%d %q %s", test.label,
+				test.asn, test.desc, test.prefix, r.ASN, r.Description, r.Prefix)
+		}
+	}
+}
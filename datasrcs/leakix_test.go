@@ -0,0 +1,68 @@
+// Copyright © by Jeff Foley 2017-2023. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+// SPDX-License-Identifier: Apache-2.0
+
+package datasrcs
+
+import (
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestLeakIXSubdomains(t *testing.T) {
+	overrides := scriptOverrides(`
+function vert_url(domain)
+    return "%[1]s/api/subdomains/" .. domain
+end
+`)
+
+	var lock sync.Mutex
+	var count int
+	setup := withKeys("LeakIX", []string{"testing"})
+	names, logs := runConfiguredScript(t, "scripts/api/leakix.ads", setup, overrides, func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("api-key") != "testing" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		if r.URL.Path != "/api/subdomains/owasp.org" {
+			_, _ = w.Write([]byte(`[]`))
+			return
+		}
+
+		lock.Lock()
+		count++
+		first := count == 1
+		lock.Unlock()
+
+		if first {
+			w.Header().Set("Retry-After", "1")
+			w.WriteHeader(http.StatusTooManyRequests)
+			_, _ = w.Write([]byte(`{"Error":"Rate limited"}`))
+			return
+		}
+		_, _ = w.Write([]byte(`[
+			{"subdomain":"WWW.owasp.org","distinct_ips":1,"last_seen":"2023-09-01T00:00:00Z"},
+			{"subdomain":"www.owasp.org","distinct_ips":2,"last_seen":"2023-09-02T00:00:00Z"},
+			{"subdomain":"mail.owasp.org","distinct_ips":1,"last_seen":"2023-09-03T00:00:00Z"},
+			{"subdomain":"www.example.net","distinct_ips":1,"last_seen":"2023-09-04T00:00:00Z"},
+			{"subdomain":"","distinct_ips":0}
+		]`))
+	})
+
+	sort.Strings(names)
+	if strings.Join(names, ",") != "mail.owasp.org,www.owasp.org" {
+		t.Errorf("the script returned the wrong names: %v", names)
+	}
+	if strings.Contains(logs, "returned") {
+		t.Errorf("the rate limited request was not retried: %s", logs)
+	}
+
+	lock.Lock()
+	defer lock.Unlock()
+	if count != 2 {
+		t.Errorf("expected 2 requests and the service received %d", count)
+	}
+}
@@ -0,0 +1,84 @@
+// Copyright © by Jeff Foley 2017-2023. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+// SPDX-License-Identifier: Apache-2.0
+
+package datasrcs
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+)
+
+const netcraftFirstPage = `<html><body>
+<table class="results-table">
+<tr><td>1.</td><td><a href="http://www.owasp.org" rel="nofollow">www.owasp.org</a></td>
+<td><a href="https://sitereport.netcraft.com/?url=http://www.owasp.org">Site Report</a></td></tr>
+<tr><td>2.</td><td><a href="http://wiki.owasp.org" rel="nofollow">wiki.owasp.org</a></td>
+<td><a href="https://sitereport.netcraft.com/?url=http://wiki.owasp.org">Site Report</a></td></tr>
+</table>
+<p><a class="btn-next" href="?restriction=site+contains&amp;host=*.owasp.org&amp;last=wiki.owasp.org&amp;from=3">
+<b>Next Page</b></a></p>
+</body></html>`
+
+const netcraftLastPage = `<html><body>
+<table class="results-table">
+<tr><td>3.</td><td><a href="http://cheatsheetseries.owasp.org" rel="nofollow">cheatsheetseries.owasp.org</a></td>
+<td><a href="https://sitereport.netcraft.com/?url=http://cheatsheetseries.owasp.org">Site Report</a></td></tr>
+</table>
+</body></html>`
+
+func TestNetcraftPages(t *testing.T) {
+	var lock sync.Mutex
+	var queries []string
+	var cookies []bool
+
+	names, logs := runScript(t, "scripts/scrape/netcraft.ads", func(url string) string {
+		return fmt.Sprintf(`
+function start()
+    base_url = "%s/"
+end
+`, url)
+	}, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("host") != "*.owasp.org" {
+			return
+		}
+
+		_, err := r.Cookie("netcraft_js_verification_challenge")
+		lock.Lock()
+		queries = append(queries, r.URL.Query().Get("from"))
+		cookies = append(cookies, err == nil)
+		lock.Unlock()
+
+		if r.URL.Query().Get("from") == "" {
+			http.SetCookie(w, &http.Cookie{Name: "netcraft_js_verification_challenge", Value: "testing"})
+			_, _ = w.Write([]byte(netcraftFirstPage))
+			return
+		}
+		_, _ = w.Write([]byte(netcraftLastPage))
+	})
+
+	lock.Lock()
+	defer lock.Unlock()
+	if strings.Join(queries, ",") != ",3" {
+		t.Errorf("expected the first and the next page to be requested and got %q: %s", queries, logs)
+	}
+	if len(cookies) != 2 || cookies[0] || !cookies[1] {
+		t.Errorf("the cookie set by the first page was not sent with the next page: %v", cookies)
+	}
+
+	found := make(map[string]bool)
+	for _, n := range names {
+		found[n] = true
+	}
+	for _, n := range []string{"www.owasp.org", "wiki.owasp.org", "cheatsheetseries.owasp.org"} {
+		if !found[n] {
+			t.Errorf("the name %s was not found in %v", n, names)
+		}
+	}
+	if found["sitereport.netcraft.com"] {
+		t.Errorf("the out of scope names were not filtered: %v", names)
+	}
+}
@@ -0,0 +1,61 @@
+// Copyright © by Jeff Foley 2017-2023. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+// SPDX-License-Identifier: Apache-2.0
+
+package datasrcs
+
+import (
+	"encoding/json"
+	"net/http"
+	"sync"
+	"testing"
+)
+
+func TestQuakeResponses(t *testing.T) {
+	overrides := scriptOverrides(`
+function datasrc_config()
+    return {['credentials']={['key']="testing"}}
+end
+
+function build_url()
+    return "%[1]s/api/v3/search/quake_service"
+end
+`)
+
+	var lock sync.Mutex
+	var bodies []map[string]interface{}
+	runResponseTests(t, "scripts/api/quake.ads", nil, overrides, []responseTest{
+		{"Pages", func(w http.ResponseWriter, r *http.Request) {
+			var body map[string]interface{}
+			_ = json.NewDecoder(r.Body).Decode(&body)
+
+			lock.Lock()
+			bodies = append(bodies, body)
+			lock.Unlock()
+
+			if r.Header.Get("X-QuakeToken") != "testing" || body["query"] != `domain:"owasp.org"` {
+				_, _ = w.Write([]byte(`{"code":0,"data":[],"meta":{"pagination":{"total":0}}}`))
+				return
+			}
+			if body["start"] == float64(0) {
+				_, _ = w.Write([]byte(`{"code":0,"message":"Successful.","data":[
+					{"domain":"www.owasp.org","service":{"http":{"host":"www.owasp.org"}}},
+					{"service":{"http":{"host":"dev.owasp.org"}}}
+				],"meta":{"pagination":{"count":2,"page_index":1,"page_size":1000,"total":1500}}}`))
+				return
+			}
+			_, _ = w.Write([]byte(`{"code":0,"message":"Successful.","data":[
+				{"domain":"api.owasp.org"}
+			],"meta":{"pagination":{"count":1,"page_index":2,"page_size":1000,"total":1500}}}`))
+		}, "www.owasp.org,www.owasp.org,dev.owasp.org,api.owasp.org", ""},
+		{"Error", func(w http.ResponseWriter, r *http.Request) {
+			_, _ = w.Write([]byte(`{"code":"u3005","message":"Quota exhausted","data":[]}`))
+		}, "", "Quota exhausted"},
+	})
+
+	lock.Lock()
+	defer lock.Unlock()
+	if len(bodies) != 2 || bodies[1]["start"] != float64(1000) {
+		t.Errorf("the script did not request the second page as expected: %v", bodies)
+	}
+}
@@ -0,0 +1,33 @@
+// Copyright © by Jeff Foley 2017-2023. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+// SPDX-License-Identifier: Apache-2.0
+
+package datasrcs
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestRobtexRecords(t *testing.T) {
+	overrides := scriptOverrides(`
+function build_url(domain, key)
+    return "%[1]s/pdns/forward/" .. domain
+end
+`)
+
+	runResponseTests(t, "scripts/api/robtex.ads", nil, overrides, []responseTest{
+		{"Records", func(w http.ResponseWriter, r *http.Request) {
+			if !strings.HasSuffix(r.URL.Path, "/owasp.org") {
+				return
+			}
+			_, _ = w.Write([]byte(`{"rrname":"owasp.org","rrdata":"ns1.owasp.org","rrtype":"NS","time_first":1500000000,"time_last":1700000000,"count":12}
+{"rrname":"owasp.org","rrdata":"mx.example.com","rrtype":"MX","time_first":1500000000,"time_last":1700000000,"count":3}
+
+not a record
+{"rrname":"owasp.org","rrdata":"104.22.26.77","rrtype":"A","time_first":1500000000,"time_last":1700000000,"count":40}
+`))
+		}, "owasp.org,ns1.owasp.org,owasp.org,owasp.org", ""},
+	})
+}
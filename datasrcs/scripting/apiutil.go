@@ -0,0 +1,28 @@
+// Copyright © by Jeff Foley 2017-2023. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+// SPDX-License-Identifier: Apache-2.0
+
+package scripting
+
+import (
+	_ "embed"
+
+	lua "github.com/yuin/gopher-lua"
+)
+
+// The Lua module providing the API key and error helpers shared by the API scripts.
+//
+//go:embed lib/api_util.lua
+var apiUtilModule string
+
+func apiUtilLoader(L *lua.LState) int {
+	fn, err := L.LoadString(apiUtilModule)
+	if err != nil {
+		L.RaiseError("failed to load the api_util module: %v", err)
+		return 0
+	}
+
+	L.Push(fn)
+	L.Call(0, 1)
+	return 1
+}
@@ -0,0 +1,60 @@
+// Copyright © by Jeff Foley 2017-2023. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+// SPDX-License-Identifier: Apache-2.0
+
+package scripting
+
+import (
+	"testing"
+
+	"github.com/owasp-amass/config/config"
+)
+
+// The results of the helpers are stored in globals when the script is loaded
+const apiUtilTestScript = `
+local api_util = require("api_util")
+
+name = "APIUtil"
+type = "api"
+
+keys = table.concat(api_util.keys({['all_credentials']={{['key']="first"}, {['key']=""}, {['key']="second"}}}), ",")
+single = table.concat(api_util.keys({['credentials']={['key']="only"}}), ",")
+none = #(api_util.keys(nil))
+
+waits = table.concat({
+    api_util.retry_after({['header']={['Retry-After']="10"}}, 5, 60),
+    api_util.retry_after({['header']={['Retry-After']="600"}}, 5, 60),
+    api_util.retry_after({['header']={['Retry-After']="soon"}}, 5, 60),
+    api_util.retry_after({}, 5, 60),
+}, ",")
+
+messages = table.concat({
+    api_util.error_message('{"error":"unauthorized"}'),
+    api_util.error_message('{"error":42,"message":"rate limited"}'),
+    api_util.error_message('{"Error":"Rate limited"}', {"Error", "error"}),
+    api_util.error_message('{"Error":"Rate limited"}'),
+    api_util.error_message("not json"),
+}, "|")
+`
+
+func TestAPIUtilModule(t *testing.T) {
+	sys := newMockSystem(config.NewConfig())
+	defer func() { _ = sys.Shutdown() }()
+
+	s := NewScript(apiUtilTestScript, sys)
+	if s == nil {
+		t.Fatal("failed to initialize the scripting environment")
+	}
+
+	for global, expected := range map[string]string{
+		"keys":     "first,second",
+		"single":   "only",
+		"none":     "0",
+		"waits":    "10,60,5,5",
+		"messages": "unauthorized|rate limited|Rate limited||",
+	} {
+		if got := s.luaState.GetGlobal(global).String(); got != expected {
+			t.Errorf("expected %s to be %q and got %q", global, expected, got)
+		}
+	}
+}
@@ -0,0 +1,220 @@
+// Copyright © by Jeff Foley 2017-2023. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+// SPDX-License-Identifier: Apache-2.0
+
+package scripting
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/owasp-amass/amass/v4/net/http"
+)
+
+const (
+	// Number of consecutive failures within the window that opens the circuit breaker
+	breakerThreshold = 5
+	breakerWindow    = 2 * time.Minute
+	breakerCooldown  = 5 * time.Minute
+)
+
+// errCircuitOpen is returned in place of the response while the circuit breaker of the data source is open.
+var errCircuitOpen = errors.New("the request was skipped, since the data source is failing consistently")
+
+// failureKind classifies the outcome of a request sent to a data source.
+type failureKind int
+
+const (
+	failureNone failureKind = iota
+	failureNetwork
+	failureAuth
+	failureServer
+)
+
+func (f failureKind) String() string {
+	switch f {
+	case failureNetwork:
+		return "network error"
+	case failureAuth:
+		return "authentication error"
+	case failureServer:
+		return "server error"
+	}
+	return "success"
+}
+
+// classifyFailure identifies the failures that indicate the data source is unusable. Responses such as
+// not found or too many requests are answers from a working service, so they are not failures.
+func classifyFailure(resp *http.Response, err error) failureKind {
+	if err != nil {
+		if errors.Is(err, http.ErrBodyTooLarge) {
+			return failureNone
+		}
+		return failureNetwork
+	}
+	if resp == nil {
+		return failureNetwork
+	}
+
+	switch {
+	case resp.StatusCode == 401 || resp.StatusCode == 403:
+		return failureAuth
+	case resp.StatusCode >= 500:
+		return failureServer
+	}
+	return failureNone
+}
+
+type breakerState int
+
+const (
+	breakerClosed breakerState = iota
+	breakerOpen
+	breakerHalfOpen
+)
+
+func (b breakerState) String() string {
+	switch b {
+	case breakerOpen:
+		return "open"
+	case breakerHalfOpen:
+		return "half-open"
+	}
+	return "closed"
+}
+
+// breaker stops the requests to a data source after consecutive failures within the window. Once the
+// cool-down period has passed, a single trial request decides whether the circuit closes or opens again.
+type breaker struct {
+	sync.Mutex
+	threshold int
+	window    time.Duration
+	cooldown  time.Duration
+	state     breakerState
+	failures  []time.Time
+	until     time.Time
+	trial     bool
+}
+
+func newBreaker(threshold int, window, cooldown time.Duration) *breaker {
+	return &breaker{
+		threshold: threshold,
+		window:    window,
+		cooldown:  cooldown,
+	}
+}
+
+// Allow returns true when the request can be sent, and the new state when the call changed it.
+func (b *breaker) Allow(now time.Time) (bool, breakerState, bool) {
+	b.Lock()
+	defer b.Unlock()
+
+	switch b.state {
+	case breakerOpen:
+		if now.Before(b.until) {
+			return false, b.state, false
+		}
+		b.state = breakerHalfOpen
+		b.trial = true
+		return true, b.state, true
+	case breakerHalfOpen:
+		// Only the trial request is sent until its outcome is known
+		if b.trial {
+			return false, b.state, false
+		}
+		b.trial = true
+	}
+	return true, b.state, false
+}
+
+// Record adds the outcome of a request, and returns the new state when the outcome changed it.
+func (b *breaker) Record(now time.Time, kind failureKind) (breakerState, bool) {
+	b.Lock()
+	defer b.Unlock()
+
+	b.trial = false
+	if kind == failureNone {
+		b.failures = nil
+		if b.state != breakerClosed {
+			b.state = breakerClosed
+			return b.state, true
+		}
+		return b.state, false
+	}
+
+	if b.state == breakerHalfOpen {
+		b.state = breakerOpen
+		b.until = now.Add(b.cooldown)
+		return b.state, true
+	}
+
+	var recent []time.Time
+	for _, t := range b.failures {
+		if now.Sub(t) <= b.window {
+			recent = append(recent, t)
+		}
+	}
+	b.failures = append(recent, now)
+
+	if b.state == breakerClosed && len(b.failures) >= b.threshold {
+		b.failures = nil
+		b.state = breakerOpen
+		b.until = now.Add(b.cooldown)
+		return b.state, true
+	}
+	return b.state, false
+}
+
+// Release allows another trial request when the pending request ended without an outcome.
+func (b *breaker) Release() {
+	b.Lock()
+	defer b.Unlock()
+
+	b.trial = false
+}
+
+// checkBreaker returns errCircuitOpen when the request to the data source must be skipped.
+func (s *Script) checkBreaker() error {
+	allowed, state, changed := s.breaker.Allow(time.Now())
+
+	if changed {
+		s.sys.Config().Log.Printf("%s: the circuit breaker is %s after the cool-down, so a trial request will be sent", s.String(), state)
+	}
+	if !allowed {
+		return errCircuitOpen
+	}
+	return nil
+}
+
+// recordOutcome updates the circuit breaker with the outcome of the request and logs the state transitions.
+func (s *Script) recordOutcome(ctx context.Context, resp *http.Response, err error) {
+	// Requests cancelled by the enumeration say nothing about the data source
+	if ctx.Err() != nil {
+		s.breaker.Release()
+		return
+	}
+
+	kind := classifyFailure(resp, err)
+	// The keyring rotates past a rejected account, so the authentication errors are only
+	// counted once the account used by the request is the last one that was not rejected
+	if kind == failureAuth && s.getKeyring().Remaining() > 1 {
+		s.breaker.Release()
+		return
+	}
+
+	state, changed := s.breaker.Record(time.Now(), kind)
+	if !changed {
+		return
+	}
+
+	cfg := s.sys.Config()
+	switch state {
+	case breakerOpen:
+		cfg.Log.Printf("%s: the circuit breaker is %s after consecutive failures ending with the %s, so requests will be skipped for %v",
+			s.String(), state, kind, s.breaker.cooldown)
+	case breakerClosed:
+		cfg.Log.Printf("%s: the circuit breaker is %s, since the data source responded successfully", s.String(), state)
+	}
+}
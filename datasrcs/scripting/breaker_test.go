@@ -0,0 +1,255 @@
+// Copyright © by Jeff Foley 2017-2023. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+// SPDX-License-Identifier: Apache-2.0
+
+package scripting
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	amasshttp "github.com/owasp-amass/amass/v4/net/http"
+	"github.com/owasp-amass/amass/v4/requests"
+	"github.com/owasp-amass/config/config"
+)
+
+func TestClassifyFailure(t *testing.T) {
+	tests := []struct {
+		name     string
+		resp     *amasshttp.Response
+		err      error
+		expected failureKind
+	}{
+		{"Success", &amasshttp.Response{StatusCode: 200}, nil, failureNone},
+		{"Not found", &amasshttp.Response{StatusCode: 404}, nil, failureNone},
+		{"Too many requests", &amasshttp.Response{StatusCode: 429}, nil, failureNone},
+		{"Unauthorized", &amasshttp.Response{StatusCode: 401}, nil, failureAuth},
+		{"Forbidden", &amasshttp.Response{StatusCode: 403}, nil, failureAuth},
+		{"Unavailable", &amasshttp.Response{StatusCode: 503}, nil, failureServer},
+		{"No such host", nil, errors.New("dial tcp: lookup api.owasp.org: no such host"), failureNetwork},
+		{"No response", nil, nil, failureNetwork},
+		{"Body too large", nil, fmt.Errorf("https://api.owasp.org: %w", amasshttp.ErrBodyTooLarge), failureNone},
+	}
+
+	for _, test := range tests {
+		if kind := classifyFailure(test.resp, test.err); kind != test.expected {
+			t.Errorf("%s: expected a %s and got a %s", test.name, test.expected, kind)
+		}
+	}
+}
+
+func TestBreakerOpensAndRecovers(t *testing.T) {
+	b := newBreaker(3, time.Minute, 5*time.Minute)
+	now := time.Now()
+
+	for i := 0; i < 3; i++ {
+		if allowed, _, _ := b.Allow(now); !allowed {
+			t.Fatalf("request %d was not allowed before the breaker opened", i+1)
+		}
+		state, changed := b.Record(now, failureServer)
+		if opened := i == 2; changed != opened || (opened && state != breakerOpen) {
+			t.Fatalf("failure %d left the breaker %s, changed: %t", i+1, state, changed)
+		}
+	}
+
+	if allowed, _, _ := b.Allow(now.Add(4 * time.Minute)); allowed {
+		t.Error("the request was allowed during the cool-down period")
+	}
+
+	// A failed trial request opens the circuit breaker again
+	now = now.Add(6 * time.Minute)
+	if allowed, state, changed := b.Allow(now); !allowed || !changed || state != breakerHalfOpen {
+		t.Fatalf("the trial request was not allowed after the cool-down: %t, %s", allowed, state)
+	}
+	if allowed, _, _ := b.Allow(now); allowed {
+		t.Error("a second request was allowed while the trial request was pending")
+	}
+	b.Release()
+	if allowed, _, _ := b.Allow(now); !allowed {
+		t.Fatal("the trial request was not allowed after the pending request was cancelled")
+	}
+	if state, changed := b.Record(now, failureAuth); !changed || state != breakerOpen {
+		t.Fatalf("the failed trial request left the breaker %s", state)
+	}
+
+	now = now.Add(6 * time.Minute)
+	if allowed, _, _ := b.Allow(now); !allowed {
+		t.Fatal("the trial request was not allowed after the second cool-down")
+	}
+	if state, changed := b.Record(now, failureNone); !changed || state != breakerClosed {
+		t.Fatalf("the successful trial request left the breaker %s", state)
+	}
+	if allowed, _, _ := b.Allow(now); !allowed {
+		t.Error("the request was not allowed after the breaker closed")
+	}
+}
+
+func TestBreakerCountsConsecutiveFailures(t *testing.T) {
+	b := newBreaker(3, time.Minute, 5*time.Minute)
+	now := time.Now()
+
+	// A success resets the count of failures
+	_, _ = b.Record(now, failureNetwork)
+	_, _ = b.Record(now, failureNetwork)
+	_, _ = b.Record(now, failureNone)
+	_, _ = b.Record(now, failureNetwork)
+	if state, _ := b.Record(now, failureNetwork); state != breakerClosed {
+		t.Error("the breaker opened without consecutive failures")
+	}
+
+	// Failures outside of the window are not counted
+	now = now.Add(2 * time.Minute)
+	if state, _ := b.Record(now, failureNetwork); state != breakerClosed {
+		t.Error("the breaker opened with failures outside of the window")
+	}
+	_, _ = b.Record(now, failureNetwork)
+	if state, _ := b.Record(now, failureNetwork); state != breakerOpen {
+		t.Error("the breaker did not open after the consecutive failures")
+	}
+}
+
+func TestBreakerSkipsRequests(t *testing.T) {
+	var lock sync.Mutex
+	var count int
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		lock.Lock()
+		count++
+		lock.Unlock()
+		w.WriteHeader(http.StatusUnauthorized)
+	}))
+	defer ts.Close()
+
+	script, sys := setupMockScriptEnv(fmt.Sprintf(`
+		name="breaker"
+		type="testing"
+
+		function vertical(ctx, domain)
+			local skipped = 0
+			for i=1,8 do
+				local _, err = request(ctx, {['url']="%s"})
+				if (err ~= nil and string.find(err, "failing consistently", 1, true) ~= nil) then
+					skipped = skipped + 1
+				end
+			end
+			new_name(ctx, "skipped" .. skipped .. "." .. domain)
+		end
+	`, ts.URL))
+	if script == nil || sys == nil {
+		t.Fatal("failed to initialize the scripting environment")
+	}
+	defer func() { _ = sys.Shutdown() }()
+
+	var buf bytes.Buffer
+	cfg := sys.Config()
+	cfg.Log = log.New(&buf, "", 0)
+	cfg.AddDomain("owasp.org")
+	script.Input() <- &requests.DNSRequest{Name: "owasp.org", Domain: "owasp.org"}
+
+	select {
+	case <-time.After(10 * time.Second):
+		t.Fatal("the script did not finish the requests")
+	case req := <-script.Output():
+		if d, ok := req.(*requests.DNSRequest); !ok || d.Name != fmt.Sprintf("skipped%d.owasp.org", 8-breakerThreshold) {
+			t.Errorf("the script returned an unexpected request: %v", req)
+		}
+	}
+
+	lock.Lock()
+	if count != breakerThreshold {
+		t.Errorf("expected %d requests and the service received %d", breakerThreshold, count)
+	}
+	lock.Unlock()
+	if !strings.Contains(buf.String(), "breaker: the circuit breaker is open after consecutive failures ending with the authentication error") {
+		t.Errorf("the state transition was not logged: %s", buf.String())
+	}
+}
+
+func TestBreakerIgnoresRotatedKeys(t *testing.T) {
+	var lock sync.Mutex
+	var count int
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		lock.Lock()
+		count++
+		lock.Unlock()
+
+		if r.Header.Get("X-Key") != "f-key" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		_, _ = w.Write([]byte("ok"))
+	}))
+	defer ts.Close()
+
+	var buf bytes.Buffer
+	cfg := config.NewConfig()
+	cfg.Log = log.New(&buf, "", 0)
+	cfg.AddDomain("owasp.org")
+	// The accounts are handed out in order, and only the last account is accepted
+	src := &config.DataSource{Name: "breaker"}
+	for _, account := range []string{"a", "b", "c", "d", "e", "f"} {
+		_ = src.AddCredentials(account, &config.Credentials{Name: "breaker", Apikey: account + "-key"})
+	}
+	cfg.DataSrcConfigs = &config.DataSourceConfig{Datasources: []*config.DataSource{src}}
+
+	sys := newMockSystem(cfg)
+	defer func() { _ = sys.Shutdown() }()
+	script := NewScript(fmt.Sprintf(`
+		name="breaker"
+		type="testing"
+
+		function vertical(ctx, domain)
+			while true do
+				local c = next_credentials()
+				if (c == nil) then
+					return
+				end
+
+				local resp, err = request(ctx, {
+					['url']="%s",
+					['header']={['X-Key']=c.key},
+				})
+				if (err ~= nil and err ~= "") then
+					return
+				elseif (resp.status_code == 401) then
+					credentials_failed(c.account)
+				else
+					new_name(ctx, c.account .. "." .. domain)
+					return
+				end
+			end
+		end
+	`, ts.URL), sys)
+	if script == nil {
+		t.Fatal("failed to initialize the scripting environment")
+	}
+	if err := sys.AddAndStart(script); err != nil {
+		t.Fatalf("failed to start the script: %v", err)
+	}
+	script.Input() <- &requests.DNSRequest{Name: "owasp.org", Domain: "owasp.org"}
+
+	select {
+	case <-time.After(10 * time.Second):
+		t.Fatal("the script did not reach the accepted account")
+	case req := <-script.Output():
+		if d, ok := req.(*requests.DNSRequest); !ok || d.Name != "f.owasp.org" {
+			t.Errorf("the script returned an unexpected request: %v", req)
+		}
+	}
+
+	lock.Lock()
+	if count != 6 {
+		t.Errorf("expected 6 requests and the service received %d", count)
+	}
+	lock.Unlock()
+	if strings.Contains(buf.String(), "circuit breaker") {
+		t.Errorf("the rejected accounts opened the circuit breaker: %s", buf.String())
+	}
+}
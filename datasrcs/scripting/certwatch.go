@@ -0,0 +1,208 @@
+// Copyright © by Jeff Foley 2017-2023. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+// SPDX-License-Identifier: Apache-2.0
+
+package scripting
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"sync"
+	"time"
+
+	// The driver of the crt.sh certwatch database
+	_ "github.com/jackc/pgx/v5/stdlib"
+	lua "github.com/yuin/gopher-lua"
+)
+
+const (
+	// The public PostgreSQL interface of crt.sh
+	certwatchDSN = "postgres://guest@crt.sh:5432/certwatch?sslmode=disable&connect_timeout=10"
+	// The number of certificates requested in each batch
+	defaultCertwatchBatch = 1000
+	// The identities of the certificates, aggregated per certificate like the JSON output of the HTTP API.
+	// Like the %25.domain and domain queries of the HTTP API, the identities equal the domain or are names
+	// within it, and the suffix match is written so that it uses the reverse index of the identities
+	certwatchQuery = `SELECT cai.CERTIFICATE_ID, x509_commonName(cai.CERTIFICATE), string_agg(DISTINCT cai.NAME_VALUE, E'\n'),
+		x509_notAfter(cai.CERTIFICATE)
+	FROM certificate_and_identities cai
+	WHERE plainto_tsquery('certwatch', $1) @@ identities(cai.CERTIFICATE)
+		AND (lower(cai.NAME_VALUE) = lower($1)
+			OR reverse(lower(cai.NAME_VALUE)) LIKE reverse(lower('%.' || $1)))
+		AND ($2 OR x509_notAfter(cai.CERTIFICATE) > now() AT TIME ZONE 'UTC')
+	GROUP BY cai.CERTIFICATE_ID, cai.CERTIFICATE
+	ORDER BY cai.CERTIFICATE_ID
+	LIMIT $3 OFFSET $4`
+)
+
+// certRow is a certificate found in the certwatch database.
+type certRow struct {
+	ID         int64
+	CommonName string
+	NameValue  string
+	NotAfter   time.Time
+}
+
+// certwatchDB provides a batch of the certificates matching the domain identity.
+type certwatchDB interface {
+	certificates(ctx context.Context, domain string, expired bool, limit, offset int) ([]certRow, error)
+}
+
+// The connection pools are shared by the scripts, keyed by the connection string of the database.
+var (
+	certwatchLock sync.Mutex
+	certwatchDBs  = make(map[string]certwatchDB)
+)
+
+type sqlCertwatch struct {
+	db *sql.DB
+}
+
+func certwatchFor(dsn string) (certwatchDB, error) {
+	certwatchLock.Lock()
+	defer certwatchLock.Unlock()
+
+	if db, found := certwatchDBs[dsn]; found {
+		return db, nil
+	}
+
+	db, err := sql.Open("pgx", dsn)
+	if err != nil {
+		return nil, err
+	}
+	db.SetMaxOpenConns(5)
+	db.SetConnMaxIdleTime(time.Minute)
+
+	c := &sqlCertwatch{db: db}
+	certwatchDBs[dsn] = c
+	return c, nil
+}
+
+func (c *sqlCertwatch) certificates(ctx context.Context, domain string, expired bool, limit, offset int) ([]certRow, error) {
+	rows, err := c.db.QueryContext(ctx, certwatchQuery, domain, expired, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var certs []certRow
+	for rows.Next() {
+		var id int64
+		var cn, names sql.NullString
+		var notAfter sql.NullTime
+
+		if err := rows.Scan(&id, &cn, &names, &notAfter); err != nil {
+			return nil, err
+		}
+		certs = append(certs, certRow{ID: id, CommonName: cn.String, NameValue: names.String, NotAfter: notAfter.Time})
+	}
+	return certs, rows.Err()
+}
+
+// entry returns the certificate with the fields of an entry in the JSON output of the crt.sh HTTP API.
+func (r certRow) entry() map[string]interface{} {
+	e := map[string]interface{}{"id": float64(r.ID)}
+
+	if r.CommonName != "" {
+		e["common_name"] = r.CommonName
+	}
+	if r.NameValue != "" {
+		e["name_value"] = r.NameValue
+	}
+	if !r.NotAfter.IsZero() {
+		e["not_after"] = r.NotAfter.UTC().Format("2006-01-02T15:04:05")
+	}
+	return e
+}
+
+// Wrapper so that scripts can query the crt.sh certwatch database for the certificates matching the domain
+// identity. The callback receives each certificate in the form of the JSON output of the HTTP API, and
+// returns false to stop the query. Returns the number of certificates and an error value.
+func (s *Script) certwatchQuery(L *lua.LState) int {
+	ctx, err := extractContext(L.CheckUserData(1))
+	if err != nil {
+		L.Push(lua.LNumber(0))
+		L.Push(lua.LString("No user data parameter or context expired"))
+		return 2
+	}
+
+	opt := L.CheckTable(2)
+	fn := L.CheckFunction(3)
+	domain, found := getStringField(L, opt, "domain")
+	if !found {
+		L.Push(lua.LNumber(0))
+		L.Push(lua.LString("No domain found in the parameters"))
+		return 2
+	}
+
+	batch := defaultCertwatchBatch
+	if n, ok := getNumberField(L, opt, "batch"); ok && n > 0 {
+		batch = int(n)
+	}
+	expired := true
+	if v, ok := L.GetField(opt, "expired").(lua.LBool); ok {
+		expired = bool(v)
+	}
+
+	num, err := s.certwatch(ctx, certwatchDSN, domain, expired, batch, func(r certRow) (bool, error) {
+		if err := L.CallByParam(lua.P{
+			Fn:      fn,
+			NRet:    1,
+			Protect: true,
+		}, toLuaValue(L, r.entry())); err != nil {
+			return false, err
+		}
+
+		ret := L.Get(-1)
+		L.Pop(1)
+		return ret != lua.LFalse, nil
+	})
+	stopForDryRun(L, err)
+
+	L.Push(lua.LNumber(num))
+	if err != nil {
+		L.Push(lua.LString(err.Error()))
+	} else {
+		L.Push(lua.LNil)
+	}
+	return 2
+}
+
+// certwatch requests the certificates in batches, waiting on the rate limit before each batch,
+// until the last batch was received or fn returns false.
+func (s *Script) certwatch(ctx context.Context, dsn, domain string, expired bool, batch int, fn func(certRow) (bool, error)) (int, error) {
+	if s.skipForDryRun("QUERY", "certwatch: "+domain) {
+		return 0, errDryRun
+	}
+
+	db, err := certwatchFor(dsn)
+	if err != nil {
+		return 0, err
+	}
+
+	var num int
+	for offset := 0; ; offset += batch {
+		if contextExpired(ctx) {
+			return num, errors.New("the context expired")
+		}
+
+		numRateLimitChecks(s, s.seconds)
+		rows, err := db.certificates(ctx, domain, expired, batch, offset)
+		s.metrics.queries.Add(1)
+		if err != nil {
+			s.metrics.httpErrors.Add(1)
+			return num, err
+		}
+
+		for _, r := range rows {
+			num++
+			if more, err := fn(r); err != nil || !more {
+				return num, err
+			}
+		}
+		if len(rows) < batch {
+			return num, nil
+		}
+	}
+}
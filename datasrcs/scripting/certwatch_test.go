@@ -0,0 +1,210 @@
+// Copyright © by Jeff Foley 2017-2023. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+// SPDX-License-Identifier: Apache-2.0
+
+package scripting
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/owasp-amass/amass/v4/requests"
+	"github.com/owasp-amass/amass/v4/resources"
+	"github.com/owasp-amass/config/config"
+)
+
+// certwatchFixture returns the certificates shared by the database and the HTTP API fixtures.
+func certwatchFixture() []certRow {
+	rows := []certRow{
+		{ID: 1, CommonName: "www.owasp.org", NameValue: "www.owasp.org\nowasp.org"},
+		{ID: 2, CommonName: "*.dev.owasp.org", NameValue: "*.dev.owasp.org\nMAIL.owasp.org"},
+		{ID: 3, NameValue: "api.owasp.org\nwww.example.com"},
+		{ID: 4, CommonName: "owasp.org"},
+	}
+	for i := 5; i <= 2500; i++ {
+		rows = append(rows, certRow{ID: int64(i), CommonName: fmt.Sprintf("host%d.owasp.org", i%1200)})
+	}
+	return rows
+}
+
+type fakeCertwatch struct {
+	sync.Mutex
+	rows    []certRow
+	err     error
+	batches int
+}
+
+func (f *fakeCertwatch) certificates(ctx context.Context, domain string, expired bool, limit, offset int) ([]certRow, error) {
+	f.Lock()
+	defer f.Unlock()
+
+	f.batches++
+	if f.err != nil {
+		return nil, f.err
+	}
+	if offset >= len(f.rows) {
+		return nil, nil
+	}
+	end := offset + limit
+	if end > len(f.rows) {
+		end = len(f.rows)
+	}
+	return f.rows[offset:end], nil
+}
+
+// Serves the fixture in the JSON form of the crt.sh HTTP API.
+func crtshFixtureServer(rows []certRow) *httptest.Server {
+	type entry struct {
+		ID         int64  `json:"id"`
+		CommonName string `json:"common_name,omitempty"`
+		NameValue  string `json:"name_value,omitempty"`
+	}
+
+	var entries []entry
+	for _, r := range rows {
+		entries = append(entries, entry{ID: r.ID, CommonName: r.CommonName, NameValue: r.NameValue})
+	}
+	body, _ := json.Marshal(entries)
+
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.Contains(r.URL.RawQuery, "owasp.org") {
+			_, _ = w.Write([]byte(`[]`))
+			return
+		}
+		_, _ = w.Write(body)
+	}))
+}
+
+// Runs the crt.sh script for owasp.org and returns the sorted names and the log output.
+func runCrtsh(t *testing.T, postgres bool, url string) ([]string, string) {
+	f, err := resources.GetResourceFile("scripts/cert/crtsh.ads")
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := io.ReadAll(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var lock sync.Mutex
+	var buf bytes.Buffer
+	cfg := config.NewConfig()
+	cfg.Log = log.New(writerFunc(func(p []byte) (int, error) {
+		lock.Lock()
+		defer lock.Unlock()
+		return buf.Write(p)
+	}), "", 0)
+	cfg.AddDomain("owasp.org")
+	sys := newMockSystem(cfg)
+	defer func() { _ = sys.Shutdown() }()
+
+	s := NewScript(string(data)+fmt.Sprintf(`
+function start() end
+
+function datasrc_config()
+    return {['options']={['postgres']=%t}}
+end
+
+function build_url(q, expired)
+    return "%s/?q=" .. q .. "&output=json"
+end
+`, postgres, url), sys)
+	if s == nil {
+		t.Fatal("failed to load the crt.sh script")
+	}
+	if err := sys.AddAndStart(s); err != nil {
+		t.Fatalf("failed to start the crt.sh script: %v", err)
+	}
+	s.Input() <- &requests.DNSRequest{Name: "owasp.org", Domain: "owasp.org"}
+
+	var names []string
+	for {
+		select {
+		case req := <-s.Output():
+			if d, ok := req.(*requests.DNSRequest); ok {
+				names = append(names, d.Name)
+			}
+			continue
+		case <-time.After(time.Second):
+		}
+		break
+	}
+
+	sort.Strings(names)
+	lock.Lock()
+	defer lock.Unlock()
+	return names, buf.String()
+}
+
+type writerFunc func(p []byte) (int, error)
+
+func (f writerFunc) Write(p []byte) (int, error) { return f(p) }
+
+func TestCertwatchMatchesHTTP(t *testing.T) {
+	rows := certwatchFixture()
+	srv := crtshFixtureServer(rows)
+	defer srv.Close()
+
+	fake := &fakeCertwatch{rows: rows}
+	certwatchLock.Lock()
+	certwatchDBs[certwatchDSN] = fake
+	certwatchLock.Unlock()
+	defer func() {
+		certwatchLock.Lock()
+		delete(certwatchDBs, certwatchDSN)
+		certwatchLock.Unlock()
+	}()
+
+	httpNames, _ := runCrtsh(t, false, srv.URL)
+	// The certwatch path must not reach the HTTP API
+	dbNames, logs := runCrtsh(t, true, "http://127.0.0.1:1")
+
+	if len(httpNames) != 1205 {
+		t.Errorf("expected 1205 names from the HTTP API and got %d", len(httpNames))
+	}
+	if strings.Join(httpNames, ",") != strings.Join(dbNames, ",") {
+		t.Errorf("the certwatch names differ from the HTTP API names: %d and %d names: %s", len(dbNames), len(httpNames), logs)
+	}
+
+	fake.Lock()
+	defer fake.Unlock()
+	if fake.batches != 3 {
+		t.Errorf("expected 3 batches and the database received %d queries", fake.batches)
+	}
+}
+
+func TestCertwatchFallback(t *testing.T) {
+	rows := certwatchFixture()[:4]
+	srv := crtshFixtureServer(rows)
+	defer srv.Close()
+
+	certwatchLock.Lock()
+	certwatchDBs[certwatchDSN] = &fakeCertwatch{err: errors.New("connection refused")}
+	certwatchLock.Unlock()
+	defer func() {
+		certwatchLock.Lock()
+		delete(certwatchDBs, certwatchDSN)
+		certwatchLock.Unlock()
+	}()
+
+	names, logs := runCrtsh(t, true, srv.URL)
+	expected := "api.owasp.org,dev.owasp.org,mail.owasp.org,owasp.org,www.owasp.org"
+	if strings.Join(names, ",") != expected {
+		t.Errorf("expected %s from the HTTP API and got %v", expected, names)
+	}
+	if !strings.Contains(logs, "the certwatch query failed, so the HTTP API is used: connection refused") {
+		t.Errorf("the fallback was not logged: %s", logs)
+	}
+}
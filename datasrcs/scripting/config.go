@@ -5,10 +5,18 @@
 package scripting
 
 import (
+	"fmt"
+	"net"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
 	"github.com/caffix/service"
 	"github.com/owasp-amass/amass/v4/format"
 	"github.com/owasp-amass/config/config"
 	lua "github.com/yuin/gopher-lua"
+	"gopkg.in/yaml.v3"
 )
 
 // Wrapper so that scripts can obtain the configuration for the current enumeration.
@@ -26,6 +34,7 @@ func (s *Script) config(L *lua.LState) int {
 		r.RawSetString("mode", lua.LString("normal"))
 	}
 
+	r.RawSetString("verbose", lua.LBool(cfg.Verbose))
 	r.RawSetString("max_dns_queries", lua.LNumber(cfg.MaxDNSQueries))
 
 	scope := L.NewTable()
@@ -124,26 +133,154 @@ func (s *Script) dataSourceConfig(L *lua.LState) int {
 	}
 
 	if creds := dsc.GetCredentials(cfg.Name); creds != nil {
-		c := L.NewTable()
+		tb.RawSetString("credentials", credentialsToTable(L, creds))
+	}
+	// Scripts able to rotate between several accounts can access all the credentials
+	if all := allCredentials(cfg); len(all) > 0 {
+		list := L.NewTable()
 
-		c.RawSetString("name", lua.LString(creds.Name))
-		if creds.Username != "" {
-			c.RawSetString("username", lua.LString(creds.Username))
+		for _, creds := range all {
+			list.Append(credentialsToTable(L, creds))
 		}
-		if creds.Password != "" {
-			c.RawSetString("password", lua.LString(creds.Password))
+		tb.RawSetString("all_credentials", list)
+	}
+
+	if opts := s.options(); len(opts) > 0 {
+		tb.RawSetString("options", toLuaValue(L, opts))
+	}
+
+	L.Push(tb)
+	return 1
+}
+
+func credentialsToTable(L *lua.LState, creds *config.Credentials) *lua.LTable {
+	c := L.NewTable()
+
+	c.RawSetString("name", lua.LString(creds.Name))
+	if creds.Username != "" {
+		c.RawSetString("username", lua.LString(creds.Username))
+	}
+	if creds.Password != "" {
+		c.RawSetString("password", lua.LString(creds.Password))
+	}
+	if creds.Apikey != "" {
+		c.RawSetString("key", lua.LString(creds.Apikey))
+	}
+	if creds.Secret != "" {
+		c.RawSetString("secret", lua.LString(creds.Secret))
+	}
+	return c
+}
+
+// allCredentials returns every set of credentials for the data source, sorted by account name.
+func allCredentials(cfg *config.DataSource) []*config.Credentials {
+	var all []*config.Credentials
+
+	for _, name := range accountNames(cfg) {
+		all = append(all, cfg.Creds[name])
+	}
+	return all
+}
+
+// accountNames returns the sorted names of the accounts that provide credentials for the data source.
+func accountNames(cfg *config.DataSource) []string {
+	var names []string
+
+	for name, creds := range cfg.Creds {
+		if creds != nil {
+			names = append(names, name)
 		}
-		if creds.Apikey != "" {
-			c.RawSetString("key", lua.LString(creds.Apikey))
+	}
+	sort.Strings(names)
+	return names
+}
+
+// isDryRun returns true when the configuration options request a dry run, which
+// logs the requests planned by the scripts without sending them.
+func isDryRun(cfg *config.Config) bool {
+	dry, ok := cfg.Options["dry_run"].(bool)
+	return ok && dry
+}
+
+// options returns the free-form options provided for the data source in the data source configuration
+// file. The file is read once by the script, and the failure to read or parse it is logged.
+func (s *Script) options() map[string]interface{} {
+	s.optsOnce.Do(func() {
+		cfg := s.sys.Config()
+
+		opts, err := loadDataSourceOptions(cfg)
+		if err != nil {
+			cfg.Log.Printf("%s: failed to load the data source options: %v", s.String(), err)
 		}
-		if creds.Secret != "" {
-			c.RawSetString("secret", lua.LString(creds.Secret))
+		s.opts = opts[strings.ToLower(s.String())]
+	})
+	return s.opts
+}
+
+// loadDataSourceOptions returns the options of each data source, keyed by the lowercase name,
+// from the data source configuration file. No options are returned when no file was provided.
+func loadDataSourceOptions(cfg *config.Config) (map[string]map[string]interface{}, error) {
+	p, ok := cfg.Options["datasources"].(string)
+	if !ok || p == "" {
+		return nil, nil
+	}
+
+	path, err := cfg.AbsPathFromConfigDir(p)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var dsc struct {
+		Datasources []struct {
+			Name    string                 `yaml:"name"`
+			Options map[string]interface{} `yaml:"options"`
+		} `yaml:"datasources"`
+	}
+	if err := yaml.Unmarshal(data, &dsc); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	opts := make(map[string]map[string]interface{})
+	for _, src := range dsc.Datasources {
+		if src.Name != "" && len(src.Options) > 0 {
+			opts[strings.ToLower(src.Name)] = src.Options
 		}
-		tb.RawSetString("credentials", c)
 	}
+	return opts, nil
+}
 
-	L.Push(tb)
-	return 1
+// Converts values unmarshalled from the configuration into the equivalent Lua values.
+func toLuaValue(L *lua.LState, v interface{}) lua.LValue {
+	switch t := v.(type) {
+	case bool:
+		return lua.LBool(t)
+	case int:
+		return lua.LNumber(t)
+	case int64:
+		return lua.LNumber(t)
+	case float64:
+		return lua.LNumber(t)
+	case string:
+		return lua.LString(t)
+	case []interface{}:
+		tb := L.NewTable()
+		for _, e := range t {
+			tb.Append(toLuaValue(L, e))
+		}
+		return tb
+	case map[string]interface{}:
+		tb := L.NewTable()
+		for k, e := range t {
+			tb.RawSetString(k, toLuaValue(L, e))
+		}
+		return tb
+	}
+	return lua.LNil
 }
 
 // Wrapper so that scripts can check if a subdomain name is in scope.
@@ -151,7 +288,7 @@ func (s *Script) inScope(L *lua.LState) int {
 	result := lua.LFalse
 
 	if _, err := extractContext(L.CheckUserData(1)); err == nil {
-		if sub := L.CheckString(2); sub != "" && s.sys.Config().IsDomainInScope(sub) {
+		if entry := L.CheckString(2); entry != "" && s.isInScope(entry) {
 			result = lua.LTrue
 		}
 	}
@@ -159,6 +296,46 @@ func (s *Script) inScope(L *lua.LState) int {
 	return 1
 }
 
+// isInScope checks subdomain names, IP addresses and netblocks in CIDR notation against the enumeration scope.
+func (s *Script) isInScope(entry string) bool {
+	cfg := s.sys.Config()
+	if ip := net.ParseIP(entry); ip != nil {
+		return cfg.IsAddressInScope(ip.String())
+	}
+	if _, ipnet, err := net.ParseCIDR(entry); err == nil {
+		return isNetblockInScope(cfg, ipnet)
+	}
+	_, _, found := s.nameInScope(entry)
+	return found
+}
+
+// nameInScope normalizes the name before the scope check, so every script and helper filters names
+// the same way. Returns the normalized name and the enumeration domain that the name belongs to.
+// The decisions are cached for the script, since it checks the same names repeatedly.
+func (s *Script) nameInScope(name string) (string, string, bool) {
+	return s.scope.lookup(s.sys.Config(), name)
+}
+
+// isNetblockInScope returns true when the netblock overlaps the addresses in scope.
+// Like addresses, all netblocks are in scope when the configuration provides no addresses.
+func isNetblockInScope(cfg *config.Config, ipnet *net.IPNet) bool {
+	if len(cfg.Scope.Addresses) == 0 && len(cfg.Scope.CIDRs) == 0 {
+		return true
+	}
+
+	for _, addr := range cfg.Scope.Addresses {
+		if ipnet.Contains(addr) {
+			return true
+		}
+	}
+	for _, cidr := range cfg.Scope.CIDRs {
+		if cidr.Contains(ipnet.IP) || ipnet.Contains(cidr.IP) {
+			return true
+		}
+	}
+	return false
+}
+
 // Wrapper so that scripts can obtain the brute force wordlist for the current enumeration.
 func (s *Script) bruteWordlist(L *lua.LState) int {
 	tb := L.NewTable()
@@ -205,6 +382,29 @@ func (s *Script) checkRateLimit(L *lua.LState) int {
 	return 0
 }
 
+// Wrapper so scripts can pause, such as when a service requests that a query be retried later.
+func (s *Script) sleep(L *lua.LState) int {
+	ctx, err := extractContext(L.CheckUserData(1))
+	if err != nil {
+		return 0
+	}
+
+	secs := float64(L.CheckNumber(2))
+	if secs <= 0 {
+		return 0
+	}
+
+	t := time.NewTimer(time.Duration(secs * float64(time.Second)))
+	defer t.Stop()
+
+	select {
+	case <-ctx.Done():
+	case <-s.Done():
+	case <-t.C:
+	}
+	return 0
+}
+
 // Wrapper so that scripts can request the path to the Amass output directory.
 func (s *Script) outputdir(L *lua.LState) int {
 	var dir string
@@ -0,0 +1,222 @@
+// Copyright © by Jeff Foley 2017-2023. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+// SPDX-License-Identifier: Apache-2.0
+
+package scripting
+
+import (
+	"bytes"
+	"log"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/owasp-amass/amass/v4/requests"
+	"github.com/owasp-amass/config/config"
+)
+
+const optionsScript = `
+	name="options"
+	type="testing"
+
+	function vertical(ctx, domain)
+		local cfg = datasrc_config()
+		if (cfg == nil or cfg.options == nil) then
+			return
+		end
+
+		if (cfg.options.enabled and cfg.options.max_entries == 100) then
+			new_name(ctx, cfg.options.label .. "." .. domain)
+		end
+	end
+`
+
+// Returns a configuration providing the data source configuration file with the content.
+func optionsConfig(t *testing.T, content string, logger *log.Logger) *config.Config {
+	path := filepath.Join(t.TempDir(), "datasources.yaml")
+	if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+		t.Fatalf("failed to write the data source configuration: %v", err)
+	}
+
+	cfg := config.NewConfig()
+	if logger != nil {
+		cfg.Log = logger
+	}
+	cfg.Options = map[string]interface{}{"datasources": path}
+	cfg.DataSrcConfigs = &config.DataSourceConfig{
+		Datasources:   []*config.DataSource{{Name: "Options"}},
+		GlobalOptions: make(map[string]int),
+	}
+	return cfg
+}
+
+func TestDataSourceOptions(t *testing.T) {
+	cfg := optionsConfig(t, `datasources:
+  - name: Options
+    options:
+      enabled: true
+      max_entries: 100
+      label: www
+`, nil)
+	sys := newMockSystem(cfg)
+	defer func() { _ = sys.Shutdown() }()
+
+	script := NewScript(optionsScript, sys)
+	if script == nil {
+		t.Fatal("failed to load the script")
+	}
+	if err := sys.AddAndStart(script); err != nil {
+		t.Fatalf("failed to start the script: %v", err)
+	}
+
+	domain := "owasp.org"
+	cfg.AddDomain(domain)
+	script.Input() <- &requests.DNSRequest{Domain: domain}
+
+	timer := time.NewTimer(10 * time.Second)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		t.Error("the options were not provided to the script")
+	case req := <-script.Output():
+		if d, ok := req.(*requests.DNSRequest); !ok || d.Name != "www."+domain {
+			t.Errorf("the script returned an unexpected request: %v", req)
+		}
+	}
+}
+
+func TestDataSourceOptionsInvalid(t *testing.T) {
+	var buf bytes.Buffer
+	cfg := optionsConfig(t, "datasources:\n  - name: [Options\n", log.New(&buf, "", 0))
+
+	s := NewScript(optionsScript, newMockSystem(cfg))
+	if s == nil {
+		t.Fatal("failed to load the script")
+	}
+	if opts := s.options(); opts != nil {
+		t.Errorf("options were returned from the invalid file: %v", opts)
+	}
+	if !strings.Contains(buf.String(), "failed to load the data source options") {
+		t.Errorf("the invalid file was not reported: %s", buf.String())
+	}
+}
+
+func TestDataSourceAllCredentials(t *testing.T) {
+	script, sys := setupMockScriptEnv(`
+		name="credentials"
+		type="testing"
+
+		function vertical(ctx, domain)
+			local cfg = datasrc_config()
+			if (cfg == nil or cfg.all_credentials == nil) then
+				return
+			end
+
+			for _, c in pairs(cfg.all_credentials) do
+				new_name(ctx, c.key .. "." .. domain)
+			end
+		end
+	`)
+	if script == nil || sys == nil {
+		t.Fatal("failed to initialize the scripting environment")
+	}
+	defer func() { _ = sys.Shutdown() }()
+
+	cfg := sys.Config()
+	cfg.DataSrcConfigs = &config.DataSourceConfig{
+		Datasources: []*config.DataSource{{
+			Name: "credentials",
+			Creds: map[string]*config.Credentials{
+				"second": {Name: "second", Apikey: "two"},
+				"first":  {Name: "first", Apikey: "one"},
+			},
+		}},
+		GlobalOptions: make(map[string]int),
+	}
+
+	domain := "owasp.org"
+	cfg.AddDomain(domain)
+	script.Input() <- &requests.DNSRequest{Domain: domain}
+
+	timer := time.NewTimer(10 * time.Second)
+	defer timer.Stop()
+
+	for _, expected := range []string{"one." + domain, "two." + domain} {
+		select {
+		case <-timer.C:
+			t.Fatal("the credentials were not provided to the script")
+		case req := <-script.Output():
+			if d, ok := req.(*requests.DNSRequest); !ok || d.Name != expected {
+				t.Errorf("the script returned %v instead of %s", req, expected)
+			}
+		}
+	}
+}
+
+func TestIsInScope(t *testing.T) {
+	cfg := config.NewConfig()
+	cfg.AddDomain("owasp.org")
+	for _, cidr := range []string{"192.168.1.0/24", "2001:db8::/32"} {
+		_, ipnet, _ := net.ParseCIDR(cidr)
+		cfg.Scope.CIDRs = append(cfg.Scope.CIDRs, ipnet)
+	}
+	cfg.Scope.Addresses = append(cfg.Scope.Addresses, net.ParseIP("10.0.0.1"))
+
+	tests := []struct {
+		entry    string
+		expected bool
+	}{
+		{"www.owasp.org", true},
+		{"www.example.com", false},
+		{"192.168.1.100", true},
+		{"192.168.2.1", false},
+		{"10.0.0.1", true},
+		{"2001:db8:1::1", true},
+		{"2001:db9::1", false},
+		{"192.168.0.0/16", true},
+		{"192.168.1.128/25", true},
+		{"10.0.0.0/24", true},
+		{"172.16.0.0/12", false},
+		{"2001:db8:ffff::/48", true},
+		{"2001:dead::/32", false},
+	}
+	for _, test := range tests {
+		if got := newScopeScript(cfg).isInScope(test.entry); got != test.expected {
+			t.Errorf("%s: expected %t and got %t", test.entry, test.expected, got)
+		}
+	}
+}
+
+func TestNameInScope(t *testing.T) {
+	cfg := config.NewConfig()
+	cfg.AddDomain("owasp.org")
+	s := newScopeScript(cfg)
+
+	tests := []struct {
+		name     string
+		expected string
+		domain   string
+	}{
+		{"owasp.org", "owasp.org", "owasp.org"},
+		{"www.owasp.org", "www.owasp.org", "owasp.org"},
+		{"WWW.OWASP.Org", "www.owasp.org", "owasp.org"},
+		{" www.owasp.org. ", "www.owasp.org", "owasp.org"},
+		{"*.dev.owasp.org", "dev.owasp.org", "owasp.org"},
+		{"www.notowasp.org", "", ""},
+		{"owasp.org.example.com", "", ""},
+		// The malformed names are dropped and counted by the enumeration
+		{"www..owasp.org", "www..owasp.org", "owasp.org"},
+		{"-www.owasp.org", "-www.owasp.org", "owasp.org"},
+		{"", "", ""},
+	}
+	for _, test := range tests {
+		name, domain, found := s.nameInScope(test.name)
+		if found != (test.expected != "") || name != test.expected || domain != test.domain {
+			t.Errorf("%q: expected (%q, %q) and got (%q, %q, %t)", test.name, test.expected, test.domain, name, domain, found)
+		}
+	}
+}
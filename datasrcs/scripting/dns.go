@@ -29,6 +29,9 @@ const (
 	maxSweepSize     = 1000
 )
 
+// The time allowed for a zone transfer connection and each read, when the script does not provide one.
+const defaultXfrTimeout = 15 * time.Second
+
 var (
 	sweepLock   sync.Mutex
 	sweepMaxCh  chan struct{}         = make(chan struct{}, maxSweepSize)
@@ -264,8 +267,7 @@ func (s *Script) zoneWalk(L *lua.LState) int {
 		return 1
 	}
 
-	domain := s.sys.Config().WhichDomain(name)
-	if domain == "" {
+	if _, _, found := s.nameInScope(name); !found {
 		L.Push(lua.LString("the name " + name + " was not in scope"))
 		return 1
 	}
@@ -282,13 +284,11 @@ func (s *Script) zoneWalk(L *lua.LState) int {
 	}
 
 	for _, nsec := range names {
-		name := resolve.RemoveLastDot(nsec.NextDomain)
-
-		if domain := s.sys.Config().WhichDomain(name); domain != "" {
-			s.Output() <- &requests.DNSRequest{
+		if name, domain, found := s.nameInScope(nsec.NextDomain); found {
+			s.emit(ctx, &requests.DNSRequest{
 				Name:   name,
 				Domain: domain,
-			}
+			})
 		}
 	}
 
@@ -318,33 +318,40 @@ func (s *Script) wrapZoneTransfer(L *lua.LState) int {
 		return 2
 	}
 
-	domain := s.sys.Config().WhichDomain(name)
-	if domain == "" {
+	_, domain, found := s.nameInScope(name)
+	if !found {
 		L.Push(lua.LNil)
 		L.Push(lua.LString("the name " + name + " was not in scope"))
 		return 2
 	}
+	// The optional timeout is provided in seconds
+	timeout := time.Duration(L.OptInt(4, 0)) * time.Second
 
 	tb := L.NewTable()
-	if reqs, err := ZoneTransfer(ctx, name, domain, server); err == nil && len(reqs) > 0 {
-		for _, req := range reqs {
-			for _, rr := range req.Records {
-				entry := L.NewTable()
-				entry.RawSetString("rrname", lua.LString(rr.Name))
-				entry.RawSetString("rrtype", lua.LNumber(rr.Type))
-				entry.RawSetString("rrdata", lua.LString(rr.Data))
-				tb.Append(entry)
-			}
-			// Zone Transfers can reveal DNS wildcards
-			if n := amassdns.RemoveAsteriskLabel(req.Name); len(n) < len(req.Name) {
-				// Signal the wildcard discovery
-				s.Output() <- &requests.DNSRequest{
-					Name:   "www." + n,
-					Domain: req.Domain,
-				}
-			} else {
-				s.Output() <- req
-			}
+	reqs, err := ZoneTransfer(ctx, name, domain, server, timeout)
+	if err != nil {
+		L.Push(tb)
+		L.Push(lua.LString(err.Error()))
+		return 2
+	}
+
+	for _, req := range reqs {
+		for _, rr := range req.Records {
+			entry := L.NewTable()
+			entry.RawSetString("rrname", lua.LString(rr.Name))
+			entry.RawSetString("rrtype", lua.LNumber(rr.Type))
+			entry.RawSetString("rrdata", lua.LString(rr.Data))
+			tb.Append(entry)
+		}
+		// Zone Transfers can reveal DNS wildcards
+		if n := amassdns.RemoveAsteriskLabel(req.Name); len(n) < len(req.Name) {
+			// Signal the wildcard discovery
+			s.emit(ctx, &requests.DNSRequest{
+				Name:   "www." + n,
+				Domain: req.Domain,
+			})
+		} else {
+			s.emit(ctx, req)
 		}
 	}
 	L.Push(tb)
@@ -352,17 +359,24 @@ func (s *Script) wrapZoneTransfer(L *lua.LState) int {
 	return 2
 }
 
-// ZoneTransfer attempts a DNS zone transfer using the provided server.
-// The returned slice contains all the records discovered from the zone transfer.
-func ZoneTransfer(ctx context.Context, sub, domain, server string) ([]*requests.DNSRequest, error) {
-	timeout := 15 * time.Second
+// ZoneTransfer attempts a DNS zone transfer using the provided server, which can include the port.
+// The timeout limits the connection and each read, and the default of 15 seconds is used when it is
+// not greater than zero. The returned slice contains all the records discovered from the zone transfer,
+// and an error is returned when the server refuses the transfer.
+func ZoneTransfer(ctx context.Context, sub, domain, server string, timeout time.Duration) ([]*requests.DNSRequest, error) {
+	if timeout <= 0 {
+		timeout = defaultXfrTimeout
+	}
 	var results []*requests.DNSRequest
 
 	// Set the maximum time allowed for making the connection
 	tctx, cancel := context.WithTimeout(ctx, timeout)
 	defer cancel()
 
-	addr := net.JoinHostPort(server, "53")
+	addr := server
+	if _, _, err := net.SplitHostPort(server); err != nil {
+		addr = net.JoinHostPort(server, "53")
+	}
 	conn, err := amassnet.DialContext(tctx, "tcp", addr)
 	if err != nil {
 		return results, fmt.Errorf("zone xfr error: Failed to obtain TCP connection to [%s]: %v", addr, err)
@@ -383,12 +397,11 @@ func ZoneTransfer(ctx context.Context, sub, domain, server string) ([]*requests.
 	}
 
 	for en := range in {
-		reqs := getXfrRequests(en, domain)
-		if reqs == nil {
-			continue
+		if en.Error != nil {
+			return results, fmt.Errorf("DNS zone transfer error for [%s]: %v", addr, en.Error)
 		}
 
-		results = append(results, reqs...)
+		results = append(results, getXfrRequests(en, domain)...)
 	}
 	return results, nil
 }
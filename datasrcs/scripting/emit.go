@@ -0,0 +1,51 @@
+// Copyright © by Jeff Foley 2017-2023. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+// SPDX-License-Identifier: Apache-2.0
+
+package scripting
+
+import (
+	"context"
+)
+
+// emit sends the finding through the bounded output of the script. While the output is full, the
+// script waits for the enumeration to catch up, so a script providing thousands of names does not
+// grow the memory without limit. When the drop_when_full option of the data source is set, the
+// findings are dropped and counted instead of waiting. Nothing is sent once the context expired
+// or the script stopped. Returns true when the finding was sent.
+func (s *Script) emit(ctx context.Context, finding interface{}) bool {
+	if contextExpired(ctx) {
+		return false
+	}
+	select {
+	case <-s.Done():
+		return false
+	default:
+	}
+
+	if s.dropFull {
+		select {
+		case s.Output() <- finding:
+		default:
+			s.metrics.dropped.Add(1)
+			return false
+		}
+	} else {
+		select {
+		case <-ctx.Done():
+			return false
+		case <-s.Done():
+			return false
+		case s.Output() <- finding:
+		}
+	}
+
+	s.metrics.findings.Add(1)
+	return true
+}
+
+// dropWhenFull returns true when the drop_when_full option was set for the data source.
+func (s *Script) dropWhenFull() bool {
+	drop, _ := s.options()["drop_when_full"].(bool)
+	return drop
+}
@@ -0,0 +1,113 @@
+// Copyright © by Jeff Foley 2017-2023. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+// SPDX-License-Identifier: Apache-2.0
+
+package scripting
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/owasp-amass/amass/v4/requests"
+	"github.com/owasp-amass/config/config"
+)
+
+const emitScript = `
+	name="%s"
+	type="testing"
+
+	function vertical(ctx, domain)
+		for i=1,100 do
+			new_name(ctx, "host" .. i .. "." .. domain)
+		end
+	end
+`
+
+// waitForMetrics returns true when the condition is met by the script metrics before the timeout.
+func waitForMetrics(s *Script, cond func(m Metrics) bool) bool {
+	for deadline := time.Now().Add(5 * time.Second); time.Now().Before(deadline); {
+		if cond(s.Metrics()) {
+			return true
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	return false
+}
+
+func TestEmitBackpressure(t *testing.T) {
+	s, sys := setupMockScriptEnv(fmt.Sprintf(emitScript, "backpressure"))
+	if s == nil || sys == nil {
+		t.Fatal("failed to initialize the scripting environment")
+	}
+	defer func() { _ = sys.Shutdown() }()
+
+	script := s.(*Script)
+	size := int64(cap(s.Output()))
+	sys.Config().AddDomain("owasp.org")
+	s.Input() <- &requests.DNSRequest{Name: "owasp.org", Domain: "owasp.org"}
+
+	if !waitForMetrics(script, func(m Metrics) bool { return m.QueueDepth == size }) {
+		t.Fatalf("the output of the script was not filled: %+v", script.Metrics())
+	}
+	// The script must wait for the slow consumer instead of buffering the remaining names
+	time.Sleep(100 * time.Millisecond)
+	if m := script.Metrics(); m.Findings != size || m.Dropped != 0 {
+		t.Errorf("the findings were not bounded by the output: %+v", m)
+	}
+
+	for i := 0; i < 5; i++ {
+		<-s.Output()
+	}
+	if !waitForMetrics(script, func(m Metrics) bool { return m.Findings == size+5 }) {
+		t.Errorf("the script did not continue after the consumer caught up: %+v", script.Metrics())
+	}
+
+	if err := s.Stop(); err != nil {
+		t.Fatalf("failed to stop the script: %v", err)
+	}
+	sent := script.Metrics().Findings
+	time.Sleep(100 * time.Millisecond)
+	if m := script.Metrics(); m.Findings != sent || m.Findings >= 100 {
+		t.Errorf("findings were sent after the script stopped: %+v", m)
+	}
+}
+
+func TestEmitDropWhenFull(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "datasources.yaml")
+	data := []byte(`datasources:
+  - name: Drop
+    options:
+      drop_when_full: true
+`)
+	if err := os.WriteFile(path, data, 0600); err != nil {
+		t.Fatalf("failed to write the data source configuration: %v", err)
+	}
+
+	cfg := config.NewConfig()
+	cfg.Options = map[string]interface{}{"datasources": path}
+	cfg.AddDomain("owasp.org")
+	sys := newMockSystem(cfg)
+	defer func() { _ = sys.Shutdown() }()
+
+	s := NewScript(fmt.Sprintf(emitScript, "Drop"), sys)
+	if s == nil {
+		t.Fatal("failed to load the script")
+	}
+	if err := sys.AddAndStart(s); err != nil {
+		t.Fatalf("failed to start the script: %v", err)
+	}
+
+	s.Input() <- &requests.DNSRequest{Name: "owasp.org", Domain: "owasp.org"}
+	// The script does not wait for the consumer, so all the names are handled without reading the output
+	if !waitForMetrics(s, func(m Metrics) bool { return m.Findings+m.Dropped == 100 }) {
+		t.Fatalf("the script waited for the consumer: %+v", s.Metrics())
+	}
+
+	size := int64(cap(s.Output()))
+	if m := s.Metrics(); m.Findings != size || m.Dropped != 100-size || m.QueueDepth != size {
+		t.Errorf("expected %d findings and %d dropped, and got %+v", size, 100-size, m)
+	}
+}
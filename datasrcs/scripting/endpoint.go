@@ -0,0 +1,70 @@
+// Copyright © by Jeff Foley 2017-2023. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+// SPDX-License-Identifier: Apache-2.0
+
+package scripting
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+
+	lua "github.com/yuin/gopher-lua"
+)
+
+// Matches the verbs of a format string, such as those accepted by the Lua string.format function.
+var fmtVerbRE = regexp.MustCompile(`%[-+ #0-9.]*[a-zA-Z%]`)
+
+// Wrapper so that scripts can obtain the endpoint URL, which the url option of
+// the data source configuration replaces. An invalid override raises an error.
+func (s *Script) endpoint(L *lua.LState) int {
+	def := L.CheckString(1)
+
+	u := def
+	if opts := s.options(); opts != nil {
+		if o, ok := opts["url"].(string); ok && strings.TrimSpace(o) != "" {
+			u = strings.TrimSpace(o)
+		}
+	}
+
+	if err := checkEndpoint(def, u); err != nil {
+		L.RaiseError("the url option %q is invalid: %v", u, err)
+		return 0
+	}
+
+	L.Push(lua.LString(u))
+	return 1
+}
+
+// checkEndpoint returns an error when the override is not an HTTP URL
+// providing the same format verbs, in the same order, as the default.
+func checkEndpoint(def, override string) error {
+	expected := fmtVerbs(def)
+	if verbs := fmtVerbs(override); strings.Join(verbs, "") != strings.Join(expected, "") {
+		return fmt.Errorf("expected the format verbs %v and found %v", expected, verbs)
+	}
+
+	u, err := url.Parse(fmtVerbRE.ReplaceAllString(override, "x"))
+	if err != nil {
+		return err
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return fmt.Errorf("the scheme %q is not supported", u.Scheme)
+	}
+	if u.Host == "" {
+		return fmt.Errorf("no host was provided")
+	}
+	return nil
+}
+
+func fmtVerbs(s string) []string {
+	var verbs []string
+
+	for _, v := range fmtVerbRE.FindAllString(s, -1) {
+		if v != "%%" {
+			verbs = append(verbs, v)
+		}
+	}
+	return verbs
+}
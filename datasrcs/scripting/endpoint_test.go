@@ -0,0 +1,114 @@
+// Copyright © by Jeff Foley 2017-2023. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+// SPDX-License-Identifier: Apache-2.0
+
+package scripting
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/owasp-amass/amass/v4/requests"
+	"github.com/owasp-amass/config/config"
+)
+
+func TestCheckEndpoint(t *testing.T) {
+	tests := []struct {
+		name     string
+		def      string
+		override string
+		valid    bool
+	}{
+		{"Test 1: Default", "https://crt.sh/?q=%s&output=json", "https://crt.sh/?q=%s&output=json", true},
+		{"Test 2: Mirror", "https://crt.sh/?q=%s&output=json", "http://10.0.0.1:8080/crtsh?q=%s&output=json", true},
+		{"Test 3: Several verbs", "http://www.sitedossier.com/parentdomain/%s/%d", "https://cache.example.com/sd/%s/%d", true},
+		{"Test 4: No verbs", "https://html.duckduckgo.com/html/", "https://ddg.example.com/html/", true},
+		{"Test 5: Escaped percent", "https://crt.sh/?q=%s", "https://crt.example.com/?q=%%25.%s", true},
+		{"Test 6: Missing verb", "https://crt.sh/?q=%s&output=json", "https://crt.example.com/?output=json", false},
+		{"Test 7: Extra verb", "https://html.duckduckgo.com/html/", "https://ddg.example.com/%s/", false},
+		{"Test 8: Verbs out of order", "http://www.sitedossier.com/parentdomain/%s/%d", "https://cache.example.com/sd/%d/%s", false},
+		{"Test 9: Unsupported scheme", "https://crt.sh/?q=%s", "ftp://crt.example.com/?q=%s", false},
+		{"Test 10: Missing host", "https://crt.sh/?q=%s", "/crtsh?q=%s", false},
+	}
+
+	for _, tt := range tests {
+		if err := checkEndpoint(tt.def, tt.override); (err == nil) != tt.valid {
+			t.Errorf("Error Event %s: was expecting valid to be %t, got the error %v", tt.name, tt.valid, err)
+		}
+	}
+}
+
+func TestEndpointOption(t *testing.T) {
+	script := `
+		name="endpoint"
+		type="testing"
+
+		local fmtstr = "https://api.owasp.org/?domain=%s"
+
+		function start()
+			fmtstr = endpoint(fmtstr)
+		end
+
+		function vertical(ctx, domain)
+			local u = string.format(fmtstr, domain)
+			new_name(ctx, string.match(u, "^https?://([^/:]+)"))
+		end
+	`
+
+	tests := []struct {
+		override string
+		valid    bool
+	}{
+		{"", true},
+		{"https://www.owasp.org/mirror/?domain=%s", true},
+		{"https://www.owasp.org/mirror/", false},
+	}
+	for _, test := range tests {
+		path := filepath.Join(t.TempDir(), "datasources.yaml")
+		data := "datasources:\n  - name: Endpoint\n"
+		if test.override != "" {
+			data += fmt.Sprintf("    options:\n      url: %s\n", test.override)
+		}
+		if err := os.WriteFile(path, []byte(data), 0600); err != nil {
+			t.Fatalf("failed to write the data source configuration: %v", err)
+		}
+
+		cfg := config.NewConfig()
+		cfg.Options = map[string]interface{}{"datasources": path}
+		cfg.AddDomain("owasp.org")
+		sys := newMockSystem(cfg)
+
+		s := NewScript(script, sys)
+		if s == nil {
+			t.Fatal("failed to initialize the scripting environment")
+		}
+
+		err := s.Start()
+		if (err == nil) != test.valid {
+			t.Errorf("override %q: was expecting valid to be %t, got the start error %v", test.override, test.valid, err)
+		}
+		if err != nil {
+			_ = sys.Shutdown()
+			continue
+		}
+
+		s.Input() <- &requests.DNSRequest{Domain: "owasp.org"}
+		select {
+		case <-time.After(10 * time.Second):
+			t.Errorf("override %q: the script did not provide a name", test.override)
+		case req := <-s.Output():
+			expected := "www.owasp.org"
+			if test.override == "" {
+				expected = "api.owasp.org"
+			}
+			if d, ok := req.(*requests.DNSRequest); !ok || d.Name != expected {
+				t.Errorf("override %q: the script returned an unexpected request: %v", test.override, req)
+			}
+		}
+		_ = s.Stop()
+		_ = sys.Shutdown()
+	}
+}
@@ -0,0 +1,92 @@
+// Copyright © by Jeff Foley 2017-2023. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+// SPDX-License-Identifier: Apache-2.0
+
+package scripting
+
+import (
+	"context"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/owasp-amass/config/config"
+)
+
+// defaultWhoisInterval is the time between the requests sent to a host by the horizontal callbacks, when
+// none was configured and the rate limit of the data source is shorter. The WHOIS and RDAP servers throttle
+// the lookups independently, and the smaller registries ban the clients sending a few lookups per second.
+const defaultWhoisInterval = 2 * time.Second
+
+// hostInterval returns the configured time between the requests sent to the host, and false when
+// it was not configured. The host_rate_limit option provides the default number of seconds between
+// the requests sent to a host, and the host_rate_limits option replaces it for specific hosts.
+func hostInterval(cfg *config.Config, host string) (time.Duration, bool) {
+	v, found := cfg.Options["host_rate_limit"]
+	if secs, ok := hostOption(cfg.Options["host_rate_limits"], host); ok {
+		v, found = secs, true
+	}
+	if !found {
+		return 0, false
+	}
+	return time.Duration(optionSeconds(v) * float64(time.Second)), true
+}
+
+// hostOption returns the value provided for the host by the host_rate_limits option.
+func hostOption(opt interface{}, host string) (interface{}, bool) {
+	hosts, ok := opt.(map[string]interface{})
+	if !ok {
+		return nil, false
+	}
+
+	host = strings.TrimSuffix(host, ".")
+	for h, secs := range hosts {
+		if strings.EqualFold(strings.TrimSuffix(h, "."), host) {
+			return secs, true
+		}
+	}
+	return nil, false
+}
+
+func optionSeconds(v interface{}) float64 {
+	switch s := v.(type) {
+	case int:
+		return float64(s)
+	case float64:
+		return s
+	}
+	return 0
+}
+
+// takeForURL waits for the limiter shared by the data sources requesting the host in the URL.
+// The host_rate_limits option of the data source replaces the configured time for the requests
+// it sends. The rate limit of the data source is used for the host when none was configured, so
+// the data sources and callbacks requesting the same host cannot exceed it together, and the
+// horizontal callbacks wait at least the conservative time kept for the WHOIS servers.
+func (s *Script) takeForURL(ctx context.Context, u string) error {
+	p, err := url.Parse(u)
+	if err != nil {
+		return nil
+	}
+
+	interval, found := hostInterval(s.sys.Config(), p.Hostname())
+	if secs, ok := hostOption(s.options()["host_rate_limits"], p.Hostname()); ok {
+		interval, found = time.Duration(optionSeconds(secs)*float64(time.Second)), true
+	}
+	if !found {
+		interval = time.Duration(s.seconds) * time.Second
+		if s.whois.Load() && interval < defaultWhoisInterval {
+			interval = defaultWhoisInterval
+		}
+	}
+	if interval <= 0 {
+		return nil
+	}
+
+	if hosts := s.sys.HostLimiters(); hosts != nil {
+		if l := hosts.Limiter(p.Hostname()); l != nil {
+			return l.Take(ctx, interval)
+		}
+	}
+	return nil
+}
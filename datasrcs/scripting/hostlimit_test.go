@@ -0,0 +1,198 @@
+// Copyright © by Jeff Foley 2017-2023. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+// SPDX-License-Identifier: Apache-2.0
+
+package scripting
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/owasp-amass/amass/v4/requests"
+	"github.com/owasp-amass/config/config"
+)
+
+func TestHostLimiterShared(t *testing.T) {
+	var lock sync.Mutex
+	var arrivals []time.Time
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		lock.Lock()
+		arrivals = append(arrivals, time.Now())
+		lock.Unlock()
+	}))
+	defer ts.Close()
+
+	// The rate limit of the scripts is used for the host without the options
+	interval := time.Second
+	cfg := config.NewConfig()
+	cfg.AddDomain("owasp.org")
+
+	sys := newMockSystem(cfg)
+	defer func() { _ = sys.Shutdown() }()
+
+	for _, name := range []string{"first", "second"} {
+		s := NewScript(fmt.Sprintf(`
+			name="%s"
+			type="testing"
+
+			function start()
+				set_rate_limit(1)
+			end
+
+			function vertical(ctx, domain)
+				request(ctx, {['url']="%s"})
+			end
+		`, name, ts.URL), sys)
+		if s == nil {
+			t.Fatalf("failed to load the %s script", name)
+		}
+		if err := sys.AddAndStart(s); err != nil {
+			t.Fatalf("failed to start the %s script: %v", name, err)
+		}
+		s.Input() <- &requests.DNSRequest{Name: "owasp.org", Domain: "owasp.org"}
+	}
+
+	for deadline := time.Now().Add(5 * time.Second); time.Now().Before(deadline); {
+		lock.Lock()
+		n := len(arrivals)
+		lock.Unlock()
+		if n == 2 {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	lock.Lock()
+	defer lock.Unlock()
+	if len(arrivals) != 2 {
+		t.Fatalf("expected two requests and the server received %d", len(arrivals))
+	}
+	sort.Slice(arrivals, func(i, j int) bool { return arrivals[i].Before(arrivals[j]) })
+	// Allow for the time between the reserved slot and the arrival of the first request
+	if gap := arrivals[1].Sub(arrivals[0]); gap < interval-50*time.Millisecond {
+		t.Errorf("the requests of the scripts were only %v apart", gap)
+	}
+}
+
+func TestHostInterval(t *testing.T) {
+	tests := []struct {
+		label    string
+		options  map[string]interface{}
+		host     string
+		interval time.Duration
+		found    bool
+	}{
+		{"Unset", nil, "crt.sh", 0, false},
+		{"Default", map[string]interface{}{"host_rate_limit": 2}, "crt.sh", 2 * time.Second, true},
+		{"Disabled", map[string]interface{}{"host_rate_limit": 0}, "crt.sh", 0, true},
+		{"Host", map[string]interface{}{
+			"host_rate_limit":  2,
+			"host_rate_limits": map[string]interface{}{"CRT.sh.": 0.5},
+		}, "crt.sh", 500 * time.Millisecond, true},
+		{"OtherHost", map[string]interface{}{
+			"host_rate_limits": map[string]interface{}{"crt.sh": 0.5},
+		}, "api.securitytrails.com", 0, false},
+	}
+
+	for _, test := range tests {
+		cfg := config.NewConfig()
+		cfg.Options = test.options
+
+		interval, found := hostInterval(cfg, test.host)
+		if interval != test.interval || found != test.found {
+			t.Errorf("%s: expected %v and %t, and got %v and %t", test.label, test.interval, test.found, interval, found)
+		}
+	}
+}
+
+func TestWhoisHostIntervals(t *testing.T) {
+	tests := []struct {
+		label     string
+		options   string
+		intervals map[string]time.Duration
+	}{
+		{"Configured", `
+    options:
+      host_rate_limits:
+        127.0.0.1: 0.2
+        localhost: 0.6
+`, map[string]time.Duration{"127.0.0.1": 200 * time.Millisecond, "localhost": 600 * time.Millisecond}},
+		// The horizontal callbacks wait at least the conservative time for the hosts without a limit
+		{"Fallback", "", map[string]time.Duration{"127.0.0.1": defaultWhoisInterval}},
+	}
+
+	for _, test := range tests {
+		var lock sync.Mutex
+		arrivals := make(map[string][]time.Time)
+		ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			lock.Lock()
+			defer lock.Unlock()
+
+			host, _, _ := strings.Cut(r.Host, ":")
+			arrivals[host] = append(arrivals[host], time.Now())
+		}))
+
+		path := filepath.Join(t.TempDir(), "datasources.yaml")
+		if err := os.WriteFile(path, []byte("datasources:\n  - name: Whois\n"+test.options), 0600); err != nil {
+			t.Fatalf("%s: failed to write the data source configuration: %v", test.label, err)
+		}
+
+		cfg := config.NewConfig()
+		cfg.Options = map[string]interface{}{"datasources": path}
+		cfg.AddDomain("owasp.org")
+		sys := newMockSystem(cfg)
+
+		var urls []string
+		for host := range test.intervals {
+			urls = append(urls, strings.Replace(ts.URL, "127.0.0.1", host, 1))
+		}
+		s := NewScript(fmt.Sprintf(`
+			name="Whois"
+			type="api"
+
+			function horizontal(ctx, domain)
+				for _, u in pairs({"%s"}) do
+					for i=1,3 do
+						request(ctx, {['url']=u})
+					end
+				end
+			end
+		`, strings.Join(urls, `","`)), sys)
+		if s == nil {
+			t.Fatalf("%s: failed to load the script", test.label)
+		}
+		if err := sys.AddAndStart(s); err != nil {
+			t.Fatalf("%s: failed to start the script: %v", test.label, err)
+		}
+		s.Input() <- &requests.WhoisRequest{Domain: "owasp.org"}
+		// The sentinel is only accepted once the horizontal callback returned
+		s.Input() <- struct{}{}
+
+		lock.Lock()
+		for host, interval := range test.intervals {
+			times := arrivals[host]
+			if len(times) != 3 {
+				t.Errorf("%s: expected three requests for %s and the server received %d", test.label, host, len(times))
+				continue
+			}
+			for i := 1; i < len(times); i++ {
+				// Allow for the time between the reserved slot and the arrival of the request
+				if gap := times[i].Sub(times[i-1]); gap < interval-50*time.Millisecond || gap > interval+500*time.Millisecond {
+					t.Errorf("%s: the requests for %s were %v apart, expected %v", test.label, host, gap, interval)
+				}
+			}
+		}
+		lock.Unlock()
+
+		_ = sys.Shutdown()
+		ts.Close()
+	}
+}
@@ -5,13 +5,19 @@
 package scripting
 
 import (
+	"archive/zip"
+	"bufio"
 	"context"
+	"errors"
 	"net/url"
+	"os"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/owasp-amass/amass/v4/net/dns"
 	"github.com/owasp-amass/amass/v4/net/http"
+	bf "github.com/tylertreat/BoomFilters"
 	lua "github.com/yuin/gopher-lua"
 )
 
@@ -38,15 +44,7 @@ func (s *Script) request(L *lua.LState) int {
 		return 2
 	}
 
-	var hdr http.Header
-	if lv := L.GetField(opt, "header"); lv != nil {
-		if tbl, ok := lv.(*lua.LTable); ok {
-			hdr = make(http.Header)
-			tbl.ForEach(func(k, v lua.LValue) {
-				hdr[k.String()] = v.String()
-			})
-		}
-	}
+	hdr := headerField(L, opt)
 
 	var body string
 	if method, ok := getStringField(L, opt, "method"); ok && strings.ToLower(method) == "post" {
@@ -61,6 +59,7 @@ func (s *Script) request(L *lua.LState) int {
 		Username: id,
 		Password: pass,
 	})
+	stopForDryRun(L, err)
 
 	if err != nil || resp == nil {
 		L.Push(lua.LNil)
@@ -76,6 +75,157 @@ func (s *Script) request(L *lua.LState) int {
 	return 2
 }
 
+func headerField(L *lua.LState, opt *lua.LTable) http.Header {
+	var hdr http.Header
+
+	if lv := L.GetField(opt, "header"); lv != nil {
+		if tbl, ok := lv.(*lua.LTable); ok {
+			hdr = make(http.Header)
+			tbl.ForEach(func(k, v lua.LValue) {
+				hdr[k.String()] = v.String()
+			})
+		}
+	}
+	return hdr
+}
+
+// Wrapper that allows scripts to request several pages of results concurrently.
+func (s *Script) requestPages(L *lua.LState) int {
+	ctx, err := extractContext(L.CheckUserData(1))
+	if err != nil {
+		L.Push(lua.LNil)
+		L.Push(lua.LString("No user data parameter or context expired"))
+		return 2
+	}
+
+	opt := L.CheckTable(2)
+	if opt == nil {
+		L.Push(lua.LNil)
+		L.Push(lua.LString("No table parameter was provided"))
+		return 2
+	}
+
+	urls := stringsField(L, opt, "urls")
+	if len(urls) == 0 {
+		L.Push(lua.LNil)
+		L.Push(lua.LString("No URLs found in the parameters"))
+		return 2
+	}
+
+	workers := 1
+	if n, ok := getNumberField(L, opt, "concurrency"); ok && n > 1 {
+		workers = int(n)
+	}
+
+	resps, err := s.fetchPages(ctx, urls, headerField(L, opt), workers, stringsField(L, opt, "stop"))
+	stopForDryRun(L, err)
+	tbl := L.NewTable()
+	for _, resp := range resps {
+		tbl.Append(responseToTable(L, resp))
+	}
+
+	L.Push(tbl)
+	if err != nil {
+		L.Push(lua.LString(err.Error()))
+	} else {
+		L.Push(lua.LNil)
+	}
+	return 2
+}
+
+func stringsField(L *lua.LState, opt *lua.LTable, key string) []string {
+	var list []string
+
+	if tbl, ok := L.GetField(opt, key).(*lua.LTable); ok {
+		for i := 1; i <= tbl.Len(); i++ {
+			if v := tbl.RawGetInt(i); v != lua.LNil {
+				list = append(list, v.String())
+			}
+		}
+	}
+	return list
+}
+
+// fetchPages requests the URLs with no more than the provided number of requests in flight,
+// while each request still waits on the rate limit of the data source. The responses are
+// returned in order and end with the first page that failed or contains one of the stop
+// strings. The requests for the pages following that one are cancelled or never made.
+func (s *Script) fetchPages(ctx context.Context, urls []string, hdr http.Header, workers int, stop []string) ([]*http.Response, error) {
+	type page struct {
+		resp   *http.Response
+		err    error
+		cancel context.CancelFunc
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var next int
+	var lock sync.Mutex
+	var wg sync.WaitGroup
+	last := len(urls)
+	pages := make([]page, len(urls))
+	for i := 0; i < workers && i < len(urls); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			for {
+				lock.Lock()
+				idx := next
+				if idx >= last || ctx.Err() != nil {
+					lock.Unlock()
+					return
+				}
+				next++
+				pctx, pcancel := context.WithCancel(ctx)
+				pages[idx].cancel = pcancel
+				lock.Unlock()
+
+				resp, err := s.req(pctx, urls[idx], "", hdr, nil)
+				if err == nil && resp == nil {
+					err = errors.New("no HTTP response")
+				}
+
+				lock.Lock()
+				pages[idx].resp, pages[idx].err = resp, err
+				if idx < last && (err != nil || containsAny(resp.Body, stop)) {
+					last = idx + 1
+					for _, p := range pages[last:] {
+						if p.cancel != nil {
+							p.cancel()
+						}
+					}
+				}
+				lock.Unlock()
+				pcancel()
+			}
+		}()
+	}
+	wg.Wait()
+
+	var resps []*http.Response
+	for _, p := range pages[:last] {
+		if p.err != nil {
+			return resps, p.err
+		}
+		if p.resp == nil {
+			return resps, ctx.Err()
+		}
+		resps = append(resps, p.resp)
+	}
+	return resps, nil
+}
+
+func containsAny(body string, list []string) bool {
+	for _, s := range list {
+		if s != "" && strings.Contains(body, s) {
+			return true
+		}
+	}
+	return false
+}
+
 func responseToTable(L *lua.LState, resp *http.Response) *lua.LTable {
 	r := L.NewTable()
 
@@ -108,13 +258,14 @@ func responseToTable(L *lua.LState, resp *http.Response) *lua.LTable {
 				c := L.NewTable()
 
 				c.RawSetString("version", lua.LNumber(cert.Version))
-				c.RawSetString("common_name", lua.LString(dns.RemoveAsteriskLabel(cert.Subject.CommonName)))
+				cn, _ := dns.NormalizeFQDN(cert.Subject.CommonName)
+				c.RawSetString("common_name", lua.LString(cn))
 
 				if len(cert.DNSNames) > 0 {
 					san := L.NewTable()
 
 					for _, name := range cert.DNSNames {
-						n := dns.RemoveAsteriskLabel(name)
+						n, _ := dns.NormalizeFQDN(name)
 
 						san.Append(lua.LString(n))
 					}
@@ -150,15 +301,7 @@ func (s *Script) scrape(L *lua.LState) int {
 		return 1
 	}
 
-	var hdr http.Header
-	if lv := L.GetField(opt, "header"); lv != nil {
-		if tbl, ok := lv.(*lua.LTable); ok {
-			hdr = make(http.Header)
-			tbl.ForEach(func(k, v lua.LValue) {
-				hdr[k.String()] = v.String()
-			})
-		}
-	}
+	hdr := headerField(L, opt)
 
 	var body string
 	if method, ok := getStringField(L, opt, "method"); ok && strings.ToLower(method) == "post" {
@@ -171,10 +314,13 @@ func (s *Script) scrape(L *lua.LState) int {
 	pass, _ := getStringField(L, opt, "pass")
 
 	sucess := lua.LFalse
-	if resp, err := s.req(ctx, url, body, hdr, &http.BasicAuth{
+	resp, err := s.req(ctx, url, body, hdr, &http.BasicAuth{
 		Username: id,
 		Password: pass,
-	}); err == nil {
+	})
+	stopForDryRun(L, err)
+
+	if err == nil {
 		if resp != nil && resp.StatusCode >= 200 && resp.StatusCode < 400 {
 			if num := s.internalSendNames(ctx, resp.Body); num > 0 {
 				sucess = lua.LTrue
@@ -193,18 +339,22 @@ func (s *Script) req(ctx context.Context, url, data string, hdr http.Header, aut
 	if data != "" {
 		method = "POST"
 	}
+	if err := s.beforeRequest(ctx, method, url); err != nil {
+		return nil, err
+	}
 
-	numRateLimitChecks(s, s.seconds)
-	ctx, cancel := context.WithTimeout(ctx, 20*time.Second)
+	rctx, cancel := context.WithTimeout(ctx, 20*time.Second)
 	defer cancel()
 
-	resp, err := http.RequestWebPage(ctx, &http.Request{
+	resp, err := http.RequestWebPage(rctx, &http.Request{
 		URL:    url,
 		Method: method,
 		Header: hdr,
 		Body:   data,
 		Auth:   auth,
 	})
+	s.recordOutcome(ctx, resp, err)
+	s.metrics.recordQuery(resp, err)
 	if err != nil {
 		cfg := s.sys.Config()
 
@@ -215,6 +365,140 @@ func (s *Script) req(ctx context.Context, url, data string, hdr http.Header, aut
 	return resp, err
 }
 
+// beforeRequest waits for the rate limits of the data source and the host, and returns
+// an error when the request must not be sent.
+func (s *Script) beforeRequest(ctx context.Context, method, url string) error {
+	if s.skipForDryRun(method, url) {
+		return errDryRun
+	}
+	if err := s.checkBreaker(); err != nil {
+		return err
+	}
+
+	numRateLimitChecks(s, s.seconds)
+	return s.takeForURL(ctx, url)
+}
+
+// errDryRun is returned in place of the response when the request was skipped for a dry run.
+var errDryRun = errors.New("the request was skipped for the dry run")
+
+// dryRunStop is raised as the Lua error that ends the callback once a request was skipped for the
+// dry run, since the script cannot process a response that was never received.
+var dryRunStop = lua.LString(errDryRun.Error())
+
+// stopForDryRun ends the callback without an error message when the request was skipped for the dry run.
+func stopForDryRun(L *lua.LState, err error) {
+	if errors.Is(err, errDryRun) {
+		L.Error(dryRunStop, 0)
+	}
+}
+
+// isDryRunStop returns true when the callback was ended by stopForDryRun.
+func isDryRunStop(err error) bool {
+	var aerr *lua.ApiError
+	return errors.As(err, &aerr) && aerr.Object == dryRunStop
+}
+
+// skipForDryRun logs the planned request and returns true when the enumeration is a dry run.
+func (s *Script) skipForDryRun(method, url string) bool {
+	cfg := s.sys.Config()
+	if !isDryRun(cfg) {
+		return false
+	}
+
+	cfg.Log.Printf("%s: dry run: %s %s", s.String(), method, url)
+	return true
+}
+
+// maxArchiveBytes is the largest archive that scripts are permitted to download.
+const maxArchiveBytes int64 = 1024 * 1024 * 1024 // 1GB
+
+// Wrapper so that scripts can send the FQDNs found in the files of a ZIP archive to Amass.
+func (s *Script) sendZipNames(L *lua.LState) int {
+	ctx, err := extractContext(L.CheckUserData(1))
+	if err != nil || contextExpired(ctx) {
+		L.Push(lua.LNumber(0))
+		L.Push(lua.LString("the context expired"))
+		return 2
+	}
+
+	num, err := s.internalSendZipNames(ctx, L.CheckString(2))
+	stopForDryRun(L, err)
+	L.Push(lua.LNumber(num))
+	if err != nil {
+		L.Push(lua.LString(err.Error()))
+	} else {
+		L.Push(lua.LNil)
+	}
+	return 2
+}
+
+func (s *Script) internalSendZipNames(ctx context.Context, u string) (int, error) {
+	if s.skipForDryRun("GET", u) {
+		return 0, errDryRun
+	}
+	// The archive is written to disk, since it can be much larger than other responses
+	f, err := os.CreateTemp("", "amass-*.zip")
+	if err != nil {
+		return 0, err
+	}
+	defer func() {
+		f.Close()
+		os.Remove(f.Name())
+	}()
+
+	numRateLimitChecks(s, s.seconds)
+	if err := s.takeForURL(ctx, u); err != nil {
+		return 0, err
+	}
+	err = http.DownloadFile(ctx, &http.Request{URL: u}, f, maxArchiveBytes)
+	s.metrics.queries.Add(1)
+	if err != nil {
+		s.metrics.httpErrors.Add(1)
+		return 0, err
+	}
+
+	info, err := f.Stat()
+	if err != nil {
+		return 0, err
+	}
+
+	zr, err := zip.NewReader(f, info.Size())
+	if err != nil {
+		return 0, err
+	}
+
+	filter := bf.NewDefaultStableBloomFilter(100000, 0.01)
+	defer filter.Reset()
+
+	var count int
+	for _, file := range zr.File {
+		if file.FileInfo().IsDir() {
+			continue
+		}
+
+		rc, err := file.Open()
+		if err != nil {
+			continue
+		}
+
+		scanner := bufio.NewScanner(rc)
+		for scanner.Scan() {
+			if contextExpired(ctx) {
+				rc.Close()
+				return count, nil
+			}
+
+			if n := http.CleanName(scanner.Text()); n != "" && !filter.TestAndAdd([]byte(n)) {
+				s.newNameWithContext(ctx, n)
+				count++
+			}
+		}
+		rc.Close()
+	}
+	return count, nil
+}
+
 // Wrapper so that scripts can crawl for subdomain names in scope.
 func (s *Script) crawl(L *lua.LState) int {
 	cfg := s.sys.Config()
@@ -229,6 +513,10 @@ func (s *Script) crawl(L *lua.LState) int {
 	}
 
 	max := L.CheckInt(3)
+	if s.skipForDryRun("CRAWL", u) {
+		return 0
+	}
+
 	ctx, cancel := context.WithTimeout(ctx, 2*time.Minute)
 	defer cancel()
 
@@ -0,0 +1,305 @@
+// Copyright © by Jeff Foley 2017-2023. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+// SPDX-License-Identifier: Apache-2.0
+
+package scripting
+
+import (
+	"archive/zip"
+	"bytes"
+	"fmt"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/caffix/stringset"
+	"github.com/owasp-amass/amass/v4/requests"
+)
+
+func TestSendZipNames(t *testing.T) {
+	expected := stringset.New("www.owasp.org", "ftp.owasp.org", "mail.owasp.org", "api.owasp.org")
+	defer expected.Close()
+
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	files := map[string]string{
+		"owasp.org.txt": "www.owasp.org\nftp.owasp.org\nWWW.owasp.org\n",
+		"other.txt":     "mail.owasp.org\napi.owasp.org\nwww.example.com\n",
+	}
+	for name, content := range files {
+		w, err := zw.Create(name)
+		if err != nil {
+			t.Fatalf("Failed to create the archive: %v", err)
+		}
+		_, _ = w.Write([]byte(content))
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatalf("Failed to create the archive: %v", err)
+	}
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write(buf.Bytes())
+	}))
+	defer ts.Close()
+
+	ctx, sys := setupMockScriptEnv(fmt.Sprintf(`
+		name="zip"
+		type="testing"
+
+		function vertical(ctx, domain)
+			local num, err = send_zip_names(ctx, "%s")
+			if (err == nil and num == 5) then
+				new_name(ctx, "done." .. domain)
+			end
+		end
+	`, ts.URL))
+	if ctx == nil || sys == nil {
+		t.Fatal("Failed to initialize the scripting environment")
+	}
+	defer func() { _ = sys.Shutdown() }()
+
+	domain := "owasp.org"
+	sys.Config().AddDomain(domain)
+	sys.DataSources()[0].Input() <- &requests.DNSRequest{Domain: domain}
+	expected.Insert("done." + domain)
+
+	timer := time.NewTimer(10 * time.Second)
+	defer timer.Stop()
+
+	for l := expected.Len(); l > 0; l-- {
+		select {
+		case <-timer.C:
+			t.Fatalf("The names were not all found: %v", expected.Slice())
+		case req := <-sys.DataSources()[0].Output():
+			if d, ok := req.(*requests.DNSRequest); !ok || !expected.Has(d.Name) {
+				t.Errorf("%v was not found in the list of expected names", req)
+			} else {
+				expected.Remove(d.Name)
+			}
+		}
+	}
+}
+
+func TestDryRun(t *testing.T) {
+	var count int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&count, 1)
+		_, _ = w.Write([]byte("www.owasp.org"))
+	}))
+	defer ts.Close()
+
+	tests := []struct {
+		label   string
+		call    string
+		planned string
+	}{
+		{"Request", `request(ctx, {['url']="%[1]s/request?domain=" .. domain})`, "GET %[1]s/request?domain=owasp.org"},
+		{"Scrape", `scrape(ctx, {['url']="%[1]s/scrape?domain=" .. domain})`, "GET %[1]s/scrape?domain=owasp.org"},
+		{"Pages", `request_pages(ctx, {['urls']={"%[1]s/pages?page=1"}})`, "GET %[1]s/pages?page=1"},
+		{"Paginate", `paginate(ctx, {['fetch']=function(c, p) request(ctx, {['url']="%[1]s/paginate"}) return 0, "" end})`, "GET %[1]s/paginate"},
+		{"Stream", `stream_json(ctx, {['url']="%[1]s/stream"}, function(item) end)`, "GET %[1]s/stream"},
+		{"ZipNames", `send_zip_names(ctx, "%[1]s/archive.zip")`, "GET %[1]s/archive.zip"},
+	}
+
+	for _, test := range tests {
+		script, sys := setupMockScriptEnv(fmt.Sprintf(`
+			name="dryrun"
+			type="testing"
+
+			function vertical(ctx, domain)
+				if domain == "example.com" then
+					new_name(ctx, "done.owasp.org")
+					return
+				end
+
+				`+test.call+`
+				new_name(ctx, "after." .. domain)
+			end
+		`, ts.URL))
+		if script == nil || sys == nil {
+			t.Fatalf("%s: failed to initialize the scripting environment", test.label)
+		}
+
+		var buf bytes.Buffer
+		cfg := sys.Config()
+		cfg.Log = log.New(&buf, "", 0)
+		cfg.Options = map[string]interface{}{"dry_run": true}
+		cfg.AddDomain("owasp.org")
+		// The requests are dispatched in order, so the second marks the end of the first callback
+		script.Input() <- &requests.DNSRequest{Name: "owasp.org", Domain: "owasp.org"}
+		script.Input() <- &requests.DNSRequest{Name: "example.com", Domain: "example.com"}
+
+		timer := time.NewTimer(10 * time.Second)
+		select {
+		case <-timer.C:
+			t.Errorf("%s: the script did not complete the dry run", test.label)
+		case req := <-script.Output():
+			if d, ok := req.(*requests.DNSRequest); !ok || d.Name != "done.owasp.org" {
+				t.Errorf("%s: the callback was not stopped by the dry run: %v", test.label, req)
+			}
+		}
+		timer.Stop()
+
+		logs := buf.String()
+		if planned := "dry run: " + fmt.Sprintf(test.planned, ts.URL); !strings.Contains(logs, planned) {
+			t.Errorf("%s: the planned request was not logged: %s", test.label, planned)
+		}
+		if strings.Contains(logs, "callback:") || strings.Contains(logs, "scrape:") {
+			t.Errorf("%s: the dry run was logged as a failure: %s", test.label, logs)
+		}
+		_ = sys.Shutdown()
+	}
+
+	if n := atomic.LoadInt32(&count); n != 0 {
+		t.Errorf("the service received %d requests during the dry run", n)
+	}
+}
+
+func TestRequestPages(t *testing.T) {
+	var lock sync.Mutex
+	var inflight, max int
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		lock.Lock()
+		inflight++
+		if inflight > max {
+			max = inflight
+		}
+		lock.Unlock()
+		defer func() {
+			lock.Lock()
+			inflight--
+			lock.Unlock()
+		}()
+
+		page := strings.TrimPrefix(r.URL.Path, "/")
+		if page == "4" {
+			_, _ = w.Write([]byte("page " + page + ": end of results"))
+			return
+		}
+
+		select {
+		case <-r.Context().Done():
+		case <-time.After(300 * time.Millisecond):
+			_, _ = w.Write([]byte("page " + page))
+		}
+	}))
+	defer ts.Close()
+
+	script, sys := setupMockScriptEnv(fmt.Sprintf(`
+		name="pages"
+		type="testing"
+
+		function vertical(ctx, domain)
+			local urls = {}
+			for i=1,6 do
+				table.insert(urls, "%s/" .. i)
+			end
+
+			local resps, err = request_pages(ctx, {
+				['urls']=urls,
+				['concurrency']=3,
+				['stop']={"end of results"},
+			})
+			if (err ~= nil and err ~= "") then
+				return
+			end
+
+			local bodies = {}
+			for _, resp in ipairs(resps) do
+				table.insert(bodies, resp.body)
+			end
+			if (table.concat(bodies, ",") == "page 1,page 2,page 3,page 4: end of results") then
+				new_name(ctx, "done." .. domain)
+			end
+		end
+	`, ts.URL))
+	if script == nil || sys == nil {
+		t.Fatal("Failed to initialize the scripting environment")
+	}
+	defer func() { _ = sys.Shutdown() }()
+
+	domain := "owasp.org"
+	sys.Config().AddDomain(domain)
+	start := time.Now()
+	script.Input() <- &requests.DNSRequest{Name: domain, Domain: domain}
+
+	select {
+	case <-time.After(10 * time.Second):
+		t.Fatal("the script did not request the pages")
+	case req := <-script.Output():
+		if d, ok := req.(*requests.DNSRequest); !ok || d.Name != "done."+domain {
+			t.Errorf("the script returned an unexpected request: %v", req)
+		}
+	}
+	// The first three pages are requested together, so the requests take two delays instead of four
+	if elapsed := time.Since(start); elapsed >= 1200*time.Millisecond {
+		t.Errorf("the pages were not requested concurrently: %v elapsed", elapsed)
+	}
+
+	lock.Lock()
+	defer lock.Unlock()
+	if max > 3 {
+		t.Errorf("%d requests were in flight at the same time", max)
+	}
+}
+
+func TestRequestPagesRateLimit(t *testing.T) {
+	var lock sync.Mutex
+	var arrivals []time.Time
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		lock.Lock()
+		arrivals = append(arrivals, time.Now())
+		lock.Unlock()
+		_, _ = w.Write([]byte("page"))
+	}))
+	defer ts.Close()
+
+	script, sys := setupMockScriptEnv(fmt.Sprintf(`
+		name="pages"
+		type="testing"
+
+		function start()
+			set_rate_limit(1)
+		end
+
+		function vertical(ctx, domain)
+			local resps, err = request_pages(ctx, {
+				['urls']={"%[1]s/1", "%[1]s/2", "%[1]s/3"},
+				['concurrency']=3,
+			})
+			if (err == nil and #resps == 3) then
+				new_name(ctx, "done." .. domain)
+			end
+		end
+	`, ts.URL))
+	if script == nil || sys == nil {
+		t.Fatal("Failed to initialize the scripting environment")
+	}
+	defer func() { _ = sys.Shutdown() }()
+
+	domain := "owasp.org"
+	sys.Config().AddDomain(domain)
+	script.Input() <- &requests.DNSRequest{Name: domain, Domain: domain}
+
+	select {
+	case <-time.After(10 * time.Second):
+		t.Fatal("the script did not request the pages")
+	case <-script.Output():
+	}
+
+	lock.Lock()
+	defer lock.Unlock()
+	if len(arrivals) != 3 {
+		t.Fatalf("expected three requests and the service received %d", len(arrivals))
+	}
+	for i := 1; i < len(arrivals); i++ {
+		if gap := arrivals[i].Sub(arrivals[i-1]); gap < 900*time.Millisecond {
+			t.Errorf("the requests exceeded the rate limit with a gap of %v", gap)
+		}
+	}
+}
@@ -0,0 +1,128 @@
+// Copyright © by Jeff Foley 2017-2023. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+// SPDX-License-Identifier: Apache-2.0
+
+package scripting
+
+import (
+	"sync"
+	"time"
+
+	"github.com/owasp-amass/config/config"
+	lua "github.com/yuin/gopher-lua"
+)
+
+// keyring hands out the accounts of a data source in round-robin order,
+// skipping the accounts that were rate limited or rejected during the session.
+type keyring struct {
+	sync.Mutex
+	accounts []string
+	creds    map[string]*config.Credentials
+	next     int
+	dead     map[string]struct{}
+	until    map[string]time.Time
+}
+
+func newKeyring(cfg *config.DataSource) *keyring {
+	k := &keyring{
+		creds: make(map[string]*config.Credentials),
+		dead:  make(map[string]struct{}),
+		until: make(map[string]time.Time),
+	}
+
+	if cfg != nil {
+		k.accounts = accountNames(cfg)
+		for _, name := range k.accounts {
+			k.creds[name] = cfg.Creds[name]
+		}
+	}
+	return k
+}
+
+// Next returns the name and credentials of the next usable account.
+// The credentials are nil when every account has been exhausted.
+func (k *keyring) Next() (string, *config.Credentials) {
+	k.Lock()
+	defer k.Unlock()
+
+	now := time.Now()
+	for i := 0; i < len(k.accounts); i++ {
+		idx := (k.next + i) % len(k.accounts)
+		name := k.accounts[idx]
+
+		if _, found := k.dead[name]; found {
+			continue
+		}
+		if t, found := k.until[name]; found && now.Before(t) {
+			continue
+		}
+
+		k.next = idx + 1
+		return name, k.creds[name]
+	}
+	return "", nil
+}
+
+// Limited skips the named account until the cool-down period has passed.
+func (k *keyring) Limited(account string, d time.Duration) {
+	k.Lock()
+	defer k.Unlock()
+
+	k.until[account] = time.Now().Add(d)
+}
+
+// Failed skips the named account for the remainder of the session.
+func (k *keyring) Failed(account string) {
+	k.Lock()
+	defer k.Unlock()
+
+	k.dead[account] = struct{}{}
+}
+
+// Remaining returns the number of accounts that were not rejected during the session.
+func (k *keyring) Remaining() int {
+	k.Lock()
+	defer k.Unlock()
+
+	return len(k.accounts) - len(k.dead)
+}
+
+func (s *Script) getKeyring() *keyring {
+	s.keysOnce.Do(func() {
+		s.keys = newKeyring(s.sys.Config().GetDataSourceConfig(s.String()))
+	})
+	return s.keys
+}
+
+// Wrapper so that scripts can obtain the credentials of the next usable account.
+func (s *Script) nextCredentials(L *lua.LState) int {
+	account, creds := s.getKeyring().Next()
+	if creds == nil {
+		L.Push(lua.LNil)
+		return 1
+	}
+
+	tb := credentialsToTable(L, creds)
+	tb.RawSetString("account", lua.LString(account))
+	L.Push(tb)
+	return 1
+}
+
+// Wrapper so that scripts can report an account that was rate limited by the data source.
+func (s *Script) credentialsLimited(L *lua.LState) int {
+	account := L.CheckString(1)
+	secs := L.CheckInt(2)
+
+	if account != "" && secs > 0 {
+		s.getKeyring().Limited(account, time.Duration(secs)*time.Second)
+	}
+	return 0
+}
+
+// Wrapper so that scripts can report an account that was rejected by the data source.
+func (s *Script) credentialsFailed(L *lua.LState) int {
+	if account := L.CheckString(1); account != "" {
+		s.getKeyring().Failed(account)
+	}
+	return 0
+}
@@ -0,0 +1,105 @@
+// Copyright © by Jeff Foley 2017-2023. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+// SPDX-License-Identifier: Apache-2.0
+
+package scripting
+
+import (
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/owasp-amass/config/config"
+)
+
+func newTestKeyring(accounts ...string) *keyring {
+	src := &config.DataSource{Name: "testing"}
+
+	for _, account := range accounts {
+		_ = src.AddCredentials(account, &config.Credentials{Name: "testing", Apikey: account + "-key"})
+	}
+	return newKeyring(src)
+}
+
+func TestKeyringRoundRobin(t *testing.T) {
+	k := newTestKeyring("c", "a", "b")
+
+	var got []string
+	for i := 0; i < 4; i++ {
+		account, creds := k.Next()
+		if creds == nil || creds.Apikey != account+"-key" {
+			t.Fatalf("Next returned the wrong credentials for account %s: %v", account, creds)
+		}
+		got = append(got, account)
+	}
+	if strings.Join(got, ",") != "a,b,c,a" {
+		t.Errorf("the accounts were not handed out in round-robin order: %v", got)
+	}
+}
+
+func TestKeyringSkipsExhaustedAccounts(t *testing.T) {
+	k := newTestKeyring("a", "b", "c")
+
+	k.Failed("a")
+	k.Limited("b", 50*time.Millisecond)
+	for i := 0; i < 2; i++ {
+		if account, _ := k.Next(); account != "c" {
+			t.Errorf("expected only account c to be usable and got %s", account)
+		}
+	}
+
+	k.Failed("c")
+	if account, creds := k.Next(); creds != nil {
+		t.Errorf("expected every account to be exhausted and got %s", account)
+	}
+
+	time.Sleep(100 * time.Millisecond)
+	if account, _ := k.Next(); account != "b" {
+		t.Errorf("expected account b to be usable after the cool-down and got %s", account)
+	}
+}
+
+func TestKeyringWithoutCredentials(t *testing.T) {
+	if account, creds := newKeyring(nil).Next(); creds != nil {
+		t.Errorf("expected no credentials and got account %s", account)
+	}
+}
+
+func TestKeyringConcurrentUse(t *testing.T) {
+	k := newTestKeyring("a", "b")
+
+	var wg sync.WaitGroup
+	var lock sync.Mutex
+	counts := make(map[string]int)
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			for j := 0; j < 100; j++ {
+				account, _ := k.Next()
+
+				lock.Lock()
+				counts[account]++
+				lock.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+
+	if counts["a"] != 500 || counts["b"] != 500 {
+		t.Errorf("the accounts were not handed out evenly: %v", counts)
+	}
+}
+
+func TestKeyringRemaining(t *testing.T) {
+	k := newTestKeyring("a", "b", "c")
+
+	k.Failed("a")
+	// Rate limited accounts are usable again after the cool-down
+	k.Limited("b", time.Minute)
+	if n := k.Remaining(); n != 2 {
+		t.Errorf("expected 2 remaining accounts and got %d", n)
+	}
+}
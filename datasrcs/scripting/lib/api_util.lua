@@ -0,0 +1,64 @@
+-- Copyright © by Jeff Foley 2017-2023. All rights reserved.
+-- Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+-- SPDX-License-Identifier: Apache-2.0
+
+-- The helpers shared by the data sources that rotate API keys and report the errors of the service
+local json = require("json")
+
+local api_util = {}
+
+-- Returns the API keys of all the credentials configured for the data source
+function api_util.keys(cfg)
+    local keys = {}
+    if (cfg == nil) then
+        return keys
+    end
+
+    local creds = cfg.all_credentials
+    if (creds == nil and cfg.credentials ~= nil) then
+        creds = {cfg.credentials}
+    end
+    if (creds ~= nil) then
+        for _, c in pairs(creds) do
+            if (c.key ~= nil and c.key ~= "") then
+                table.insert(keys, c.key)
+            end
+        end
+    end
+    return keys
+end
+
+-- Returns the number of seconds requested by the Retry-After header, within the bounds
+function api_util.retry_after(resp, default_wait, max_wait)
+    local secs
+    if (resp.header ~= nil and resp.header['Retry-After'] ~= nil) then
+        secs = tonumber(resp.header['Retry-After'])
+    end
+
+    if (secs == nil or secs <= 0) then
+        return default_wait
+    elseif (secs > max_wait) then
+        return max_wait
+    end
+    return secs
+end
+
+-- Extracts the message from an error response body, such as {"error":"unauthorized"}, using
+-- the first of the fields holding a string. The fields default to error and message.
+function api_util.error_message(body, fields)
+    local d = json.decode(body)
+    if (d == nil) then
+        return ""
+    end
+
+    for _, field in pairs(fields or {"error", "message"}) do
+        local msg = d[field]
+        -- only accept string values
+        if (msg ~= nil and msg ~= "" and tostring(msg) == msg) then
+            return msg
+        end
+    end
+    return ""
+end
+
+return api_util
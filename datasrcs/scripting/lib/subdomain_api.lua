@@ -0,0 +1,83 @@
+-- Copyright © by Jeff Foley 2017-2023. All rights reserved.
+-- Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+-- SPDX-License-Identifier: Apache-2.0
+
+-- The callbacks shared by the data sources that query an API for the subdomain names of a domain
+local json = require("json")
+
+local subdomain_api = {}
+
+-- Defines the start, check and vertical callbacks of the script using the provided table:
+--   rate          seconds between requests (default: 1)
+--   key_required  when true, the script is disabled without an API key
+--   query         function(ctx, domain, credentials) returning a table of names
+function subdomain_api.register(spec)
+    local rate = spec.rate or 1
+
+    start = function()
+        set_rate_limit(rate)
+    end
+
+    if spec.key_required then
+        check = function()
+            return subdomain_api.credentials() ~= nil
+        end
+    end
+
+    vertical = function(ctx, domain)
+        local c = subdomain_api.credentials()
+        if (spec.key_required and c == nil) then
+            return
+        end
+
+        local names = spec.query(ctx, domain, c)
+        if (names == nil) then
+            return
+        end
+
+        local seen = {}
+        for _, name in pairs(names) do
+            -- scripts replace the type function with their type field, so other values are skipped this way
+            local ok, key = pcall(string.lower, name)
+
+            if (ok and key ~= "" and seen[key] == nil) then
+                seen[key] = true
+                new_name(ctx, key)
+            end
+        end
+    end
+end
+
+-- Returns the credentials of the data source when an API key has been configured
+function subdomain_api.credentials()
+    local cfg = datasrc_config()
+    if (cfg == nil) then
+        return nil
+    end
+
+    local c = cfg.credentials
+    if (c == nil or c.key == nil or c.key == "") then
+        return nil
+    end
+    return c
+end
+
+-- Sends the request and returns the decoded JSON response, or nil after logging the failure
+function subdomain_api.get_json(ctx, req)
+    local resp, err = request(ctx, req)
+    if (err ~= nil and err ~= "") then
+        log(ctx, "vertical request to service failed: " .. err)
+        return nil
+    elseif (resp.status_code < 200 or resp.status_code >= 400) then
+        log(ctx, "vertical request to service returned with status: " .. resp.status)
+        return nil
+    end
+
+    local d = json.decode(resp.body)
+    if (d == nil) then
+        log(ctx, "failed to decode the JSON response")
+    end
+    return d
+end
+
+return subdomain_api
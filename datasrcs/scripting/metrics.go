@@ -0,0 +1,68 @@
+// Copyright © by Jeff Foley 2017-2023. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+// SPDX-License-Identifier: Apache-2.0
+
+package scripting
+
+import (
+	"sync/atomic"
+
+	"github.com/owasp-amass/amass/v4/net/http"
+)
+
+// Metrics is a snapshot of the counters kept by a data source script during the enumeration.
+type Metrics struct {
+	// Number of HTTP requests sent to the data source
+	Queries int64
+	// Number of HTTP requests that failed or returned an error status
+	HTTPErrors int64
+	// Number of names submitted by the script
+	NamesFound int64
+	// Number of submitted names that were in scope of the enumeration
+	NamesInScope int64
+	// Number of addresses, ASN records and associated domains provided by the script
+	Assets int64
+	// Number of findings sent to the enumeration
+	Findings int64
+	// Number of findings dropped while the output of the script was full
+	Dropped int64
+	// Number of findings waiting in the output of the script
+	QueueDepth int64
+}
+
+// The counters are updated on the hot paths, so only atomic operations are used.
+type metrics struct {
+	queries      atomic.Int64
+	httpErrors   atomic.Int64
+	namesFound   atomic.Int64
+	namesInScope atomic.Int64
+	assets       atomic.Int64
+	findings     atomic.Int64
+	dropped      atomic.Int64
+}
+
+func (m *metrics) recordQuery(resp *http.Response, err error) {
+	m.queries.Add(1)
+	if err != nil || resp == nil || resp.StatusCode >= 400 {
+		m.httpErrors.Add(1)
+	}
+}
+
+func (m *metrics) snapshot() Metrics {
+	return Metrics{
+		Queries:      m.queries.Load(),
+		HTTPErrors:   m.httpErrors.Load(),
+		NamesFound:   m.namesFound.Load(),
+		NamesInScope: m.namesInScope.Load(),
+		Assets:       m.assets.Load(),
+		Findings:     m.findings.Load(),
+		Dropped:      m.dropped.Load(),
+	}
+}
+
+// Metrics returns a snapshot of the counters kept by the script since it was created.
+func (s *Script) Metrics() Metrics {
+	m := s.metrics.snapshot()
+	m.QueueDepth = int64(len(s.Output()))
+	return m
+}
@@ -0,0 +1,108 @@
+// Copyright © by Jeff Foley 2017-2023. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+// SPDX-License-Identifier: Apache-2.0
+
+package scripting
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	amasshttp "github.com/owasp-amass/amass/v4/net/http"
+	"github.com/owasp-amass/amass/v4/requests"
+)
+
+func TestMetricsConcurrentIncrements(t *testing.T) {
+	var m metrics
+	var wg sync.WaitGroup
+
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+
+			for j := 0; j < 100; j++ {
+				switch {
+				case i%3 == 0:
+					m.recordQuery(nil, errors.New("failed"))
+				case i%3 == 1:
+					m.recordQuery(&amasshttp.Response{StatusCode: 500}, nil)
+				default:
+					m.recordQuery(&amasshttp.Response{StatusCode: 200}, nil)
+				}
+				m.namesFound.Add(1)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	expected := Metrics{Queries: 5000, HTTPErrors: 3400, NamesFound: 5000}
+	if got := m.snapshot(); got != expected {
+		t.Errorf("expected %+v and got %+v", expected, got)
+	}
+}
+
+func TestScriptMetrics(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/error" {
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}))
+	defer ts.Close()
+
+	s, sys := setupMockScriptEnv(fmt.Sprintf(`
+		name="metrics"
+		type="testing"
+
+		function vertical(ctx, domain)
+			request(ctx, {['url']="%[1]s/ok"})
+			request(ctx, {['url']="%[1]s/error"})
+
+			new_name(ctx, "www." .. domain)
+			new_name(ctx, "www.example.com")
+			new_addr(ctx, "93.184.216.34", "www." .. domain)
+			associated(ctx, domain, "owasp.net")
+		end
+	`, ts.URL))
+	if s == nil || sys == nil {
+		t.Fatal("failed to initialize the scripting environment")
+	}
+	defer func() { _ = sys.Shutdown() }()
+
+	sys.Config().AddDomain("owasp.org")
+	s.Input() <- &requests.DNSRequest{Name: "owasp.org", Domain: "owasp.org"}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	// The name, the address and the associated domain
+	for i := 0; i < 3; i++ {
+		select {
+		case <-ctx.Done():
+			t.Fatal("the script did not provide the findings")
+		case <-s.Output():
+		}
+	}
+
+	expected := Metrics{
+		Queries:      2,
+		HTTPErrors:   1,
+		NamesFound:   2,
+		NamesInScope: 1,
+		Assets:       2,
+		Findings:     3,
+	}
+	// The counters are updated after the findings are received
+	for ctx.Err() == nil {
+		if got := s.(*Script).Metrics(); got == expected {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Errorf("expected %+v and got %+v", expected, s.(*Script).Metrics())
+}
@@ -7,8 +7,11 @@ package scripting
 import (
 	"context"
 	"net"
+	"net/url"
+	"regexp"
 	"strings"
 	"time"
+	"unicode/utf8"
 
 	"github.com/miekg/dns"
 	amassnet "github.com/owasp-amass/amass/v4/net"
@@ -18,34 +21,104 @@ import (
 	"github.com/owasp-amass/resolve"
 	bf "github.com/tylertreat/BoomFilters"
 	lua "github.com/yuin/gopher-lua"
+	"golang.org/x/net/idna"
 	"golang.org/x/net/publicsuffix"
 )
 
+// Names are normalized before the scope check, so every script filters names the same way.
+// The names failing the hostname validation are still sent, since the enumeration drops and
+// counts the malformed names, unless it was configured to allow them.
 func (s *Script) newNameWithContext(ctx context.Context, name string) {
-	if domain := s.sys.Config().WhichDomain(name); domain != "" {
-		select {
-		case <-ctx.Done():
-		case <-s.Done():
-		case s.Output() <- &requests.DNSRequest{
+	if n, _ := amassdns.NormalizeFQDN(name); n != "" {
+		s.newTaggedName(ctx, n, "")
+	}
+}
+
+// newTaggedName sends the normalized name along with the tag describing how the data source discovered it.
+func (s *Script) newTaggedName(ctx context.Context, name, tag string) {
+	s.metrics.namesFound.Add(1)
+	if name, domain, found := s.nameInScope(name); found {
+		s.metrics.namesInScope.Add(1)
+
+		s.emit(ctx, &requests.DNSRequest{
 			Name:   name,
 			Domain: domain,
-		}:
-		}
+			Tag:    tag,
+		})
 	}
 }
 
 // Wrapper so that scripts can send a discovered FQDN to Amass.
 func (s *Script) newName(L *lua.LState) int {
 	if ctx, err := extractContext(L.CheckUserData(1)); err == nil && !contextExpired(ctx) {
-		if n := L.CheckString(2); n != "" {
-			if name := s.subre.FindString(n); name != "" {
-				s.newNameWithContext(ctx, name)
+		// The name is extracted after internationalized labels have been converted, so the
+		// scripts can provide raw text, such as a URL or a name followed by the port
+		raw := asciiNames(strings.ToLower(strings.TrimSpace(L.CheckString(2))))
+		if name := s.subre.FindString(raw); name != "" {
+			if n, _ := amassdns.NormalizeFQDN(name); n != "" {
+				s.newTaggedName(ctx, n, L.OptString(3, ""))
 			}
 		}
 	}
 	return 0
 }
 
+// Matches the runs of text that can hold an internationalized name
+var unicodeNameRE = regexp.MustCompile(`[\p{L}\p{N}_.-]+`)
+
+// asciiNames converts the internationalized names found in the text, and keeps the rest of the text.
+func asciiNames(text string) string {
+	for _, c := range text {
+		if c >= utf8.RuneSelf {
+			return unicodeNameRE.ReplaceAllStringFunc(text, func(name string) string {
+				if ascii, err := idna.Punycode.ToASCII(name); err == nil {
+					return ascii
+				}
+				return name
+			})
+		}
+	}
+	return text
+}
+
+var urlHostRE = amassdns.AnySubdomainRegex()
+
+// Wrapper so that scripts can send the host of a discovered URL to Amass.
+func (s *Script) newURL(L *lua.LState) int {
+	if ctx, err := extractContext(L.CheckUserData(1)); err == nil && !contextExpired(ctx) {
+		if host := urlHostname(L.CheckString(2)); host != "" {
+			s.newNameWithContext(ctx, host)
+		}
+	}
+	return 0
+}
+
+// urlHostname returns the DNS name in the host of the raw URL, which can be provided without the scheme.
+func urlHostname(raw string) string {
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		return ""
+	}
+	if !strings.Contains(raw, "://") {
+		raw = "http://" + strings.TrimPrefix(raw, "//")
+	}
+
+	u, err := url.Parse(raw)
+	if err != nil {
+		return ""
+	}
+
+	host := strings.TrimSuffix(strings.ToLower(u.Hostname()), ".")
+	// URLs that reference an IP address do not provide a name
+	if host == "" || net.ParseIP(host) != nil {
+		return ""
+	}
+	if name, _ := amassdns.NormalizeFQDN(host); name != "" && urlHostRE.FindString(name) == name {
+		return name
+	}
+	return ""
+}
+
 // Wrapper so that scripts can send FQDNs found in the content to Amass.
 func (s *Script) sendNames(L *lua.LState) int {
 	var num int
@@ -116,16 +189,35 @@ func (s *Script) sendDNSRecords(L *lua.LState) int {
 	return 0
 }
 
+// The records are sent with the name that owns them, since the graph only stores a single CNAME record for each
+// request. This allows scripts to provide the complete resolution of a name, such as a chain of CNAME records
+// followed by the A and AAAA records of the final target. The records owned by names out of scope are not sent.
 func (s *Script) internalSendDNSRecords(ctx context.Context, name string, records []requests.DNSAnswer) {
-	if domain := s.sys.Config().WhichDomain(name); domain != "" {
-		select {
-		case <-ctx.Done():
-		case <-s.Done():
-		case s.Output() <- &requests.DNSRequest{
-			Name:    name,
-			Domain:  domain,
-			Records: records,
-		}:
+	name, _ = amassdns.NormalizeFQDN(name)
+	owners := []string{name}
+	byOwner := make(map[string][]requests.DNSAnswer)
+
+	for _, rr := range records {
+		owner, err := amassdns.NormalizeFQDN(rr.Name)
+		if err != nil {
+			continue
+		}
+		if _, found := byOwner[owner]; !found && owner != name {
+			owners = append(owners, owner)
+		}
+		rr.Name = owner
+		rr.Source = s.String()
+		byOwner[owner] = append(byOwner[owner], rr)
+	}
+
+	// The name is sent even when all the records were owned by other names
+	for _, owner := range owners {
+		if owner, domain, found := s.nameInScope(owner); found {
+			s.emit(ctx, &requests.DNSRequest{
+				Name:    owner,
+				Domain:  domain,
+				Records: byOwner[owner],
+			})
 		}
 	}
 }
@@ -136,7 +228,7 @@ func (s *Script) newPTR(ctx context.Context, record *resolve.ExtractedAnswer) {
 		return
 	}
 	// Check that the name discovered is in scope
-	if d := s.sys.Config().WhichDomain(answer); d == "" {
+	if _, _, found := s.nameInScope(answer); !found {
 		return
 	}
 
@@ -146,46 +238,87 @@ func (s *Script) newPTR(ctx context.Context, record *resolve.ExtractedAnswer) {
 		return
 	}
 
-	select {
-	case <-ctx.Done():
-	case <-s.Done():
-	case s.Output() <- &requests.DNSRequest{
+	s.emit(ctx, &requests.DNSRequest{
 		Name:   ptr,
 		Domain: domain,
 		Records: []requests.DNSAnswer{{
-			Name: ptr,
-			Type: int(dns.TypePTR),
-			Data: answer,
+			Name:   ptr,
+			Type:   int(dns.TypePTR),
+			Data:   answer,
+			Source: s.String(),
 		}},
-	}:
-	}
+	})
 }
 
 // Wrapper so that scripts can send discovered IP addresses to Amass.
 func (s *Script) newAddr(L *lua.LState) int {
-	ip := net.ParseIP(L.CheckString(2))
+	if ctx, err := extractContext(L.CheckUserData(1)); err == nil && !contextExpired(ctx) {
+		s.newAddrWithContext(ctx, L.CheckString(2), L.OptString(3, ""), L.OptBool(4, false))
+	}
+	return 0
+}
 
+// Addresses are released when the name is in scope, or when the name is not provided and the
+// address is within the network scope of the enumeration. Returns true when the address was released.
+// The enumeration only stores the addresses marked as in scope, and the scope checks here do not
+// establish that the address belongs to the target, since the sources can provide addresses of
+// shared hosting or stale records. The script asks for the mark when the source is authoritative.
+func (s *Script) newAddrWithContext(ctx context.Context, addr, name string, inScope bool) bool {
+	ip := net.ParseIP(strings.TrimSpace(addr))
 	if ip == nil {
-		return 0
+		return false
 	}
-	if reserved, _ := amassnet.IsReservedAddress(ip.String()); reserved {
-		return 0
+	// The canonical form also converts IPv4-mapped IPv6 addresses
+	addr = ip.String()
+	if reserved, _ := amassnet.IsReservedAddress(addr); reserved {
+		return false
 	}
-	if ctx, err := extractContext(L.CheckUserData(1)); err == nil && !contextExpired(ctx) {
-		if name := L.CheckString(3); err == nil && name != "" {
-			if domain := s.sys.Config().WhichDomain(name); domain != "" {
-				select {
-				case <-ctx.Done():
-				case <-s.Done():
-				case s.Output() <- &requests.AddrRequest{
-					Address: ip.String(),
-					Domain:  domain,
-				}:
+
+	var domain string
+	if name != "" {
+		var found bool
+		if _, domain, found = s.nameInScope(name); !found {
+			return false
+		}
+	} else if !s.addrInNetworkScope(ip) {
+		return false
+	}
+
+	if !s.emit(ctx, &requests.AddrRequest{
+		Address: addr,
+		InScope: inScope,
+		Domain:  domain,
+	}) {
+		return false
+	}
+
+	s.metrics.assets.Add(1)
+	return true
+}
+
+// addrInNetworkScope returns true when the address matches the addresses, netblocks or ASNs provided
+// for the enumeration. Unlike names, addresses are not in scope when no network scope was provided.
+func (s *Script) addrInNetworkScope(ip net.IP) bool {
+	scope := s.sys.Config().Scope
+	if scope == nil {
+		return false
+	}
+
+	if len(scope.Addresses) > 0 || len(scope.CIDRs) > 0 {
+		if s.sys.Config().IsAddressInScope(ip.String()) {
+			return true
+		}
+	}
+	if len(scope.ASNs) > 0 {
+		if r := s.sys.Cache().AddrSearch(ip.String()); r != nil {
+			for _, asn := range scope.ASNs {
+				if r.ASN == asn {
+					return true
 				}
 			}
 		}
 	}
-	return 0
+	return false
 }
 
 // Wrapper so that scripts can send discovered ASNs to Amass.
@@ -237,6 +370,7 @@ func (s *Script) newASN(L *lua.LState) int {
 				Description:    desc,
 				Netblocks:      netblocks,
 			})
+			s.metrics.assets.Add(1)
 		}
 	}
 	return 0
@@ -246,13 +380,11 @@ func (s *Script) newASN(L *lua.LState) int {
 func (s *Script) associated(L *lua.LState) int {
 	if ctx, err := extractContext(L.CheckUserData(1)); err == nil && !contextExpired(ctx) {
 		if domain, assoc := L.CheckString(2), L.CheckString(3); err == nil && domain != "" && assoc != "" && domain != assoc {
-			select {
-			case <-ctx.Done():
-			case <-s.Done():
-			case s.Output() <- &requests.WhoisRequest{
+			if s.emit(ctx, &requests.WhoisRequest{
 				Domain:     domain,
 				NewDomains: []string{assoc},
-			}:
+			}) {
+				s.metrics.assets.Add(1)
 			}
 		}
 	}
@@ -0,0 +1,108 @@
+// Copyright © by Jeff Foley 2017-2023. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+// SPDX-License-Identifier: Apache-2.0
+
+package scripting
+
+import (
+	lua "github.com/yuin/gopher-lua"
+)
+
+// Maximum number of pages requested by the paginate function when the script does not provide a cap
+const defaultMaxPages = 100
+
+// Wrapper that drives the pagination loop of a script. The fetch function of the script is called for each
+// page with the cursor returned by the previous page, and returns the number of results on the page,
+// the cursor of the next page and an error message. The loop ends once no cursor is returned.
+func (s *Script) paginate(L *lua.LState) int {
+	ctx, err := extractContext(L.CheckUserData(1))
+	if err != nil {
+		L.Push(lua.LNil)
+		L.Push(lua.LString("No user data parameter or context expired"))
+		return 2
+	}
+
+	opt := L.CheckTable(2)
+	if opt == nil {
+		L.Push(lua.LNil)
+		L.Push(lua.LString("No table parameter was provided"))
+		return 2
+	}
+
+	fetch, ok := L.GetField(opt, "fetch").(*lua.LFunction)
+	if !ok {
+		L.Push(lua.LNil)
+		L.Push(lua.LString("No fetch function found in the parameters"))
+		return 2
+	}
+
+	maxPages := defaultMaxPages
+	if n, ok := getNumberField(L, opt, "max_pages"); ok && n > 0 {
+		maxPages = int(n)
+	}
+	var maxResults int
+	if n, ok := getNumberField(L, opt, "max_results"); ok && n > 0 {
+		maxResults = int(n)
+	}
+
+	var estr string
+	var truncated bool
+	var pages, results int
+	cursor := L.GetField(opt, "cursor")
+	for page := 1; ; page++ {
+		// The loop only reaches another page when the previous page provided a cursor
+		if page > maxPages {
+			truncated = true
+			break
+		}
+		if contextExpired(ctx) {
+			estr = "the pagination was cancelled"
+			break
+		}
+
+		err := L.CallByParam(lua.P{
+			Fn:      fetch,
+			NRet:    3,
+			Protect: true,
+		}, L.Get(1), cursor, lua.LNumber(page))
+		if isDryRunStop(err) {
+			L.Error(dryRunStop, 0)
+		} else if err != nil {
+			estr = err.Error()
+			break
+		}
+
+		count, next, ferr := L.Get(-3), L.Get(-2), L.Get(-1)
+		L.Pop(3)
+
+		pages++
+		if n, ok := count.(lua.LNumber); ok && n > 0 {
+			results += int(n)
+		}
+		if str, ok := ferr.(lua.LString); ok && str != "" {
+			estr = string(str)
+			break
+		}
+		if next == lua.LNil || next == lua.LString("") {
+			break
+		}
+		if maxResults > 0 && results >= maxResults {
+			truncated = true
+			break
+		}
+		cursor = next
+	}
+
+	tbl := L.NewTable()
+	tbl.RawSetString("pages", lua.LNumber(pages))
+	tbl.RawSetString("results", lua.LNumber(results))
+	tbl.RawSetString("truncated", lua.LBool(truncated))
+	L.Push(tbl)
+
+	if estr != "" {
+		L.Push(lua.LString(estr))
+	} else {
+		L.Push(lua.LNil)
+	}
+	return 2
+}
@@ -0,0 +1,131 @@
+// Copyright © by Jeff Foley 2017-2023. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+// SPDX-License-Identifier: Apache-2.0
+
+package scripting
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/owasp-amass/amass/v4/requests"
+	lua "github.com/yuin/gopher-lua"
+)
+
+func TestPaginate(t *testing.T) {
+	tests := []struct {
+		label      string
+		maxPages   int
+		maxResults int
+		failPage   int
+		expected   string
+	}{
+		{"All_Pages", 0, 0, 0, "p5-r50-complete"},
+		{"Max_Pages", 3, 0, 0, "p3-r30-truncated"},
+		{"Max_Results", 0, 25, 0, "p3-r30-truncated"},
+		{"Last_Page_Within_Caps", 5, 50, 0, "p5-r50-complete"},
+		{"Failed_Page", 0, 0, 2, "p2-r10-failed"},
+	}
+
+	for _, test := range tests {
+		s, sys := setupMockScriptEnv(fmt.Sprintf(`
+			name="paginate"
+			type="testing"
+
+			function vertical(ctx, domain)
+				local res, err = paginate(ctx, {
+					['max_pages']=%d,
+					['max_results']=%d,
+					['cursor']="start",
+					['fetch']=function(ctx, cursor, page)
+						local expected = "start"
+						if (page > 1) then
+							expected = "page" .. (page - 1)
+						end
+
+						if (cursor ~= expected) then
+							return 0, nil, "unexpected cursor " .. cursor
+						elseif (page == %d) then
+							return 0, nil, "the page failed"
+						elseif (page == 5) then
+							return 10, nil
+						end
+						return 10, "page" .. page
+					end,
+				})
+
+				local outcome = "complete"
+				if (err ~= nil and err ~= "") then
+					outcome = "failed"
+				elseif (res.truncated) then
+					outcome = "truncated"
+				end
+				new_name(ctx, "p" .. res.pages .. "-r" .. res.results .. "-" .. outcome .. "." .. domain)
+			end
+		`, test.maxPages, test.maxResults, test.failPage))
+		if s == nil || sys == nil {
+			t.Fatalf("%s: failed to initialize the scripting environment", test.label)
+		}
+
+		sys.Config().AddDomain("owasp.org")
+		s.Input() <- &requests.DNSRequest{Name: "owasp.org", Domain: "owasp.org"}
+
+		select {
+		case <-time.After(10 * time.Second):
+			t.Errorf("%s: the script did not finish the pagination", test.label)
+		case req := <-s.Output():
+			if d, ok := req.(*requests.DNSRequest); !ok || d.Name != test.expected+".owasp.org" {
+				t.Errorf("%s: expected %s and got %v", test.label, test.expected, req)
+			}
+		}
+		_ = sys.Shutdown()
+	}
+}
+
+func TestPaginateCancellation(t *testing.T) {
+	s, sys := setupMockScriptEnv(`
+		name="cancel"
+		type="testing"
+
+		function vertical(ctx, domain)
+			local res, err = paginate(ctx, {
+				['fetch']=function(ctx, cursor, page)
+					new_name(ctx, "page" .. page .. "." .. domain)
+					return 1, "next"
+				end,
+			})
+			report(res.pages, err)
+		end
+	`)
+	if s == nil || sys == nil {
+		t.Fatal("failed to initialize the scripting environment")
+	}
+	defer func() { _ = sys.Shutdown() }()
+
+	var pages int
+	var estr string
+	L := s.(*Script).luaState
+	L.SetGlobal("report", L.NewFunction(func(L *lua.LState) int {
+		pages = L.CheckInt(1)
+		estr = L.OptString(2, "")
+		return 0
+	}))
+
+	sys.Config().AddDomain("owasp.org")
+	s.Input() <- &requests.DNSRequest{Name: "owasp.org", Domain: "owasp.org"}
+
+	select {
+	case <-time.After(10 * time.Second):
+		t.Fatal("the script did not request the first page")
+	case <-s.Output():
+	}
+	// Stopping the script waits for the in-flight callback to return
+	if err := s.Stop(); err != nil {
+		t.Fatalf("failed to stop the script: %v", err)
+	}
+
+	if estr != "the pagination was cancelled" || pages >= defaultMaxPages {
+		t.Errorf("the pagination was not cancelled between the pages: %d pages, error %q", pages, estr)
+	}
+}
@@ -0,0 +1,107 @@
+// Copyright © by Jeff Foley 2017-2023. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+// SPDX-License-Identifier: Apache-2.0
+
+package scripting
+
+import (
+	"container/list"
+	"sync"
+
+	amassdns "github.com/owasp-amass/amass/v4/net/dns"
+	"github.com/owasp-amass/config/config"
+)
+
+// Bounds the number of scope decisions kept for a script
+const maxScopeEntries = 10000
+
+// sameDomains returns true when the domain lists hold the same domains in the same order.
+func sameDomains(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+type scopeResult struct {
+	key    string
+	name   string
+	domain string
+	found  bool
+}
+
+// scopeCache keeps the scope decisions made for the names provided by a script. The decisions
+// are only valid for the domain list they were made with, so the cache keeps a copy of the list.
+type scopeCache struct {
+	sync.Mutex
+	domains []string
+	order   *list.List
+	entries map[string]*list.Element
+}
+
+func newScopeCache() *scopeCache {
+	return &scopeCache{
+		order:   list.New(),
+		entries: make(map[string]*list.Element),
+	}
+}
+
+// lookup returns the cached decision for the name, and makes the decision when it is not cached.
+// The entries are dropped when the domains in scope change, including the domains added or edited
+// during the enumeration, and the least recently used decision is removed when the cache is full.
+func (c *scopeCache) lookup(cfg *config.Config, name string) (string, string, bool) {
+	domains := cfg.Domains()
+
+	c.Lock()
+	if !sameDomains(domains, c.domains) {
+		c.domains = append([]string(nil), domains...)
+		c.order.Init()
+		c.entries = make(map[string]*list.Element)
+	}
+	if e, found := c.entries[name]; found {
+		c.order.MoveToFront(e)
+		r := e.Value.(*scopeResult)
+		c.Unlock()
+		return r.name, r.domain, r.found
+	}
+	c.Unlock()
+
+	r := &scopeResult{key: name}
+	r.name, r.domain, r.found = checkNameScope(cfg, name)
+
+	c.Lock()
+	defer c.Unlock()
+	// Only keep the decision when the domains did not change while it was made
+	if _, found := c.entries[name]; !found && sameDomains(c.domains, domains) && sameDomains(cfg.Domains(), domains) {
+		c.entries[name] = c.order.PushFront(r)
+		if c.order.Len() > maxScopeEntries {
+			c.remove(c.order.Back())
+		}
+	}
+	return r.name, r.domain, r.found
+}
+
+func (c *scopeCache) remove(e *list.Element) {
+	c.order.Remove(e)
+	delete(c.entries, e.Value.(*scopeResult).key)
+}
+
+// checkNameScope makes the scope decision for the name without the cache. The malformed names
+// are kept, so the enumeration can count them and store them when it allows the malformed names.
+func checkNameScope(cfg *config.Config, name string) (string, string, bool) {
+	n, _ := amassdns.NormalizeFQDN(name)
+	if n == "" {
+		return "", "", false
+	}
+
+	domain := cfg.WhichDomain(n)
+	if domain == "" {
+		return "", "", false
+	}
+	return n, domain, true
+}
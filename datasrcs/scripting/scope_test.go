@@ -0,0 +1,144 @@
+// Copyright © by Jeff Foley 2017-2023. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+// SPDX-License-Identifier: Apache-2.0
+
+package scripting
+
+import (
+	"fmt"
+	"math/rand"
+	"strings"
+	"testing"
+
+	"github.com/owasp-amass/amass/v4/systems"
+	"github.com/owasp-amass/config/config"
+)
+
+var scopeLabels = []string{"www", "WWW", "mail", "dev", "*", "_sip", "-bad", "xn--bcher-kva", "a_b", "", "owasp", "org", "com", "example"}
+
+// randomNames returns names built from labels that are in and out of scope, valid and malformed.
+func randomNames(r *rand.Rand, num int) []string {
+	suffixes := []string{"owasp.org", "OWASP.ORG.", "example.com", "notowasp.org", "org", "owasp.org.example.com", "test.net"}
+
+	names := make([]string, 0, num)
+	for i := 0; i < num; i++ {
+		labels := make([]string, r.Intn(4))
+		for j := range labels {
+			labels[j] = scopeLabels[r.Intn(len(scopeLabels))]
+		}
+		labels = append(labels, suffixes[r.Intn(len(suffixes))])
+
+		name := strings.Join(labels, ".")
+		if r.Intn(10) == 0 {
+			name = " " + name + " "
+		}
+		names = append(names, name)
+	}
+	return names
+}
+
+// newScopeScript returns a script with only the parts needed for the scope checks.
+func newScopeScript(cfg *config.Config) *Script {
+	return &Script{sys: &systems.SimpleSystem{Cfg: cfg}, scope: newScopeCache()}
+}
+
+func TestScopeCacheMatchesUncached(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	cfg := config.NewConfig()
+	cfg.AddDomain("owasp.org")
+	s := newScopeScript(cfg)
+
+	names := randomNames(r, 5000)
+	compare := func(stage string) {
+		// The first pass fills the cache and the second pass is served by it
+		for pass := 0; pass < 2; pass++ {
+			for _, name := range names {
+				n1, d1, f1 := s.nameInScope(name)
+				n2, d2, f2 := checkNameScope(cfg, name)
+				if n1 != n2 || d1 != d2 || f1 != f2 {
+					t.Fatalf("%s: %q: the cached (%q, %q, %t) differs from (%q, %q, %t)", stage, name, n1, d1, f1, n2, d2, f2)
+				}
+			}
+		}
+	}
+
+	compare("initial scope")
+	cfg.AddDomain("example.com")
+	compare("added domain")
+	cfg.Scope.Domains = []string{"test.net"}
+	compare("replaced domains")
+	cfg.Scope.Domains[0] = "owasp.org"
+	compare("edited domain")
+	cfg.Scope.Domains = nil
+	compare("no domains")
+}
+
+func TestScopeCacheEditedDomain(t *testing.T) {
+	cfg := config.NewConfig()
+	cfg.AddDomain("owasp.org")
+
+	c := newScopeCache()
+	if _, _, found := c.lookup(cfg, "www.owasp.org"); !found {
+		t.Fatal("www.owasp.org was not in scope")
+	}
+
+	// The length of the list is unchanged, so only the domains reveal the edit
+	cfg.Scope.Domains[0] = "example.com"
+	if _, _, found := c.lookup(cfg, "www.owasp.org"); found {
+		t.Error("www.owasp.org was still in scope after the domain was edited")
+	}
+}
+
+func TestScopeCacheBounded(t *testing.T) {
+	cfg := config.NewConfig()
+	cfg.AddDomain("owasp.org")
+
+	c := newScopeCache()
+	for i := 0; i < maxScopeEntries+10; i++ {
+		if _, _, found := c.lookup(cfg, fmt.Sprintf("host%d.owasp.org", i)); !found {
+			t.Fatalf("host%d.owasp.org was not in scope", i)
+		}
+		// The first name is used repeatedly, so it is never the least recently used
+		_, _, _ = c.lookup(cfg, "host0.owasp.org")
+	}
+
+	c.Lock()
+	defer c.Unlock()
+	if l := len(c.entries); l != maxScopeEntries || c.order.Len() != maxScopeEntries {
+		t.Errorf("the cache holds %d entries and %d list elements", l, c.order.Len())
+	}
+	// Only the least recently used decisions were removed
+	for _, name := range []string{"host0.owasp.org", fmt.Sprintf("host%d.owasp.org", maxScopeEntries+9)} {
+		if _, found := c.entries[name]; !found {
+			t.Errorf("%s was removed from the cache", name)
+		}
+	}
+	for i := 1; i <= 10; i++ {
+		if _, found := c.entries[fmt.Sprintf("host%d.owasp.org", i)]; found {
+			t.Errorf("host%d.owasp.org was kept in the cache", i)
+		}
+	}
+}
+
+func benchmarkScopeFilter(b *testing.B, check func(cfg *config.Config, name string) (string, string, bool)) {
+	cfg := config.NewConfig()
+	cfg.AddDomains("owasp.org", "example.com", "example.net", "example.edu")
+	// The sources provide the same names repeatedly during an enumeration
+	names := randomNames(rand.New(rand.NewSource(1)), 10000)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		for j := 0; j < 100000; j++ {
+			_, _, _ = check(cfg, names[j%len(names)])
+		}
+	}
+}
+
+func BenchmarkNameInScopeCached(b *testing.B) {
+	c := newScopeCache()
+	benchmarkScopeFilter(b, c.lookup)
+}
+
+func BenchmarkNameInScopeUncached(b *testing.B) {
+	benchmarkScopeFilter(b, checkNameScope)
+}
@@ -10,6 +10,8 @@ import (
 	"fmt"
 	"regexp"
 	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/caffix/service"
 	luaurl "github.com/cjoudrey/gluaurl"
@@ -21,6 +23,9 @@ import (
 	luajson "layeh.com/gopher-json"
 )
 
+// Maximum time that stopping a script waits for the in-flight callback to return
+const stopTimeout = 10 * time.Second
+
 // Script callback functions
 type callbacks struct {
 	Start      lua.LValue
@@ -40,6 +45,7 @@ type Script struct {
 	start      chan struct{}
 	startRet   chan error
 	stop       chan struct{}
+	finished   chan struct{}
 	SourceType string
 	sys        systems.System
 	luaState   *lua.LState
@@ -47,8 +53,22 @@ type Script struct {
 	cbsLock    sync.Mutex
 	subre      *regexp.Regexp
 	seconds    int
+	keys       *keyring
+	keysOnce   sync.Once
+	opts       map[string]interface{}
+	optsOnce   sync.Once
+	breaker    *breaker
+	metrics    *metrics
+	scope      *scopeCache
+	dropFull   bool
 	ctx        context.Context
 	cancel     context.CancelFunc
+	// started is only accessed by the requests goroutine
+	started bool
+	// whois is set while the horizontal callback runs
+	whois    atomic.Bool
+	deadline time.Time
+	dlLock   sync.Mutex
 }
 
 // NewScript returns the object initialized, but not yet started.
@@ -62,8 +82,12 @@ func NewScript(script string, sys systems.System) *Script {
 		start:    make(chan struct{}, 1),
 		startRet: make(chan error, 1),
 		stop:     make(chan struct{}, 1),
+		finished: make(chan struct{}),
 		sys:      sys,
 		subre:    re,
+		breaker:  newBreaker(breakerThreshold, breakerWindow, breakerCooldown),
+		metrics:  new(metrics),
+		scope:    newScopeCache(),
 	}
 	s.ctx, s.cancel = context.WithCancel(context.Background())
 	L := s.newLuaState(sys.Config())
@@ -88,7 +112,6 @@ func NewScript(script string, sys systems.System) *Script {
 
 	s.BaseService = *service.NewBaseService(s, name)
 	s.assignCallbacks()
-	go s.requests()
 	return s
 }
 
@@ -106,6 +129,8 @@ func (s *Script) newLuaState(cfg *config.Config) *lua.LState {
 	registerSocketType(L)
 	L.PreloadModule("url", luaurl.Loader)
 	L.PreloadModule("json", luajson.Loader)
+	L.PreloadModule("subdomain_api", subdomainAPILoader)
+	L.PreloadModule("api_util", apiUtilLoader)
 	L.SetGlobal("config", L.NewFunction(s.config))
 	L.SetGlobal("datasrc_config", L.NewFunction(s.dataSourceConfig))
 	L.SetGlobal("brute_wordlist", L.NewFunction(s.bruteWordlist))
@@ -113,15 +138,24 @@ func (s *Script) newLuaState(cfg *config.Config) *lua.LState {
 	L.SetGlobal("log", L.NewFunction(s.log))
 	L.SetGlobal("find", L.NewFunction(s.find))
 	L.SetGlobal("submatch", L.NewFunction(s.submatch))
+	L.SetGlobal("extract_assets", L.NewFunction(s.extractAssets))
+	L.SetGlobal("registered_domain", L.NewFunction(s.registeredDomain))
 	L.SetGlobal("mtime", L.NewFunction(s.modDateTime))
 	L.SetGlobal("new_name", L.NewFunction(s.newName))
+	L.SetGlobal("new_url", L.NewFunction(s.newURL))
+	L.SetGlobal("resolve_url", L.NewFunction(s.resolveURL))
 	L.SetGlobal("send_names", L.NewFunction(s.sendNames))
+	L.SetGlobal("send_zip_names", L.NewFunction(s.sendZipNames))
 	L.SetGlobal("send_dns_records", L.NewFunction(s.sendDNSRecords))
 	L.SetGlobal("new_addr", L.NewFunction(s.newAddr))
 	L.SetGlobal("new_asn", L.NewFunction(s.newASN))
 	L.SetGlobal("associated", L.NewFunction(s.associated))
 	L.SetGlobal("in_scope", L.NewFunction(s.inScope))
 	L.SetGlobal("request", L.NewFunction(s.request))
+	L.SetGlobal("request_pages", L.NewFunction(s.requestPages))
+	L.SetGlobal("stream_json", L.NewFunction(s.streamJSON))
+	L.SetGlobal("certwatch_query", L.NewFunction(s.certwatchQuery))
+	L.SetGlobal("paginate", L.NewFunction(s.paginate))
 	L.SetGlobal("scrape", L.NewFunction(s.scrape))
 	L.SetGlobal("crawl", L.NewFunction(s.crawl))
 	L.SetGlobal("resolve", L.NewFunction(s.resolve))
@@ -131,6 +165,11 @@ func (s *Script) newLuaState(cfg *config.Config) *lua.LState {
 	L.SetGlobal("output_dir", L.NewFunction(s.outputdir))
 	L.SetGlobal("set_rate_limit", L.NewFunction(s.setRateLimit))
 	L.SetGlobal("check_rate_limit", L.NewFunction(s.checkRateLimit))
+	L.SetGlobal("sleep", L.NewFunction(s.sleep))
+	L.SetGlobal("next_credentials", L.NewFunction(s.nextCredentials))
+	L.SetGlobal("credentials_limited", L.NewFunction(s.credentialsLimited))
+	L.SetGlobal("credentials_failed", L.NewFunction(s.credentialsFailed))
+	L.SetGlobal("endpoint", L.NewFunction(s.endpoint))
 	L.SetGlobal("subdomain_regex", lua.LString(dns.AnySubdomainRegexString()))
 	return L
 }
@@ -185,15 +224,54 @@ func (s *Script) Description() string {
 	return s.SourceType
 }
 
-// OnStart implements the Service interface.
+// OnStart implements the Service interface. The requests are only handled once the script was started,
+// so the scripts that are never started, such as those rejected for a duplicate name, hold no goroutine.
 func (s *Script) OnStart() error {
+	go s.requests()
+
 	s.start <- struct{}{}
 	return <-s.startRet
 }
 
-// OnStop implements the Service interface.
+// Close releases the Lua state of a script that was never started, such as one rejected for a
+// duplicate name. The scripts that were started release their Lua state once they are stopped.
+func (s *Script) Close() {
+	s.stopScript()
+}
+
+// SetStopDeadline implements the deadline shared by the data sources stopped together, so
+// stopping many scripts waits for the in-flight callbacks no longer than stopping one script.
+func (s *Script) SetStopDeadline(deadline time.Time) {
+	s.dlLock.Lock()
+	defer s.dlLock.Unlock()
+
+	s.deadline = deadline
+}
+
+// OnStop implements the Service interface. The outstanding requests of the in-flight callback are
+// cancelled, and the script waits for the callback to return, so no findings are sent after it stopped.
 func (s *Script) OnStop() error {
-	s.stop <- struct{}{}
+	s.cancel()
+	select {
+	case s.stop <- struct{}{}:
+	default:
+	}
+
+	s.dlLock.Lock()
+	wait := time.Until(s.deadline)
+	if s.deadline.IsZero() {
+		wait = stopTimeout
+	}
+	s.dlLock.Unlock()
+
+	t := time.NewTimer(wait)
+	defer t.Stop()
+
+	select {
+	case <-s.finished:
+	case <-t.C:
+		s.sys.Config().Log.Printf("%s: the in-flight callback did not return before the stop deadline", s.String())
+	}
 	return nil
 }
 
@@ -233,6 +311,9 @@ func (s *Script) HandlesReq(req interface{}) bool {
 }
 
 func (s *Script) requests() {
+	defer close(s.finished)
+	defer s.stopScript()
+
 	for {
 		select {
 		case <-s.Done():
@@ -240,16 +321,24 @@ func (s *Script) requests() {
 		case <-s.ctx.Done():
 			return
 		case <-s.start:
-			s.startScript()
+			err := s.startScript()
+
+			s.startRet <- err
+			if err != nil {
+				return
+			}
 		case <-s.stop:
-			s.stopScript()
+			return
 		case in := <-s.Input():
-			s.dispatch(in)
+			// The requests received while stopping are not dispatched
+			if !contextExpired(s.ctx) {
+				s.dispatch(in)
+			}
 		}
 	}
 }
 
-func (s *Script) startScript() {
+func (s *Script) startScript() error {
 	if L := s.luaState; s.cbs.Start.Type() != lua.LTNil {
 		err := L.CallByParam(lua.P{
 			Fn:      s.cbs.Start,
@@ -258,16 +347,20 @@ func (s *Script) startScript() {
 		})
 		if err != nil {
 			s.sys.Config().Log.Printf("%s: start callback: %v", s.String(), err)
-			s.startRet <- err
-			return
+			return err
 		}
 	}
 
 	if s.seconds > 0 {
 		s.SetRateLimit(1)
 	}
+	s.dropFull = s.dropWhenFull()
 
-	s.startRet <- s.checkConfig()
+	if err := s.checkConfig(); err != nil {
+		return err
+	}
+	s.started = true
+	return nil
 }
 
 func (s *Script) checkConfig() error {
@@ -302,10 +395,11 @@ func (s *Script) checkConfig() error {
 	return errors.New(estr)
 }
 
+// stopScript releases the Lua state, and only executes the stop callback when the script was started.
 func (s *Script) stopScript() {
 	s.cancel()
 
-	if L := s.luaState; s.cbs.Stop.Type() != lua.LTNil {
+	if L := s.luaState; s.started && s.cbs.Stop.Type() != lua.LTNil {
 		err := L.CallByParam(lua.P{
 			Fn:      s.cbs.Stop,
 			NRet:    0,
@@ -389,7 +483,7 @@ func (s *Script) dnsRequest(ctx context.Context, callback lua.LValue, req *reque
 		NRet:    0,
 		Protect: true,
 	}, s.contextToUserData(ctx), lua.LString(req.Domain))
-	if err != nil {
+	if err != nil && !isDryRunStop(err) {
 		s.sys.Config().Log.Printf("%s: vertical callback: %v", s.String(), err)
 	}
 }
@@ -416,7 +510,7 @@ func (s *Script) resolvedRequest(ctx context.Context, callback lua.LValue, req *
 		NRet:    0,
 		Protect: true,
 	}, s.contextToUserData(ctx), lua.LString(req.Name), lua.LString(req.Domain), records)
-	if err != nil {
+	if err != nil && !isDryRunStop(err) {
 		s.sys.Config().Log.Printf("%s: resolved callback: %v", s.String(), err)
 	}
 }
@@ -433,7 +527,7 @@ func (s *Script) subdomainRequest(ctx context.Context, callback lua.LValue, req
 		NRet:    0,
 		Protect: true,
 	}, s.contextToUserData(ctx), lua.LString(req.Name), lua.LString(req.Domain), lua.LNumber(req.Times))
-	if err != nil {
+	if err != nil && !isDryRunStop(err) {
 		s.sys.Config().Log.Printf("%s: subdomain callback: %v", s.String(), err)
 	}
 }
@@ -450,7 +544,7 @@ func (s *Script) addrRequest(ctx context.Context, callback lua.LValue, req *requ
 		NRet:    0,
 		Protect: true,
 	}, s.contextToUserData(ctx), lua.LString(req.Address))
-	if err != nil {
+	if err != nil && !isDryRunStop(err) {
 		s.sys.Config().Log.Printf("%s: address callback: %v", s.String(), err)
 	}
 }
@@ -467,7 +561,7 @@ func (s *Script) asnRequest(ctx context.Context, callback lua.LValue, req *reque
 		NRet:    0,
 		Protect: true,
 	}, s.contextToUserData(ctx), lua.LString(req.Address), lua.LNumber(req.ASN))
-	if err != nil {
+	if err != nil && !isDryRunStop(err) {
 		s.sys.Config().Log.Printf("%s: asn callback: %v", s.String(), err)
 	}
 }
@@ -479,12 +573,15 @@ func (s *Script) whoisRequest(ctx context.Context, callback lua.LValue, req *req
 		return
 	}
 
+	s.whois.Store(true)
+	defer s.whois.Store(false)
+
 	err := L.CallByParam(lua.P{
 		Fn:      callback,
 		NRet:    0,
 		Protect: true,
 	}, s.contextToUserData(ctx), lua.LString(req.Domain))
-	if err != nil {
+	if err != nil && !isDryRunStop(err) {
 		s.sys.Config().Log.Printf("%s: horizontal callback: %v", s.String(), err)
 	}
 }
@@ -5,12 +5,24 @@
 package scripting
 
 import (
+	"bytes"
+	"fmt"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
 	"github.com/caffix/netmap"
 	"github.com/caffix/service"
+	amasshttp "github.com/owasp-amass/amass/v4/net/http"
 	"github.com/owasp-amass/amass/v4/requests"
 	"github.com/owasp-amass/amass/v4/systems"
 	"github.com/owasp-amass/config/config"
 	"github.com/owasp-amass/resolve"
+	lua "github.com/yuin/gopher-lua"
 )
 
 func setupMockScriptEnv(script string) (service.Service, systems.System) {
@@ -31,6 +43,7 @@ func newMockSystem(cfg *config.Config) systems.System {
 		Trusted:  resolve.NewResolvers(),
 		Graph:    netmap.NewGraph("memory", "", ""),
 		ASNCache: requests.NewASNCache(),
+		Hosts:    amasshttp.NewHostLimiters(),
 	}
 
 	ss.Pool.SetLogger(cfg.Log)
@@ -39,3 +52,241 @@ func newMockSystem(cfg *config.Config) systems.System {
 	_ = ss.Trusted.AddResolvers(20, "8.8.8.8")
 	return ss
 }
+
+func TestExtractAssetsFunction(t *testing.T) {
+	s, sys := setupMockScriptEnv(`
+		name="extract"
+		type="testing"
+
+		function vertical(ctx, domain)
+			local assets = extract_assets("mail admin[at]owasp.org via https://api.owasp.org at 93.184.216.34")
+			for _, list in pairs({assets.names, assets.emails, assets.urls}) do
+				for _, v in pairs(list) do
+					new_name(ctx, string.match(v, "([%w%.]+)$"))
+				end
+			end
+			for _, addr in pairs(assets.addrs) do
+				new_addr(ctx, addr, domain)
+			end
+		end
+	`)
+	if s == nil || sys == nil {
+		t.Fatal("failed to initialize the scripting environment")
+	}
+	defer func() { _ = sys.Shutdown() }()
+
+	sys.Config().AddDomain("owasp.org")
+	s.Input() <- &requests.DNSRequest{Name: "owasp.org", Domain: "owasp.org"}
+
+	timer := time.NewTimer(10 * time.Second)
+	defer timer.Stop()
+
+	// The names, the email address, the URL and the address
+	var results []string
+	for len(results) < 5 {
+		select {
+		case <-timer.C:
+			t.Fatalf("the script only provided %v", results)
+		case req := <-s.Output():
+			switch v := req.(type) {
+			case *requests.DNSRequest:
+				results = append(results, v.Name)
+			case *requests.AddrRequest:
+				results = append(results, v.Address)
+			}
+		}
+	}
+
+	if got := strings.Join(results, ","); got != "owasp.org,api.owasp.org,owasp.org,api.owasp.org,93.184.216.34" {
+		t.Errorf("the script extracted the wrong assets: %s", got)
+	}
+}
+
+func TestStopDrainsInFlightCallback(t *testing.T) {
+	received := make(chan struct{})
+	cancelled := make(chan struct{})
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(received)
+
+		select {
+		case <-r.Context().Done():
+			close(cancelled)
+		case <-time.After(2 * stopTimeout):
+			_, _ = w.Write([]byte("www.owasp.org"))
+		}
+	}))
+	defer ts.Close()
+
+	s, sys := setupMockScriptEnv(fmt.Sprintf(`
+		name="drain"
+		type="testing"
+
+		function vertical(ctx, domain)
+			local resp, err = request(ctx, {['url']="%s"})
+			if (err == nil or err == "") then
+				new_name(ctx, resp.body)
+			end
+			new_name(ctx, "late." .. domain)
+			returned()
+		end
+	`, ts.URL))
+	if s == nil || sys == nil {
+		t.Fatal("failed to initialize the scripting environment")
+	}
+	defer func() { _ = sys.Shutdown() }()
+
+	var returned bool
+	L := s.(*Script).luaState
+	L.SetGlobal("returned", L.NewFunction(func(L *lua.LState) int {
+		returned = true
+		return 0
+	}))
+
+	sys.Config().AddDomain("owasp.org")
+	s.Input() <- &requests.DNSRequest{Name: "owasp.org", Domain: "owasp.org"}
+
+	select {
+	case <-time.After(10 * time.Second):
+		t.Fatal("the script did not send the request")
+	case <-received:
+	}
+
+	start := time.Now()
+	if err := s.Stop(); err != nil {
+		t.Fatalf("failed to stop the script: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed >= stopTimeout {
+		t.Errorf("the script took %v to stop", elapsed)
+	}
+
+	if !returned {
+		t.Error("the script stopped before the in-flight callback returned")
+	}
+	// The server observes the cancellation once the connection has been closed
+	select {
+	case <-cancelled:
+	case <-time.After(time.Second):
+		t.Error("the outstanding request was not cancelled")
+	}
+
+	select {
+	case req := <-s.Output():
+		t.Errorf("the script provided %v after it stopped", req)
+	case <-time.After(250 * time.Millisecond):
+	}
+}
+
+func TestStopSkipsUnstartedScripts(t *testing.T) {
+	var buf bytes.Buffer
+	cfg := config.NewConfig()
+	cfg.Log = log.New(&buf, "", 0)
+	sys := newMockSystem(cfg)
+	defer func() { _ = sys.Shutdown() }()
+
+	s := NewScript(`
+		name="unstarted"
+		type="testing"
+
+		function check()
+			return false
+		end
+
+		function stop()
+			error("the script that failed to start was stopped")
+		end
+	`, sys)
+	if s == nil {
+		t.Fatal("failed to initialize the scripting environment")
+	}
+	if err := s.Start(); err == nil {
+		t.Fatal("the script started without passing the check")
+	}
+
+	select {
+	case <-s.finished:
+	case <-time.After(5 * time.Second):
+		t.Fatal("the script did not release its resources after failing to start")
+	}
+	if strings.Contains(buf.String(), "stop callback") {
+		t.Errorf("the stop callback was executed for the script that failed to start: %s", buf.String())
+	}
+}
+
+func TestCloseUnstartedScript(t *testing.T) {
+	var buf bytes.Buffer
+	cfg := config.NewConfig()
+	cfg.Log = log.New(&buf, "", 0)
+	sys := newMockSystem(cfg)
+	defer func() { _ = sys.Shutdown() }()
+
+	s := NewScript(`
+		name="rejected"
+		type="testing"
+
+		function stop()
+			error("the script that was never started was stopped")
+		end
+	`, sys)
+	if s == nil {
+		t.Fatal("failed to initialize the scripting environment")
+	}
+
+	s.Close()
+	if s.luaState != nil {
+		t.Error("the Lua state of the script was not released")
+	}
+	if s.ctx.Err() == nil {
+		t.Error("the context of the script was not cancelled")
+	}
+	if strings.Contains(buf.String(), "stop callback") {
+		t.Errorf("the stop callback was executed for the script that was never started: %s", buf.String())
+	}
+}
+
+func TestStopSharedDeadline(t *testing.T) {
+	var scripts []*Script
+	for i := 0; i < 3; i++ {
+		s, sys := setupMockScriptEnv(fmt.Sprintf(`
+			name="deadline%d"
+			type="testing"
+
+			function vertical(ctx, domain)
+				block()
+			end
+		`, i))
+		if s == nil || sys == nil {
+			t.Fatal("failed to initialize the scripting environment")
+		}
+		defer func() { _ = sys.Shutdown() }()
+
+		script := s.(*Script)
+		entered := make(chan struct{})
+		L := script.luaState
+		// The callback ignores the cancellation, so only the deadline ends the wait
+		L.SetGlobal("block", L.NewFunction(func(L *lua.LState) int {
+			close(entered)
+			time.Sleep(2 * time.Second)
+			return 0
+		}))
+		script.Input() <- &requests.DNSRequest{Name: "owasp.org", Domain: "owasp.org"}
+		<-entered
+		scripts = append(scripts, script)
+	}
+
+	start := time.Now()
+	deadline := start.Add(250 * time.Millisecond)
+	var wg sync.WaitGroup
+	for _, s := range scripts {
+		s.SetStopDeadline(deadline)
+		wg.Add(1)
+		go func(s *Script) {
+			defer wg.Done()
+			_ = s.Stop()
+		}(s)
+	}
+	wg.Wait()
+
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("stopping the scripts took %v, which exceeds the shared deadline", elapsed)
+	}
+}
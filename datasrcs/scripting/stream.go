@@ -0,0 +1,120 @@
+// Copyright © by Jeff Foley 2017-2023. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+// SPDX-License-Identifier: Apache-2.0
+
+package scripting
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+
+	"github.com/owasp-amass/amass/v4/net/http"
+	lua "github.com/yuin/gopher-lua"
+)
+
+// maxErrorBodyBytes bounds the body read from a streamed response that was not successful.
+const maxErrorBodyBytes int64 = 64 * 1024
+
+// Wrapper so that scripts can process the elements of a JSON array response one at a time, without
+// holding the whole response in memory. The callback receives each element, and returns false to
+// stop the processing. The response is returned without the body when the request was successful.
+func (s *Script) streamJSON(L *lua.LState) int {
+	ctx, err := extractContext(L.CheckUserData(1))
+	if err != nil {
+		L.Push(lua.LNil)
+		L.Push(lua.LString("No user data parameter or context expired"))
+		return 2
+	}
+
+	opt := L.CheckTable(2)
+	fn := L.CheckFunction(3)
+	url, found := getStringField(L, opt, "url")
+	if !found {
+		L.Push(lua.LNil)
+		L.Push(lua.LString("No URL found in the parameters"))
+		return 2
+	}
+
+	resp, err := s.stream(ctx, url, headerField(L, opt), func(v interface{}) (bool, error) {
+		if err := L.CallByParam(lua.P{
+			Fn:      fn,
+			NRet:    1,
+			Protect: true,
+		}, toLuaValue(L, v)); err != nil {
+			return false, err
+		}
+
+		ret := L.Get(-1)
+		L.Pop(1)
+		return ret != lua.LFalse, nil
+	})
+	stopForDryRun(L, err)
+
+	if resp == nil {
+		L.Push(lua.LNil)
+	} else {
+		L.Push(responseToTable(L, resp))
+	}
+	if err != nil {
+		L.Push(lua.LString(err.Error()))
+	} else {
+		L.Push(lua.LNil)
+	}
+	return 2
+}
+
+// stream sends the request and passes the elements of the JSON array in the response body to fn,
+// until fn returns false. The body of an unsuccessful response is returned for the error message.
+func (s *Script) stream(ctx context.Context, url string, hdr http.Header, fn func(interface{}) (bool, error)) (*http.Response, error) {
+	if err := s.beforeRequest(ctx, "GET", url); err != nil {
+		return nil, err
+	}
+
+	resp, body, err := http.OpenWebPage(ctx, &http.Request{
+		URL:    url,
+		Header: hdr,
+	})
+	s.recordOutcome(ctx, resp, err)
+	s.metrics.recordQuery(resp, err)
+	if err != nil {
+		return nil, err
+	}
+	defer body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		b, _ := io.ReadAll(io.LimitReader(body, maxErrorBodyBytes))
+		resp.Body = string(b)
+		return resp, nil
+	}
+	return resp, decodeJSONArray(body, fn)
+}
+
+// decodeJSONArray decodes the elements of the JSON array read from r one at a time. An empty body is an empty array.
+func decodeJSONArray(r io.Reader, fn func(interface{}) (bool, error)) error {
+	dec := json.NewDecoder(r)
+
+	tok, err := dec.Token()
+	if errors.Is(err, io.EOF) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	if d, ok := tok.(json.Delim); !ok || d != '[' {
+		return errors.New("the response does not provide a JSON array")
+	}
+
+	for dec.More() {
+		var v interface{}
+		if err := dec.Decode(&v); err != nil {
+			return err
+		}
+		if more, err := fn(v); err != nil || !more {
+			return err
+		}
+	}
+
+	_, err = dec.Token()
+	return err
+}
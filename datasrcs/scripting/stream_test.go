@@ -0,0 +1,40 @@
+// Copyright © by Jeff Foley 2017-2023. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+// SPDX-License-Identifier: Apache-2.0
+
+package scripting
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestDecodeJSONArray(t *testing.T) {
+	tests := []struct {
+		body     string
+		stop     int
+		expected int
+		err      bool
+	}{
+		{"", 0, 0, false},
+		{"[]", 0, 0, false},
+		{`[{"id":1},{"id":2},{"id":3}]`, 0, 3, false},
+		{`[{"id":1},{"id":2},{"id":3}]`, 2, 2, false},
+		{`{"error":"unauthorized"}`, 0, 0, true},
+		{`[{"id":1},{"id":`, 0, 1, true},
+	}
+	for _, test := range tests {
+		var count int
+		err := decodeJSONArray(strings.NewReader(test.body), func(v interface{}) (bool, error) {
+			count++
+			return test.stop == 0 || count < test.stop, nil
+		})
+
+		if (err != nil) != test.err {
+			t.Errorf("%q: unexpected error value: %v", test.body, err)
+		}
+		if count != test.expected {
+			t.Errorf("%q: expected %d elements and got %d", test.body, test.expected, count)
+		}
+	}
+}
@@ -0,0 +1,28 @@
+// Copyright © by Jeff Foley 2017-2023. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+// SPDX-License-Identifier: Apache-2.0
+
+package scripting
+
+import (
+	_ "embed"
+
+	lua "github.com/yuin/gopher-lua"
+)
+
+// The Lua module providing the callbacks shared by the simple subdomain API scripts.
+//
+//go:embed lib/subdomain_api.lua
+var subdomainAPIModule string
+
+func subdomainAPILoader(L *lua.LState) int {
+	fn, err := L.LoadString(subdomainAPIModule)
+	if err != nil {
+		L.RaiseError("failed to load the subdomain_api module: %v", err)
+		return 0
+	}
+
+	L.Push(fn)
+	L.Call(0, 1)
+	return 1
+}
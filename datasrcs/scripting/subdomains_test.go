@@ -0,0 +1,99 @@
+// Copyright © by Jeff Foley 2017-2023. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+// SPDX-License-Identifier: Apache-2.0
+
+package scripting
+
+import (
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/owasp-amass/amass/v4/requests"
+	"github.com/owasp-amass/config/config"
+)
+
+const subdomainAPITestScript = `
+local subdomain_api = require("subdomain_api")
+
+name = "SubdomainAPI"
+type = "api"
+
+subdomain_api.register({
+    ['key_required']=true,
+    ['query']=function(ctx, domain, c)
+        if (c.key ~= "testing") then
+            return nil
+        end
+        return {"www." .. domain, "WWW." .. domain, "", 42, {['name']="ftp." .. domain}, "mail." .. domain, "www.example.com"}
+    end,
+})
+`
+
+func TestSubdomainAPIRegister(t *testing.T) {
+	cfg := config.NewConfig()
+	cfg.AddDomain("owasp.org")
+	src := &config.DataSource{Name: "SubdomainAPI"}
+	_ = src.AddCredentials("account", &config.Credentials{Name: "SubdomainAPI", Apikey: "testing"})
+	cfg.DataSrcConfigs = &config.DataSourceConfig{Datasources: []*config.DataSource{src}}
+
+	sys := newMockSystem(cfg)
+	defer func() { _ = sys.Shutdown() }()
+
+	s := NewScript(subdomainAPITestScript, sys)
+	if s == nil {
+		t.Fatal("failed to initialize the scripting environment")
+	}
+	if err := sys.AddAndStart(s); err != nil {
+		t.Fatalf("failed to start the script: %v", err)
+	}
+	if s.seconds != 1 {
+		t.Errorf("expected the default rate limit of 1 second and got %d", s.seconds)
+	}
+
+	s.Input() <- &requests.DNSRequest{Name: "owasp.org", Domain: "owasp.org"}
+
+	var names []string
+	timer := time.NewTimer(10 * time.Second)
+	defer timer.Stop()
+loop:
+	for len(names) < 2 {
+		select {
+		case <-timer.C:
+			break loop
+		case req := <-s.Output():
+			if d, ok := req.(*requests.DNSRequest); ok {
+				names = append(names, d.Name)
+			}
+		}
+	}
+	// Allow an unexpected name to arrive before checking the results
+	select {
+	case req := <-s.Output():
+		if d, ok := req.(*requests.DNSRequest); ok {
+			names = append(names, d.Name)
+		}
+	case <-time.After(250 * time.Millisecond):
+	}
+
+	sort.Strings(names)
+	if strings.Join(names, ",") != "mail.owasp.org,www.owasp.org" {
+		t.Errorf("the query names were not filtered as expected: %v", names)
+	}
+}
+
+func TestSubdomainAPIKeyRequired(t *testing.T) {
+	cfg := config.NewConfig()
+	sys := newMockSystem(cfg)
+	defer func() { _ = sys.Shutdown() }()
+
+	s := NewScript(subdomainAPITestScript, sys)
+	if s == nil {
+		t.Fatal("failed to initialize the scripting environment")
+	}
+	if err := s.Start(); err == nil {
+		t.Error("the script started without an API key")
+		_ = s.Stop()
+	}
+}
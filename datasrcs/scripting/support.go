@@ -9,8 +9,11 @@ import (
 	"errors"
 	"os"
 	"regexp"
+	"strings"
 
+	"github.com/owasp-amass/amass/v4/net/http"
 	lua "github.com/yuin/gopher-lua"
+	"golang.org/x/net/publicsuffix"
 )
 
 type contextWrapper struct {
@@ -120,6 +123,42 @@ func (s *Script) submatch(L *lua.LState) int {
 	return 1
 }
 
+// Wrapper that exposes a function that returns the names, email addresses, IP addresses and URLs in the content.
+func (s *Script) extractAssets(L *lua.LState) int {
+	assets := http.ExtractAssets(L.CheckString(1))
+
+	tb := L.NewTable()
+	for key, values := range map[string][]string{
+		"names":  assets.Names,
+		"emails": assets.Emails,
+		"addrs":  assets.Addrs,
+		"urls":   assets.URLs,
+	} {
+		list := L.NewTable()
+		for _, v := range values {
+			list.Append(lua.LString(v))
+		}
+		L.SetField(tb, key, list)
+	}
+
+	L.Push(tb)
+	return 1
+}
+
+// Wrapper that exposes a function that returns the registered domain name of a subdomain name.
+func (s *Script) registeredDomain(L *lua.LState) int {
+	var domain string
+
+	if name := strings.ToLower(strings.TrimSuffix(L.CheckString(1), ".")); name != "" {
+		if d, err := publicsuffix.EffectiveTLDPlusOne(name); err == nil {
+			domain = d
+		}
+	}
+
+	L.Push(lua.LString(domain))
+	return 1
+}
+
 // Wrapper that exposes a function that returns the modification date/time of a file.
 func (s *Script) modDateTime(L *lua.LState) int {
 	var seconds int64
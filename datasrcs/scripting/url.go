@@ -0,0 +1,91 @@
+// Copyright © by Jeff Foley 2017-2023. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+// SPDX-License-Identifier: Apache-2.0
+
+package scripting
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+
+	amassdns "github.com/owasp-amass/amass/v4/net/dns"
+	lua "github.com/yuin/gopher-lua"
+)
+
+// Wrapper so that scripts can resolve a reference, such as a link found in a page, against the URL of the page.
+// The normalized URL is returned with true when its host is in scope, and nil is returned when it is not valid.
+func (s *Script) resolveURL(L *lua.LState) int {
+	u, err := resolveReference(L.CheckString(1), L.CheckString(2))
+	if err != nil {
+		L.Push(lua.LNil)
+		L.Push(lua.LFalse)
+		return 2
+	}
+
+	L.Push(lua.LString(u.String()))
+	L.Push(lua.LBool(s.urlInScope(u)))
+	return 2
+}
+
+// urlInScope returns true when the name in the host of the URL is in scope, or when the
+// address in the host is within the network scope of the enumeration.
+func (s *Script) urlInScope(u *url.URL) bool {
+	host := u.Hostname()
+	if ip := net.ParseIP(host); ip != nil {
+		return s.addrInNetworkScope(ip)
+	}
+
+	_, _, found := s.nameInScope(host)
+	return found
+}
+
+// resolveReference returns the reference resolved against the base URL, which can be empty when the reference
+// is absolute. The scheme and the host are lowercased, internationalized names are converted to their ASCII
+// form, and the default port of the scheme and the fragment are removed.
+func resolveReference(base, ref string) (*url.URL, error) {
+	b, err := url.Parse(strings.TrimSpace(base))
+	if err != nil {
+		return nil, err
+	}
+	r, err := url.Parse(strings.TrimSpace(ref))
+	if err != nil {
+		return nil, err
+	}
+
+	u := b.ResolveReference(r)
+	u.Scheme = strings.ToLower(u.Scheme)
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return nil, fmt.Errorf("the scheme %q is not supported", u.Scheme)
+	}
+
+	host := u.Hostname()
+	if host == "" {
+		return nil, errors.New("no host was provided")
+	}
+	if ip := net.ParseIP(host); ip != nil {
+		host = ip.String()
+	} else if name, err := amassdns.NormalizeFQDN(host); err == nil {
+		host = name
+	} else {
+		return nil, err
+	}
+
+	port := u.Port()
+	if (u.Scheme == "http" && port == "80") || (u.Scheme == "https" && port == "443") {
+		port = ""
+	}
+	if strings.Contains(host, ":") {
+		host = "[" + host + "]"
+	}
+	if port != "" {
+		host += ":" + port
+	}
+
+	u.Host = host
+	u.Fragment = ""
+	u.RawFragment = ""
+	return u, nil
+}
@@ -0,0 +1,99 @@
+// Copyright © by Jeff Foley 2017-2023. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+// SPDX-License-Identifier: Apache-2.0
+
+package scripting
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/owasp-amass/amass/v4/requests"
+)
+
+func TestResolveReference(t *testing.T) {
+	base := "https://www.owasp.org/search/results?page=1#top"
+
+	tests := []struct {
+		label    string
+		base     string
+		ref      string
+		expected string
+	}{
+		{"Query", base, "?page=2", "https://www.owasp.org/search/results?page=2"},
+		{"Relative_Path", base, "next/", "https://www.owasp.org/search/next/"},
+		{"Parent_Path", base, "../about", "https://www.owasp.org/about"},
+		{"Absolute_Path", base, "/docs#intro", "https://www.owasp.org/docs"},
+		{"Network_Path", base, "//api.owasp.org/v1", "https://api.owasp.org/v1"},
+		{"Absolute_URL", base, "HTTP://WWW.OWASP.ORG:80/Index.html", "http://www.owasp.org/Index.html"},
+		{"No_Base", "", "https://owasp.org:443/", "https://owasp.org/"},
+		{"Other_Port", base, "http://owasp.org:8443/", "http://owasp.org:8443/"},
+		{"IPv4", base, "http://192.168.1.1:80/admin", "http://192.168.1.1/admin"},
+		{"IPv6", base, "https://[2001:DB8::0001]:443/", "https://[2001:db8::1]/"},
+		{"IPv6_Port", base, "https://[2001:db8::1]:8443/", "https://[2001:db8::1]:8443/"},
+		{"Punycode", base, "https://Bücher.owasp.org/", "https://xn--bcher-kva.owasp.org/"},
+		{"Trailing_Dot", base, "https://www.owasp.org./", "https://www.owasp.org/"},
+		{"Unsupported_Scheme", base, "mailto:admin@owasp.org", ""},
+		{"Relative_Without_Base", "", "/docs", ""},
+		{"Invalid_Host", base, "https://www..owasp.org/", ""},
+	}
+
+	for _, test := range tests {
+		var got string
+		if u, err := resolveReference(test.base, test.ref); err == nil {
+			got = u.String()
+		}
+		if got != test.expected {
+			t.Errorf("%s: expected %q and got %q", test.label, test.expected, got)
+		}
+	}
+}
+
+func TestResolveURLScope(t *testing.T) {
+	script, sys := setupMockScriptEnv(`
+		name="resolve_url"
+		type="testing"
+
+		function vertical(ctx, domain)
+			local refs = {"/docs", "https://www.example.com/", "http://192.168.1.10/", "http://10.0.0.1/", "ftp://owasp.org/"}
+			for i, ref in ipairs(refs) do
+				local u, scope = resolve_url("https://www." .. domain .. "/index.html", ref)
+				if (u ~= nil and scope) then
+					new_name(ctx, "ref" .. i .. "." .. domain)
+				end
+			end
+		end
+	`)
+	if script == nil || sys == nil {
+		t.Fatal("failed to initialize the scripting environment")
+	}
+	defer func() { _ = sys.Shutdown() }()
+
+	cfg := sys.Config()
+	cfg.AddDomain("owasp.org")
+	_, ipnet, _ := net.ParseCIDR("192.168.1.0/24")
+	cfg.Scope.CIDRs = append(cfg.Scope.CIDRs, ipnet)
+	script.Input() <- &requests.DNSRequest{Name: "owasp.org", Domain: "owasp.org"}
+
+	timer := time.NewTimer(10 * time.Second)
+	defer timer.Stop()
+
+	// The relative reference and the address within the network scope
+	for _, expected := range []string{"ref1.owasp.org", "ref3.owasp.org"} {
+		select {
+		case <-timer.C:
+			t.Fatalf("the script did not provide %s", expected)
+		case req := <-script.Output():
+			if d, ok := req.(*requests.DNSRequest); !ok || d.Name != expected {
+				t.Errorf("expected %s and the script returned %v", expected, req)
+			}
+		}
+	}
+
+	select {
+	case req := <-script.Output():
+		t.Errorf("a URL out of scope was reported in scope: %v", req)
+	case <-time.After(100 * time.Millisecond):
+	}
+}
@@ -0,0 +1,461 @@
+// Copyright © by Jeff Foley 2017-2023. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+// SPDX-License-Identifier: Apache-2.0
+
+package datasrcs
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/owasp-amass/amass/v4/requests"
+	"github.com/owasp-amass/config/config"
+)
+
+// Appended to the embedded script to remove the rate limit and send the requests to the test server
+const securityTrailsTestOverrides = `
+function start() end
+
+function vert_url(domain)
+    return "%[1]s/v1/domain/" .. domain .. "/subdomains"
+end
+
+function usage_url()
+    return "%[1]s/v1/account/usage"
+end
+`
+
+// Returns the overrides for the SecurityTrails script along with the functions, where the
+// %[1]s verbs are replaced with the URL of the test server.
+func securityTrailsOverrides(funcs string) func(url string) string {
+	return func(url string) string {
+		return fmt.Sprintf(securityTrailsTestOverrides+funcs, url)
+	}
+}
+
+// Responds to the usage checks with the quota of the keys, and to the other requests using the handler.
+func securityTrailsHandler(usage int, handler http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/v1/account/usage" {
+			_, _ = fmt.Fprintf(w, `{"current_monthly_usage":%d,"allowed_monthly_usage":50}`, usage)
+			return
+		}
+		handler(w, r)
+	}
+}
+
+func TestSecurityTrailsKeyRotation(t *testing.T) {
+	var lock sync.Mutex
+	counts := make(map[string]int)
+	setup := withKeys("SecurityTrails", []string{"rejected", "limited", "good"})
+	names, _ := runConfiguredScript(t, "scripts/api/securitytrails.ads", setup, securityTrailsOverrides(""),
+		securityTrailsHandler(10, func(w http.ResponseWriter, r *http.Request) {
+			key := r.Header.Get("APIKEY")
+			lock.Lock()
+			counts[key]++
+			lock.Unlock()
+
+			switch key {
+			case "rejected":
+				w.WriteHeader(http.StatusForbidden)
+			case "limited":
+				w.WriteHeader(http.StatusTooManyRequests)
+			default:
+				_, _ = w.Write([]byte(`{"subdomains":["www"],"subdomain_count":1}`))
+			}
+		}))
+
+	if len(names) != 1 || names[0] != "www.owasp.org" {
+		t.Errorf("the script returned the wrong names: %v", names)
+	}
+
+	lock.Lock()
+	defer lock.Unlock()
+	if counts["rejected"] != 1 || counts["limited"] != 1 || counts["good"] != 1 {
+		t.Errorf("the keys were not rotated as expected: %v", counts)
+	}
+}
+
+func TestSecurityTrailsExhaustedKeys(t *testing.T) {
+	var lock sync.Mutex
+	var usage, queries int
+	setup := withKeys("SecurityTrails", []string{"first", "second"})
+	names, logs := runConfiguredScript(t, "scripts/api/securitytrails.ads", setup, securityTrailsOverrides(""), func(w http.ResponseWriter, r *http.Request) {
+		lock.Lock()
+		defer lock.Unlock()
+
+		if r.URL.Path == "/v1/account/usage" {
+			usage++
+			_, _ = w.Write([]byte(`{"current_monthly_usage":50,"allowed_monthly_usage":50}`))
+			return
+		}
+		queries++
+		_, _ = w.Write([]byte(`{"subdomains":["www"],"subdomain_count":1}`))
+	})
+
+	if len(names) > 0 {
+		t.Errorf("the exhausted keys provided names: %v", names)
+	}
+
+	lock.Lock()
+	defer lock.Unlock()
+	if usage != 2 || queries != 0 {
+		t.Errorf("expected 2 usage checks and no queries, the service received %d and %d", usage, queries)
+	}
+	for _, account := range []string{"account0", "account1"} {
+		if n := strings.Count(logs, "the "+account+" key has exhausted the monthly quota"); n != 1 {
+			t.Errorf("the warning for the %s key was logged %d times: %s", account, n, logs)
+		}
+	}
+}
+
+func TestSecurityTrailsNearbyHostnames(t *testing.T) {
+	tests := []struct {
+		label   string
+		options string
+		names   string
+		nearby  string
+	}{
+		// The second address shares the neighborhood of the first, so only two queries are sent
+		{"Enabled", "      ip_neighbors: true\n", "www.owasp.org,mail.owasp.org,www.owasp.org,mail.owasp.org", "93.184.216.34,104.22.26.77"},
+		// The nearby lookups consume the quota, so they are only sent when requested
+		{"Default", "", "", ""},
+	}
+
+	for _, test := range tests {
+		var lock sync.Mutex
+		var nearby []string
+		res := scriptRun{
+			path:  "scripts/api/securitytrails.ads",
+			setup: withSetup(withKeys("SecurityTrails", []string{"good"}), withOptions(t, "SecurityTrails", test.options)),
+			overrides: securityTrailsOverrides(`
+function nearby_url(addr)
+    return "%[1]s/v1/ips/nearby/" .. addr
+end
+`),
+			handler: securityTrailsHandler(10, func(w http.ResponseWriter, r *http.Request) {
+				lock.Lock()
+				nearby = append(nearby, strings.TrimPrefix(r.URL.Path, "/v1/ips/nearby/"))
+				lock.Unlock()
+				_, _ = w.Write([]byte(`{"endpoint":"/v1/ips/nearby/93.184.216.34","blocks":[
+					{"ip":"93.184.216.32/28","hostnames":["www.owasp.org","www.example.com"],"ports":[443],"sites":2},
+					{"ip":"93.184.216.48/28","hostnames":["mail.owasp.org"],"ports":[],"sites":1},
+					{"ip":"93.184.216.64/28","ports":[],"sites":0}
+				]}`))
+			}),
+			inputs: []interface{}{
+				&requests.AddrRequest{Address: "93.184.216.34", InScope: true},
+				&requests.AddrRequest{Address: "93.184.216.35", InScope: true},
+				&requests.AddrRequest{Address: "104.22.26.77", InScope: true},
+				&requests.AddrRequest{Address: "104.22.26.78", InScope: true},
+			},
+		}.run(t)
+
+		if got := strings.Join(res.names(), ","); got != test.names {
+			t.Errorf("%s: expected the names %s and got %s", test.label, test.names, got)
+		}
+
+		lock.Lock()
+		if got := strings.Join(nearby, ","); got != test.nearby {
+			t.Errorf("%s: the neighborhoods were not queried as expected: %v", test.label, nearby)
+		}
+		lock.Unlock()
+	}
+}
+
+func TestSecurityTrailsDNSHistory(t *testing.T) {
+	// Trimmed from the responses of the history endpoints
+	history := map[string]string{
+		"/v1/history/owasp.org/dns/a?page=1": `{"type":"a/ipv4","pages":2,"records":[
+			{"values":[{"ip":"104.22.26.77","ip_count":3},{"ip":"104.22.27.77","ip_count":3}],
+			 "type":"a","organizations":["Cloudflare, Inc."],"first_seen":"2019-11-04","last_seen":"2024-01-10"},
+			{"values":[{"ip":"104.22.26.77","ip_count":3}],"type":"a","first_seen":"2017-02-01","last_seen":"2019-11-03"}
+		]}`,
+		"/v1/history/owasp.org/dns/a?page=2": `{"type":"a/ipv4","pages":2,"records":[
+			{"values":[{"ip":"192.30.252.154","ip_count":1}],"type":"a","first_seen":"2014-06-11","last_seen":"2017-01-31"}
+		]}`,
+		"/v1/history/owasp.org/dns/aaaa?page=1": `{"type":"aaaa/ipv6","pages":1,"records":[
+			{"values":[{"ipv6":"2606:4700:10::6816:1a4d","ipv6_count":2}],"type":"aaaa","first_seen":"2019-11-04","last_seen":"2024-01-10"}
+		]}`,
+	}
+
+	var lock sync.Mutex
+	var queried []string
+	res := scriptRun{
+		path: "scripts/api/securitytrails.ads",
+		setup: withSetup(withKeys("SecurityTrails", []string{"good"}),
+			withOptions(t, "SecurityTrails", "      dns_history: true\n")),
+		overrides: securityTrailsOverrides(`
+function history_url(domain, rrtype, pagenum)
+    return "%[1]s/v1/history/" .. domain .. "/dns/" .. rrtype .. "?page=" .. pagenum
+end
+`),
+		handler: securityTrailsHandler(10, func(w http.ResponseWriter, r *http.Request) {
+			if strings.HasSuffix(r.URL.Path, "/subdomains") {
+				_, _ = w.Write([]byte(`{"subdomains":["www"],"subdomain_count":1}`))
+				return
+			}
+
+			lock.Lock()
+			queried = append(queried, r.URL.RequestURI())
+			lock.Unlock()
+			_, _ = w.Write([]byte(history[r.URL.RequestURI()]))
+		}),
+	}.run(t)
+
+	// The addresses are sent as the records of the name, so the enumeration stores them with the name
+	var names, records []string
+	for _, req := range res.out {
+		if d, ok := req.(*requests.DNSRequest); ok {
+			if len(d.Records) == 0 {
+				names = append(names, d.Name)
+			}
+			for _, rr := range d.Records {
+				records = append(records, fmt.Sprintf("%s:%d:%s", rr.Name, rr.Type, rr.Data))
+			}
+		}
+	}
+
+	expected := "owasp.org:1:104.22.26.77,owasp.org:1:104.22.27.77,owasp.org:1:192.30.252.154,owasp.org:28:2606:4700:10::6816:1a4d"
+	if got := strings.Join(records, ","); got != expected {
+		t.Errorf("expected the historical records %s and got %s", expected, got)
+	}
+	if strings.Join(names, ",") != "www.owasp.org" {
+		t.Errorf("the subdomains were not sent along with the history: %v", names)
+	}
+
+	lock.Lock()
+	defer lock.Unlock()
+	expected = "/v1/history/owasp.org/dns/a?page=1,/v1/history/owasp.org/dns/a?page=2,/v1/history/owasp.org/dns/aaaa?page=1"
+	if got := strings.Join(queried, ","); got != expected {
+		t.Errorf("the history was not queried as expected: %v", queried)
+	}
+}
+
+func TestSecurityTrailsSubdomainsParams(t *testing.T) {
+	tests := []struct {
+		options  string
+		expected string
+	}{
+		{"", "children_only=false&include_inactive=true"},
+		{"      children_only: true\n", "children_only=true&include_inactive=true"},
+		{"      include_inactive: false\n", "children_only=false&include_inactive=false"},
+		{"      children_only: true\n      include_inactive: false\n", "children_only=true&include_inactive=false"},
+	}
+
+	for _, test := range tests {
+		var lock sync.Mutex
+		var queries []string
+		setup := withSetup(withKeys("SecurityTrails", []string{"good"}), withOptions(t, "SecurityTrails", test.options))
+		names, _ := runConfiguredScript(t, "scripts/api/securitytrails.ads", setup, securityTrailsOverrides(""), func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Path != "/v1/domain/owasp.org/subdomains" {
+				return
+			}
+
+			lock.Lock()
+			queries = append(queries, r.URL.RawQuery)
+			lock.Unlock()
+			_, _ = w.Write([]byte(`{"subdomains":["www"],"subdomain_count":1}`))
+		})
+
+		if strings.Join(names, ",") != "www.owasp.org" {
+			t.Errorf("options %q: the script returned the wrong names: %v", test.options, names)
+		}
+
+		lock.Lock()
+		if strings.Join(queries, ",") != test.expected {
+			t.Errorf("options %q: expected the query %s and got %v", test.options, test.expected, queries)
+		}
+		lock.Unlock()
+	}
+}
+
+func TestSecurityTrailsScroll(t *testing.T) {
+	overrides := securityTrailsOverrides(`
+function list_url()
+    return "%[1]s/v1/domains/list"
+end
+
+function scroll_url(id)
+    return "%[1]s/v1/scroll/" .. id
+end
+`)
+
+	tests := []struct {
+		label    string
+		options  string
+		listCode int
+		names    string
+		scrolls  int
+		logged   string
+	}{
+		{"All_Pages", "", http.StatusOK, "www.owasp.org,a.owasp.org,b.owasp.org,c.owasp.org,d.owasp.org", 2, ""},
+		{"Max_Records", "      max_records: 3\n", http.StatusOK, "www.owasp.org,a.owasp.org,b.owasp.org,c.owasp.org,d.owasp.org", 1,
+			"the scroll results for owasp.org were truncated at 4 records"},
+		{"Not_In_Plan", "", http.StatusForbidden, "www.owasp.org", 0, "the scroll API is not included in the plan"},
+	}
+
+	for _, test := range tests {
+		var lock sync.Mutex
+		var scrolls int
+		setup := withSetup(withKeys("SecurityTrails", []string{"good"}), withOptions(t, "SecurityTrails", test.options))
+		names, logs := runConfiguredScript(t, "scripts/api/securitytrails.ads", setup, overrides, func(w http.ResponseWriter, r *http.Request) {
+			switch r.URL.Path {
+			case "/v1/domain/owasp.org/subdomains":
+				_, _ = w.Write([]byte(`{"subdomains":["www"],"subdomain_count":5}`))
+			case "/v1/domains/list":
+				if r.Method != http.MethodPost || r.Header.Get("APIKEY") != "good" {
+					w.WriteHeader(http.StatusBadRequest)
+					return
+				}
+				w.WriteHeader(test.listCode)
+				_, _ = w.Write([]byte(`{"id":"first","records":[{"hostname":"a.owasp.org"},{"hostname":"b.owasp.org"}]}`))
+			case "/v1/scroll/first":
+				lock.Lock()
+				scrolls++
+				lock.Unlock()
+				_, _ = w.Write([]byte(`{"id":"second","records":[{"hostname":"c.owasp.org"},{"hostname":"d.owasp.org"}]}`))
+			case "/v1/scroll/second":
+				lock.Lock()
+				scrolls++
+				lock.Unlock()
+				_, _ = w.Write([]byte(`{"id":"third","records":[]}`))
+			}
+		})
+
+		if got := strings.Join(names, ","); got != test.names {
+			t.Errorf("%s: expected the names %s and got %s", test.label, test.names, got)
+		}
+		lock.Lock()
+		if scrolls != test.scrolls {
+			t.Errorf("%s: expected %d scroll requests and got %d", test.label, test.scrolls, scrolls)
+		}
+		lock.Unlock()
+		if test.logged != "" && !strings.Contains(logs, test.logged) {
+			t.Errorf("%s: the log did not contain %q: %s", test.label, test.logged, logs)
+		}
+	}
+}
+
+func TestSecurityTrailsAssociatedDomains(t *testing.T) {
+	pages := map[string]string{
+		"1": `{"records":[{"hostname":"owasp.net"},{"hostname":"WWW.AppSecUSA.org"},{"hostname":"owasp.org"}],"meta":{"total_pages":2}}`,
+		"2": `{"records":[{"hostname":"appsecusa.org"},{"hostname":"blog.owasp.co.uk"},{"hostname":""},{"hostname":"localhost"}],"meta":{"total_pages":2}}`,
+	}
+
+	tests := []struct {
+		label   string
+		options string
+		verbose bool
+		names   string
+		pages   string
+		logged  []string
+	}{
+		{"Enabled", "", false, "owasp.org:appsecusa.org,owasp.org:owasp.co.uk,owasp.org:owasp.net", "1,2", nil},
+		{"Verbose", "", true, "owasp.org:appsecusa.org,owasp.org:owasp.co.uk,owasp.org:owasp.net", "1,2", []string{"owasp.co.uk", "owasp.net"}},
+		{"Disabled", "      associated: false\n", true, "", "", nil},
+	}
+
+	for _, test := range tests {
+		var lock sync.Mutex
+		var requested []string
+		verbose := test.verbose
+
+		res := scriptRun{
+			path: "scripts/api/securitytrails.ads",
+			setup: withSetup(withKeys("SecurityTrails", []string{"testing"}), withOptions(t, "SecurityTrails", test.options),
+				func(cfg *config.Config) {
+					cfg.Verbose = verbose
+					cfg.AddDomain("appsecusa.org")
+				}),
+			overrides: securityTrailsOverrides(`
+function horizon_url(domain, pagenum)
+    return "%[1]s/v1/domain/" .. domain .. "/associated?page=" .. pagenum
+end
+`),
+			handler: securityTrailsHandler(0, func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path != "/v1/domain/owasp.org/associated" {
+					_, _ = w.Write([]byte(`{"records":[]}`))
+					return
+				}
+
+				page := r.URL.Query().Get("page")
+				lock.Lock()
+				requested = append(requested, page)
+				lock.Unlock()
+				_, _ = w.Write([]byte(pages[page]))
+			}),
+			inputs: []interface{}{
+				&requests.WhoisRequest{Domain: "owasp.org"},
+				&requests.WhoisRequest{Domain: "example.com"},
+			},
+		}.run(t)
+
+		if got := strings.Join(res.associated(), ","); got != test.names {
+			t.Errorf("%s: expected the associated domains %s and got %s", test.label, test.names, got)
+		}
+		lock.Lock()
+		if got := strings.Join(requested, ","); got != test.pages {
+			t.Errorf("%s: expected the pages %s to be requested and got %s", test.label, test.pages, got)
+		}
+		lock.Unlock()
+
+		if got := strings.Count(res.logs, "is not in scope"); got != len(test.logged) {
+			t.Errorf("%s: expected %d associated domains to be logged and got %d: %s", test.label, len(test.logged), got, res.logs)
+		}
+		for _, name := range test.logged {
+			if !strings.Contains(res.logs, "the associated domain "+name+" is not in scope") {
+				t.Errorf("%s: the associated domain %s was not logged: %s", test.label, name, res.logs)
+			}
+		}
+	}
+}
+
+func TestSecurityTrailsRestrictedEndpoint(t *testing.T) {
+	var lock sync.Mutex
+	var associated int
+	res := scriptRun{
+		path:  "scripts/api/securitytrails.ads",
+		setup: withKeys("SecurityTrails", []string{"testing"}),
+		overrides: securityTrailsOverrides(`
+function horizon_url(domain, pagenum)
+    return "%[1]s/v1/domain/" .. domain .. "/associated?page=" .. pagenum
+end
+`),
+		handler: securityTrailsHandler(0, func(w http.ResponseWriter, r *http.Request) {
+			// The associated domains are not included in the plan of the key
+			if strings.HasSuffix(r.URL.Path, "/associated") {
+				lock.Lock()
+				associated++
+				lock.Unlock()
+				w.WriteHeader(http.StatusForbidden)
+				return
+			}
+			_, _ = w.Write([]byte(`{"subdomains":["www"],"subdomain_count":1}`))
+		}),
+		inputs: []interface{}{
+			&requests.WhoisRequest{Domain: "owasp.org"},
+			&requests.WhoisRequest{Domain: "appsecusa.org"},
+			&requests.DNSRequest{Name: "owasp.org", Domain: "owasp.org"},
+		},
+	}.run(t)
+
+	if got := strings.Join(res.names(), ","); got != "www.owasp.org" {
+		t.Errorf("the subdomains were not provided after the associated lookup was rejected: %s", got)
+	}
+	if strings.Contains(res.logs, "rejected the") {
+		t.Errorf("the key was reported as rejected: %s", res.logs)
+	}
+	if n := strings.Count(res.logs, "not included in the plan"); n != 1 {
+		t.Errorf("the restricted endpoint was logged %d times: %s", n, res.logs)
+	}
+
+	lock.Lock()
+	defer lock.Unlock()
+	if associated != 1 {
+		t.Errorf("expected the associated endpoint to be requested once and got %d", associated)
+	}
+}
@@ -0,0 +1,123 @@
+// Copyright © by Jeff Foley 2017-2023. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+// SPDX-License-Identifier: Apache-2.0
+
+package datasrcs
+
+import (
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+var siteDossierOverrides = scriptOverrides(`
+function build_url(domain, itemnum)
+    return "%[1]s/parentdomain/" .. domain .. "/" .. itemnum
+end
+`)
+
+func TestSiteDossierTerminalPages(t *testing.T) {
+	pages := map[string]string{
+		"end of results": `<html><body><ol start="1">
+<li><a href="/site/www.owasp.org">http://www.owasp.org/</a>
+<li><a href="/site/wiki.owasp.org">http://wiki.owasp.org/</a>
+</ol><p><i>End of list.</i></p></body></html>`,
+		"abusive requests": `<html><body><p>Your request has been deemed abusive.
+Please complete the CAPTCHA below to continue.</p></body></html>`,
+	}
+
+	for desc, page := range pages {
+		var lock sync.Mutex
+		var count int
+
+		names, logs := runScript(t, "scripts/scrape/sitedossier.ads", siteDossierOverrides, func(w http.ResponseWriter, r *http.Request) {
+			if !strings.Contains(r.URL.Path, "owasp.org") {
+				return
+			}
+
+			lock.Lock()
+			count++
+			lock.Unlock()
+			if strings.HasSuffix(r.URL.Path, "/1") {
+				_, _ = w.Write([]byte(page))
+				return
+			}
+			// The following pages are requested concurrently and must be cancelled
+			select {
+			case <-r.Context().Done():
+			case <-time.After(2 * time.Second):
+				_, _ = w.Write([]byte(`<li><a href="/site/late.owasp.org">http://late.owasp.org/</a>`))
+			}
+		})
+
+		lock.Lock()
+		if count < 1 || count > 3 {
+			t.Errorf("%s: the script made %d requests", desc, count)
+		}
+		lock.Unlock()
+
+		for _, n := range names {
+			if n == "late.owasp.org" {
+				t.Errorf("%s: the script used a page following the terminal page", desc)
+			}
+		}
+		if desc == "abusive requests" && !strings.Contains(logs, "blocked") {
+			t.Errorf("%s: the block was not logged: %s", desc, logs)
+		}
+	}
+}
+
+func TestSiteDossierConcurrentPages(t *testing.T) {
+	var lock sync.Mutex
+	var inflight, max int
+
+	start := time.Now()
+	names, _ := runScript(t, "scripts/scrape/sitedossier.ads", siteDossierOverrides, func(w http.ResponseWriter, r *http.Request) {
+		if !strings.Contains(r.URL.Path, "owasp.org") {
+			return
+		}
+
+		lock.Lock()
+		inflight++
+		if inflight > max {
+			max = inflight
+		}
+		lock.Unlock()
+		defer func() {
+			lock.Lock()
+			inflight--
+			lock.Unlock()
+		}()
+
+		item := r.URL.Path[strings.LastIndex(r.URL.Path, "/")+1:]
+		body := `<li><a href="/site/host` + item + `.owasp.org">http://host` + item + `.owasp.org/</a>`
+		if item == "501" {
+			body += "<p><i>End of list.</i></p>"
+		}
+		time.Sleep(250 * time.Millisecond)
+		_, _ = w.Write([]byte(body))
+	})
+	elapsed := time.Since(start)
+
+	got := strings.Join(names, ",")
+	for _, item := range []string{"1", "101", "201", "301", "401", "501"} {
+		if !strings.Contains(got, "host"+item+".owasp.org") {
+			t.Errorf("the name from result item %s was not discovered: %v", item, names)
+		}
+	}
+	if strings.Contains(got, "host601.owasp.org") {
+		t.Errorf("the script used a page following the end of the list")
+	}
+	// Requested one at a time, the six pages would take at least 1.5 seconds
+	if elapsed >= 1500*time.Millisecond {
+		t.Errorf("the pages were not requested concurrently: %v elapsed", elapsed)
+	}
+
+	lock.Lock()
+	defer lock.Unlock()
+	if max > 3 {
+		t.Errorf("%d requests were in flight at the same time", max)
+	}
+}
@@ -6,6 +6,7 @@ package datasrcs
 
 import (
 	"sort"
+	"strings"
 
 	"github.com/caffix/service"
 	"github.com/caffix/stringset"
@@ -19,11 +20,7 @@ func GetAllSources(sys systems.System) []service.Service {
 	var srvs []service.Service
 
 	if scripts, err := sys.Config().AcquireScripts(); err == nil {
-		for _, script := range scripts {
-			if s := scripting.NewScript(script, sys); s != nil {
-				srvs = append(srvs, s)
-			}
-		}
+		srvs = scriptsToSources(scripts, sys)
 	}
 
 	sort.Slice(srvs, func(i, j int) bool {
@@ -32,27 +29,52 @@ func GetAllSources(sys systems.System) []service.Service {
 	return srvs
 }
 
+// Data source names are compared in lowercase and must be unique, so
+// scripts registering a name already taken by another script are rejected.
+func scriptsToSources(scripts []string, sys systems.System) []service.Service {
+	var srvs []service.Service
+
+	names := stringset.New()
+	defer names.Close()
+
+	for _, script := range scripts {
+		s := scripting.NewScript(script, sys)
+		if s == nil {
+			continue
+		}
+
+		if name := strings.ToLower(s.String()); names.Has(name) {
+			// The rejected script was never started, so only its Lua state needs to be released
+			sys.Config().Log.Printf("Data source %s was rejected, since the name is already registered", s.String())
+			s.Close()
+			continue
+		}
+		names.Insert(strings.ToLower(s.String()))
+		srvs = append(srvs, s)
+	}
+	return srvs
+}
+
 // SelectedDataSources uses the config and available data sources to return the selected data sources.
+// The names provided in the source filter are matched without case against both data source names and types,
+// so entire categories of data sources, such as "scrape", can be included or excluded.
 func SelectedDataSources(cfg *config.Config, avail []service.Service) []service.Service {
 	specified := stringset.New()
 	defer specified.Close()
-	specified.InsertMany(cfg.SourceFilter.Sources...)
-
-	available := stringset.New()
-	defer available.Close()
-	for _, src := range avail {
-		available.Insert(src.String())
-	}
-
-	if specified.Len() > 0 && cfg.SourceFilter.Include {
-		available.Intersect(specified)
-	} else {
-		available.Subtract(specified)
+	for _, src := range cfg.SourceFilter.Sources {
+		specified.Insert(strings.ToLower(strings.TrimSpace(src)))
 	}
 
 	var results []service.Service
 	for _, src := range avail {
-		if available.Has(src.String()) {
+		matched := specified.Has(strings.ToLower(src.String())) ||
+			(src.Description() != "" && specified.Has(strings.ToLower(src.Description())))
+
+		if specified.Len() > 0 && cfg.SourceFilter.Include {
+			if matched {
+				results = append(results, src)
+			}
+		} else if !matched {
 			results = append(results, src)
 		}
 	}
@@ -0,0 +1,496 @@
+// Copyright © by Jeff Foley 2017-2023. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+// SPDX-License-Identifier: Apache-2.0
+
+package datasrcs
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/owasp-amass/amass/v4/datasrcs/scripting"
+	"github.com/owasp-amass/amass/v4/requests"
+	"github.com/owasp-amass/amass/v4/resources"
+	"github.com/owasp-amass/amass/v4/systems"
+	"github.com/owasp-amass/config/config"
+)
+
+func TestDuplicateSourceNames(t *testing.T) {
+	var buf safeBuffer
+	cfg := config.NewConfig()
+	cfg.Log = log.New(&buf, "", 0)
+	sys := &systems.SimpleSystem{Cfg: cfg}
+
+	srcs := scriptsToSources([]string{
+		`name="Crtsh"
+		type="cert"`,
+		`name="HackerTarget"
+		type="api"`,
+		`name="crtsh"
+		type="api"
+
+		function stop()
+			error("the rejected script was stopped")
+		end`,
+	}, sys)
+	if strings.Contains(buf.String(), "stop callback") {
+		t.Errorf("the stop callback of the rejected script was executed: %s", buf.String())
+	}
+
+	if l := len(srcs); l != 2 {
+		t.Fatalf("expected 2 data sources and got %d", l)
+	}
+	if srcs[0].String() != "Crtsh" || srcs[1].String() != "HackerTarget" {
+		t.Errorf("the wrong data sources were registered: %s, %s", srcs[0].String(), srcs[1].String())
+	}
+}
+
+func TestSelectedDataSources(t *testing.T) {
+	sys := &systems.SimpleSystem{Cfg: config.NewConfig()}
+
+	srcs := scriptsToSources([]string{
+		`name="Crtsh"
+		type="cert"`,
+		`name="HackerTarget"
+		type="api"`,
+		`name="DNSDumpster"
+		type="scrape"`,
+		`name="SiteDossier"
+		type="scrape"`,
+	}, sys)
+
+	tests := []struct {
+		include  bool
+		sources  []string
+		expected []string
+	}{
+		{false, nil, []string{"Crtsh", "DNSDumpster", "HackerTarget", "SiteDossier"}},
+		{false, []string{"scrape", "crtsh"}, []string{"HackerTarget"}},
+		{true, []string{"API", "sitedossier"}, []string{"HackerTarget", "SiteDossier"}},
+		{true, nil, []string{"Crtsh", "DNSDumpster", "HackerTarget", "SiteDossier"}},
+	}
+	for _, test := range tests {
+		cfg := config.NewConfig()
+		cfg.SourceFilter.Include = test.include
+		cfg.SourceFilter.Sources = test.sources
+
+		var got []string
+		for _, src := range SelectedDataSources(cfg, srcs) {
+			got = append(got, src.String())
+		}
+		if strings.Join(got, ",") != strings.Join(test.expected, ",") {
+			t.Errorf("include: %t, sources: %v: expected %v and got %v", test.include, test.sources, test.expected, got)
+		}
+	}
+}
+
+// scriptRun describes a run of an embedded data source script against a test server.
+type scriptRun struct {
+	path      string
+	setup     func(cfg *config.Config)
+	overrides func(url string) string
+	handler   http.HandlerFunc
+	// The requests sent to the script, which default to the DNS request for owasp.org
+	inputs []interface{}
+}
+
+// scriptResult holds the requests sent by the script and its log output.
+type scriptResult struct {
+	sys  *systems.SimpleSystem
+	out  []interface{}
+	logs string
+}
+
+// run loads the script with the overrides appended, sends the requests and collects the results.
+func (r scriptRun) run(t *testing.T) *scriptResult {
+	data := loadScript(t, r.path)
+	srv := httptest.NewServer(r.handler)
+	defer srv.Close()
+
+	var buf safeBuffer
+	cfg := config.NewConfig()
+	cfg.Log = log.New(&buf, "", 0)
+	cfg.AddDomain("owasp.org")
+	if r.setup != nil {
+		r.setup(cfg)
+	}
+	sys := &systems.SimpleSystem{Cfg: cfg, ASNCache: requests.NewASNCache()}
+
+	var overrides string
+	if r.overrides != nil {
+		overrides = r.overrides(srv.URL)
+	}
+	s := scripting.NewScript(data+overrides, sys)
+	if s == nil {
+		t.Fatalf("failed to load the %s script", r.path)
+	}
+	if err := s.Start(); err != nil {
+		t.Fatalf("failed to start the %s script: %v", r.path, err)
+	}
+	defer func() { _ = s.Stop() }()
+
+	inputs := r.inputs
+	if len(inputs) == 0 {
+		inputs = []interface{}{
+			&requests.DNSRequest{Name: "owasp.org", Domain: "owasp.org"},
+		}
+	}
+	res := &scriptResult{sys: sys}
+	// The output is drained while the requests are processed, since the script
+	// waits for the enumeration to catch up when the output is full
+	done := make(chan struct{})
+	drained := make(chan struct{})
+	go func() {
+		defer close(drained)
+
+		for {
+			select {
+			case <-done:
+				return
+			case req := <-s.Output():
+				res.out = append(res.out, req)
+			}
+		}
+	}()
+
+	for _, req := range inputs {
+		s.Input() <- req
+	}
+	// The script handles the requests serially and ignores the sentinel, so the sentinel
+	// is only accepted after the findings of the previous requests have all been sent
+	s.Input() <- struct{}{}
+	close(done)
+	<-drained
+
+	for {
+		select {
+		case req := <-s.Output():
+			res.out = append(res.out, req)
+		default:
+			res.logs = buf.String()
+			return res
+		}
+	}
+}
+
+// names returns the names of the DNS requests sent by the script, in the order they were sent.
+func (r *scriptResult) names() []string {
+	var names []string
+
+	for _, req := range r.out {
+		if d, ok := req.(*requests.DNSRequest); ok {
+			names = append(names, d.Name)
+		}
+	}
+	return names
+}
+
+// associated returns the domains associated by the script as sorted domain:associated pairs.
+func (r *scriptResult) associated() []string {
+	var names []string
+
+	for _, req := range r.out {
+		if w, ok := req.(*requests.WhoisRequest); ok {
+			for _, name := range w.NewDomains {
+				names = append(names, w.Domain+":"+name)
+			}
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Runs the embedded script with the overrides appended against the handler and returns the discovered names and log output.
+func runScript(t *testing.T, path string, overrides func(url string) string, handler http.HandlerFunc) ([]string, string) {
+	return runConfiguredScript(t, path, nil, overrides, handler)
+}
+
+// Same as runScript, but the setup function can modify the configuration before the script is loaded.
+func runConfiguredScript(t *testing.T, path string, setup func(cfg *config.Config),
+	overrides func(url string) string, handler http.HandlerFunc) ([]string, string) {
+	res := scriptRun{path: path, setup: setup, overrides: overrides, handler: handler}.run(t)
+	return res.names(), res.logs
+}
+
+// responseTest is a response of the service along with the names and the message it must produce.
+type responseTest struct {
+	label   string
+	handler http.HandlerFunc
+	names   string
+	logged  string
+}
+
+// Runs the script against the handler of each test, and checks the names in the order they were sent and the logged message.
+func runResponseTests(t *testing.T, path string, setup func(cfg *config.Config), overrides func(url string) string, tests []responseTest) {
+	for _, test := range tests {
+		names, logs := runConfiguredScript(t, path, setup, overrides, test.handler)
+
+		if got := strings.Join(names, ","); got != test.names {
+			t.Errorf("%s: expected the names %q and got %q", test.label, test.names, got)
+		}
+		if !strings.Contains(logs, test.logged) {
+			t.Errorf("%s: %q was not logged: %s", test.label, test.logged, logs)
+		}
+	}
+}
+
+// Returns the overrides that remove the rate limit set by the start callback and replace the
+// functions of the script, where the %[1]s verbs are replaced with the URL of the test server.
+func scriptOverrides(funcs string) func(url string) string {
+	return func(url string) string {
+		return "\nfunction start() end\n" + fmt.Sprintf(funcs, url)
+	}
+}
+
+// Returns the contents of the embedded script.
+func loadScript(t *testing.T, path string) string {
+	f, err := resources.GetResourceFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := io.ReadAll(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+// Returns a setup function that applies each of the setup functions.
+func withSetup(setups ...func(cfg *config.Config)) func(cfg *config.Config) {
+	return func(cfg *config.Config) {
+		for _, setup := range setups {
+			setup(cfg)
+		}
+	}
+}
+
+// Returns a setup function that configures the data source with an account for each of the keys.
+func withKeys(name string, keys []string) func(cfg *config.Config) {
+	return func(cfg *config.Config) {
+		src := &config.DataSource{Name: name}
+
+		for i, key := range keys {
+			_ = src.AddCredentials(fmt.Sprintf("account%d", i), &config.Credentials{Name: name, Apikey: key})
+		}
+		cfg.DataSrcConfigs = &config.DataSourceConfig{Datasources: []*config.DataSource{src}}
+	}
+}
+
+// Returns a setup function that provides the options of the data source in a data source configuration file.
+// The options are YAML lines indented for the options of the data source.
+func withOptions(t *testing.T, name, options string) func(cfg *config.Config) {
+	return func(cfg *config.Config) {
+		path := filepath.Join(t.TempDir(), "datasources.yaml")
+		data := "datasources:\n  - name: " + name + "\n"
+		if options != "" {
+			data += "    options:\n" + options
+		}
+		if err := os.WriteFile(path, []byte(data), 0600); err != nil {
+			t.Fatalf("failed to write the data source configuration: %v", err)
+		}
+		cfg.Options = map[string]interface{}{"datasources": path}
+	}
+}
+
+func TestNormalizedNamesAcrossSources(t *testing.T) {
+	subs := `["WWW","*.Api"," Mail"]`
+
+	chaos, _ := runChaosScript(t, []string{"testing"}, "", chaosHandler(func(w http.ResponseWriter, key string) {
+		_, _ = fmt.Fprintf(w, `{"domain":"OWASP.ORG.","subdomains":%s}`, subs)
+	}))
+
+	setup := withKeys("SecurityTrails", []string{"testing"})
+	strails, _ := runConfiguredScript(t, "scripts/api/securitytrails.ads", setup, securityTrailsOverrides(""), func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/v1/account/usage" {
+			_, _ = w.Write([]byte(`{"current_monthly_usage":0,"allowed_monthly_usage":50}`))
+			return
+		}
+		_, _ = fmt.Fprintf(w, `{"subdomains":%s,"subdomain_count":3}`, subs)
+	})
+
+	sort.Strings(chaos)
+	sort.Strings(strails)
+	if strings.Join(chaos, ",") != "api.owasp.org,mail.owasp.org,www.owasp.org" || strings.Join(chaos, ",") != strings.Join(strails, ",") {
+		t.Errorf("the sources filtered the names differently: %v and %v", chaos, strails)
+	}
+}
+
+func TestEndpointOverrides(t *testing.T) {
+	tests := []struct {
+		name   string
+		path   string
+		url    string
+		prefix string
+		body   string
+	}{
+		{"SiteDossier", "scripts/scrape/sitedossier.ads", "%s/sd/%%s/%%d", "/sd/",
+			`<a href="/site/www.owasp.org">http://www.owasp.org/</a><i>End of list.</i>`},
+		{"DuckDuckGo", "scripts/scrape/duckduckgo.ads", "%s/ddg/", "/ddg/",
+			`<a rel="nofollow" class="result__a" href="https://www.owasp.org/">OWASP</a>`},
+		{"Crtsh", "scripts/cert/crtsh.ads", "%s/crtsh/?q=%%s&output=json", "/crtsh/",
+			`[{"id":1,"common_name":"www.owasp.org","name_value":"www.owasp.org"}]`},
+		{"SubdomainCenter", "scripts/api/subdomaincenter.ads", "%s/sc/?domain=%%s", "/sc/", `["www.owasp.org"]`},
+	}
+
+	for _, test := range tests {
+		var lock sync.Mutex
+		var paths []string
+
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			lock.Lock()
+			paths = append(paths, r.URL.Path)
+			lock.Unlock()
+			_, _ = w.Write([]byte(test.body))
+		}))
+
+		setup := withOptions(t, test.name, "      url: "+fmt.Sprintf(test.url, srv.URL)+"\n")
+		// The start callbacks remain in place, so only the rate limits are removed
+		names, _ := runConfiguredScript(t, test.path, setup, func(url string) string {
+			return "\nfunction set_rate_limit(seconds) end\n"
+		}, func(w http.ResponseWriter, r *http.Request) {
+			t.Errorf("%s: a request was sent to the default test server", test.name)
+		})
+		srv.Close()
+
+		if len(names) != 1 || names[0] != "www.owasp.org" {
+			t.Errorf("%s: the script returned the wrong names: %v", test.name, names)
+		}
+
+		lock.Lock()
+		if len(paths) == 0 {
+			t.Errorf("%s: no requests were sent to the configured endpoint", test.name)
+		}
+		for _, p := range paths {
+			if !strings.HasPrefix(p, test.prefix) {
+				t.Errorf("%s: the request for %s did not use the configured endpoint", test.name, p)
+			}
+		}
+		lock.Unlock()
+	}
+}
+
+func TestMalformedEndpointOverrides(t *testing.T) {
+	tests := []struct {
+		name string
+		path string
+		url  string
+	}{
+		{"SiteDossier", "scripts/scrape/sitedossier.ads", "https://sd.example.com/%s"},
+		{"DuckDuckGo", "scripts/scrape/duckduckgo.ads", "https://ddg.example.com/?q=%s"},
+		{"Crtsh", "scripts/cert/crtsh.ads", "crt.example.com/?q=%s&output=json"},
+		{"SubdomainCenter", "scripts/api/subdomaincenter.ads", "https://sc.example.com/"},
+	}
+
+	for _, test := range tests {
+		var buf safeBuffer
+		cfg := config.NewConfig()
+		cfg.Log = log.New(&buf, "", 0)
+		withOptions(t, test.name, "      url: "+test.url+"\n")(cfg)
+
+		s := scripting.NewScript(loadScript(t, test.path), &systems.SimpleSystem{Cfg: cfg})
+		if s == nil {
+			t.Fatalf("%s: failed to load the script", test.name)
+		}
+		if err := s.Start(); err == nil {
+			t.Errorf("%s: the script started with the url option %s", test.name, test.url)
+			_ = s.Stop()
+		} else if !strings.Contains(buf.String(), "url option") {
+			t.Errorf("%s: the invalid url option was not logged: %s", test.name, buf.String())
+		}
+	}
+}
+
+func TestSubdomainAPIScripts(t *testing.T) {
+	tests := []struct {
+		path   string
+		setup  func(cfg *config.Config)
+		build  string
+		header string
+		body   string
+	}{
+		{"scripts/api/sublist3r.ads", nil, `"%[1]s/search.php?domain=" .. domain`, "",
+			`["www.owasp.org","WWW.owasp.org","api.owasp.org","www.example.com"]`},
+		{"scripts/api/threatminer.ads", nil, `"%[1]s/v2/domain.php?q=" .. domain`, "",
+			`{"status_code":"200","status_message":"Results found.","results":["www.owasp.org","api.owasp.org","www.example.com"]}`},
+		{"scripts/api/fullhunt.ads", withKeys("FullHunt", []string{"testing"}), `"%[1]s/api/v1/domain/" .. domain .. "/subdomains"`, "testing",
+			`{"domain":"owasp.org","hosts":["www.owasp.org","api.owasp.org","www.example.com"],"message":"","status":200}`},
+	}
+
+	for _, test := range tests {
+		var lock sync.Mutex
+		var keys []string
+
+		names, _ := runConfiguredScript(t, test.path, test.setup, subdomainAPIOverrides(test.build), func(w http.ResponseWriter, r *http.Request) {
+			lock.Lock()
+			keys = append(keys, r.Header.Get("X-API-KEY"))
+			lock.Unlock()
+			_, _ = w.Write([]byte(test.body))
+		})
+		if strings.Join(names, ",") != "www.owasp.org,api.owasp.org" {
+			t.Errorf("%s: the script returned the wrong names: %v", test.path, names)
+		}
+
+		lock.Lock()
+		if len(keys) != 1 || keys[0] != test.header {
+			t.Errorf("%s: the requests did not provide the expected API key: %v", test.path, keys)
+		}
+		lock.Unlock()
+	}
+
+	// The service reports failures in the response for ThreatMiner
+	names, logs := runScript(t, "scripts/api/threatminer.ads", subdomainAPIOverrides(`"%[1]s/v2/domain.php?q=" .. domain`),
+		func(w http.ResponseWriter, r *http.Request) {
+			_, _ = w.Write([]byte(`{"status_code":"404","status_message":"No results found.","results":["www.owasp.org"]}`))
+		})
+	if len(names) > 0 {
+		t.Errorf("the failed response provided names: %v", names)
+	}
+	if strings.Contains(logs, "vertical request") || strings.Contains(logs, "decode") {
+		t.Errorf("the response reporting no results was logged as a failure: %s", logs)
+	}
+
+	// The shared callbacks log the failed requests
+	runResponseTests(t, "scripts/api/sublist3r.ads", nil, subdomainAPIOverrides(`"%[1]s/search.php?domain=" .. domain`), []responseTest{
+		{"Unavailable", func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}, "", "returned with status: 503"},
+	})
+}
+
+// Returns the overrides replacing the build_url function of the script with the expression.
+func subdomainAPIOverrides(build string) func(url string) string {
+	return scriptOverrides(`
+function build_url(domain)
+    return ` + build + `
+end
+`)
+}
+
+type safeBuffer struct {
+	sync.Mutex
+	buf bytes.Buffer
+}
+
+func (sb *safeBuffer) Write(p []byte) (int, error) {
+	sb.Lock()
+	defer sb.Unlock()
+
+	return sb.buf.Write(p)
+}
+
+func (sb *safeBuffer) String() string {
+	sb.Lock()
+	defer sb.Unlock()
+
+	return sb.buf.String()
+}
@@ -0,0 +1,74 @@
+// Copyright © by Jeff Foley 2017-2023. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+// SPDX-License-Identifier: Apache-2.0
+
+package datasrcs
+
+import (
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestSpyOnWebSummary(t *testing.T) {
+	overrides := scriptOverrides(`
+function api_url(kind, id, key)
+    return "%[1]s/v1/" .. kind .. "/" .. id .. "?access_token=" .. key
+end
+`)
+	// Saved from the SpyOnWeb API responses, with the items trimmed
+	responses := map[string]string{
+		"/v1/summary/owasp.org": `{"status":"found","result":{"summary":{"owasp.org":{"items":{
+			"adsense":{"pub-1234567890":2},"analytics":{"UA-12345":3},"dns_servers":{"ns1.owasp.org":10},"ip":{"104.22.26.77":20}}}}}}`,
+		"/v1/adsense/pub-1234567890": `{"status":"found","result":{"adsense":{"pub-1234567890":{"fetched":2,"found":2,
+			"items":{"www.owasp.org":"2021-05-24","owasp.com":"2020-01-02"}}}}}`,
+		"/v1/analytics/UA-12345": `{"status":"found","result":{"analytics":{"UA-12345":{"fetched":3,"found":3,
+			"items":{"wiki.owasp.org":"2019-08-12","www.owasp.org":"2021-05-24","example.com":"2018-03-04"}}}}}`,
+	}
+
+	var lock sync.Mutex
+	var paths []string
+	names, _ := runConfiguredScript(t, "scripts/scrape/spyonweb.ads", withKeys("SpyOnWeb", []string{"testing"}), overrides,
+		func(w http.ResponseWriter, r *http.Request) {
+			lock.Lock()
+			paths = append(paths, r.URL.Path)
+			lock.Unlock()
+
+			if r.URL.Query().Get("access_token") != "testing" {
+				w.WriteHeader(http.StatusUnauthorized)
+				_, _ = w.Write([]byte(`{"status":"error","message":"Invalid access token"}`))
+				return
+			}
+			if body, found := responses[r.URL.Path]; found {
+				_, _ = w.Write([]byte(body))
+				return
+			}
+			_, _ = w.Write([]byte(`{"status":"not_found","result":{}}`))
+		})
+	sort.Strings(names)
+	if strings.Join(names, ",") != "wiki.owasp.org,www.owasp.org" {
+		t.Errorf("the script returned the wrong names: %v", names)
+	}
+
+	lock.Lock()
+	if len(paths) != 3 {
+		t.Errorf("expected 3 requests and the service received %d: %v", len(paths), paths)
+	}
+	lock.Unlock()
+
+	runResponseTests(t, "scripts/scrape/spyonweb.ads", withKeys("SpyOnWeb", []string{"invalid"}), overrides, []responseTest{
+		{"Invalid_Key", func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusUnauthorized)
+			_, _ = w.Write([]byte(`{"status":"error","message":"Invalid access token"}`))
+		}, "", "Invalid access token"},
+	})
+
+	names, _ = runScript(t, "scripts/scrape/spyonweb.ads", overrides, func(w http.ResponseWriter, r *http.Request) {
+		t.Errorf("the script sent the request %s without an API key", r.URL.Path)
+	})
+	if len(names) > 0 {
+		t.Errorf("the script returned names without an API key: %v", names)
+	}
+}
@@ -0,0 +1,31 @@
+// Copyright © by Jeff Foley 2017-2023. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+// SPDX-License-Identifier: Apache-2.0
+
+package datasrcs
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestSubdomainCenterResponses(t *testing.T) {
+	overrides := scriptOverrides(`
+function build_url(domain)
+    return "%[1]s/?domain=" .. domain
+end
+`)
+
+	runResponseTests(t, "scripts/api/subdomaincenter.ads", nil, overrides, []responseTest{
+		{"Names", func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Query().Get("domain") != "owasp.org" {
+				_, _ = w.Write([]byte(`[]`))
+				return
+			}
+			_, _ = w.Write([]byte(`["www.owasp.org","api.owasp.org","www.example.com","owasp.org.example.com"]`))
+		}, "www.owasp.org,api.owasp.org", ""},
+		{"Error", func(w http.ResponseWriter, r *http.Request) {
+			_, _ = w.Write([]byte(`{"error":"too many requests"}`))
+		}, "", "too many requests"},
+	})
+}
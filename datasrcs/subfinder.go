@@ -0,0 +1,150 @@
+// Copyright © by Jeff Foley 2017-2023. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+// SPDX-License-Identifier: Apache-2.0
+
+package datasrcs
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/owasp-amass/config/config"
+	"gopkg.in/yaml.v3"
+)
+
+// The formats of the keys provided in the subfinder provider configuration.
+const (
+	subfinderKey         = iota // apikey
+	subfinderKeySecret          // apikey:secret
+	subfinderUsernameKey        // username:apikey
+	subfinderHostKey            // host:apikey
+)
+
+type subfinderProvider struct {
+	Source string
+	Format int
+}
+
+// The subfinder providers that map to Amass data sources.
+var subfinderProviders = map[string]subfinderProvider{
+	"alienvault":     {"AlienVault", subfinderKey},
+	"bevigil":        {"BeVigil", subfinderKey},
+	"binaryedge":     {"BinaryEdge", subfinderKey},
+	"bufferover":     {"BufferOver", subfinderKey},
+	"builtwith":      {"BuiltWith", subfinderKey},
+	"c99":            {"C99", subfinderKey},
+	"censys":         {"Censys", subfinderKeySecret},
+	"chaos":          {"Chaos", subfinderKey},
+	"dnsdb":          {"DNSDB", subfinderKey},
+	"facebook":       {"FacebookCT", subfinderKeySecret},
+	"fofa":           {"FOFA", subfinderUsernameKey},
+	"fullhunt":       {"FullHunt", subfinderKey},
+	"github":         {"GitHub", subfinderKey},
+	"gitlab":         {"GitLab", subfinderKey},
+	"hackertarget":   {"HackerTarget", subfinderKey},
+	"hunter":         {"Hunter", subfinderKey},
+	"intelx":         {"IntelX", subfinderHostKey},
+	"leakix":         {"LeakIX", subfinderKey},
+	"netlas":         {"Netlas", subfinderKey},
+	"passivetotal":   {"PassiveTotal", subfinderUsernameKey},
+	"quake":          {"Quake", subfinderKey},
+	"robtex":         {"Robtex", subfinderKey},
+	"securitytrails": {"SecurityTrails", subfinderKey},
+	"shodan":         {"Shodan", subfinderKey},
+	"threatbook":     {"ThreatBook", subfinderKey},
+	"virustotal":     {"VirusTotal", subfinderKey},
+	"whoisxmlapi":    {"WhoisXMLAPI", subfinderKey},
+	"zoomeyeapi":     {"ZoomEye", subfinderKey},
+}
+
+// ImportSubfinderConfig adds the keys from the subfinder provider configuration file to the data source
+// configuration. Data sources that already have credentials in the Amass configuration are not modified,
+// and the providers without an equivalent data source are ignored. The names of the data sources that
+// received credentials are returned.
+func ImportSubfinderConfig(cfg *config.Config, path string) ([]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the subfinder configuration: %v", err)
+	}
+
+	var providers map[string][]string
+	if err := yaml.Unmarshal(data, &providers); err != nil {
+		return nil, fmt.Errorf("failed to parse the subfinder configuration: %v", err)
+	}
+
+	cfg.Lock()
+	if cfg.DataSrcConfigs == nil {
+		cfg.DataSrcConfigs = &config.DataSourceConfig{}
+	}
+	cfg.Unlock()
+
+	var imported []string
+	for provider, keys := range providers {
+		p, found := subfinderProviders[strings.ToLower(provider)]
+		if !found {
+			continue
+		}
+
+		src := cfg.GetDataSourceConfig(p.Source)
+		if src != nil && len(src.Creds) > 0 {
+			continue
+		}
+
+		var creds []*config.Credentials
+		for _, key := range keys {
+			if c := subfinderCredentials(p, key); c != nil {
+				creds = append(creds, c)
+			}
+		}
+		if len(creds) == 0 {
+			continue
+		}
+
+		if src == nil {
+			src = &config.DataSource{Name: p.Source}
+			cfg.Lock()
+			cfg.DataSrcConfigs.Datasources = append(cfg.DataSrcConfigs.Datasources, src)
+			cfg.Unlock()
+		}
+		for i, c := range creds {
+			_ = src.AddCredentials(fmt.Sprintf("subfinder%d", i+1), c)
+		}
+		imported = append(imported, p.Source)
+	}
+
+	sort.Strings(imported)
+	return imported, nil
+}
+
+// subfinderCredentials converts a key in the format of the provider, and returns nil when the key is malformed.
+func subfinderCredentials(p subfinderProvider, key string) *config.Credentials {
+	key = strings.TrimSpace(key)
+	if key == "" {
+		return nil
+	}
+
+	c := &config.Credentials{Name: p.Source}
+	if p.Format == subfinderKey {
+		c.Apikey = key
+		return c
+	}
+
+	first, second, found := strings.Cut(key, ":")
+	if !found || first == "" || second == "" {
+		return nil
+	}
+
+	switch p.Format {
+	case subfinderKeySecret:
+		c.Apikey = first
+		c.Secret = second
+	case subfinderUsernameKey:
+		c.Username = first
+		c.Apikey = second
+	case subfinderHostKey:
+		c.Apikey = second
+	}
+	return c
+}
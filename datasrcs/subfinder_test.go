@@ -0,0 +1,102 @@
+// Copyright © by Jeff Foley 2017-2023. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+// SPDX-License-Identifier: Apache-2.0
+
+package datasrcs
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/owasp-amass/config/config"
+)
+
+const subfinderSample = `
+securitytrails:
+  - st-key-1
+  - st-key-2
+censys:
+  - censys-id:censys-secret
+  - malformed
+fofa:
+  - user@owasp.org:fofa-key
+intelx:
+  - 2.intelx.io:intelx-key
+shodan:
+  - subfinder-shodan
+virustotal: []
+unknownsource:
+  - ignored
+`
+
+func TestImportSubfinderConfig(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "provider-config.yaml")
+	if err := os.WriteFile(path, []byte(subfinderSample), 0600); err != nil {
+		t.Fatalf("failed to write the subfinder configuration: %v", err)
+	}
+
+	cfg := config.NewConfig()
+	// The credentials already in the Amass configuration are kept
+	withKeys("Shodan", []string{"amass-shodan"})(cfg)
+
+	imported, err := ImportSubfinderConfig(cfg, path)
+	if err != nil {
+		t.Fatalf("failed to import the subfinder configuration: %v", err)
+	}
+	if got := strings.Join(imported, ","); got != "Censys,FOFA,IntelX,SecurityTrails" {
+		t.Errorf("the wrong data sources were imported: %s", got)
+	}
+
+	tests := []struct {
+		source   string
+		accounts int
+		expected config.Credentials
+	}{
+		{"SecurityTrails", 2, config.Credentials{Name: "SecurityTrails", Apikey: "st-key-1"}},
+		{"Censys", 1, config.Credentials{Name: "Censys", Apikey: "censys-id", Secret: "censys-secret"}},
+		{"FOFA", 1, config.Credentials{Name: "FOFA", Username: "user@owasp.org", Apikey: "fofa-key"}},
+		{"IntelX", 1, config.Credentials{Name: "IntelX", Apikey: "intelx-key"}},
+		{"Shodan", 1, config.Credentials{Name: "Shodan", Apikey: "amass-shodan"}},
+	}
+
+	for _, test := range tests {
+		src := cfg.GetDataSourceConfig(test.source)
+		if src == nil {
+			t.Errorf("%s: the data source configuration was not found", test.source)
+			continue
+		}
+		if len(src.Creds) != test.accounts {
+			t.Errorf("%s: expected %d accounts and got %d", test.source, test.accounts, len(src.Creds))
+		}
+
+		c := src.Creds["subfinder1"]
+		if test.source == "Shodan" {
+			c = cfg.DataSrcConfigs.GetCredentials(test.source)
+		}
+		if c == nil || *c != test.expected {
+			t.Errorf("%s: expected the credentials %+v and got %+v", test.source, test.expected, c)
+		}
+	}
+
+	if src := cfg.GetDataSourceConfig("VirusTotal"); src != nil {
+		t.Errorf("the provider without keys was imported: %+v", src)
+	}
+}
+
+func TestImportSubfinderConfigErrors(t *testing.T) {
+	cfg := config.NewConfig()
+
+	if _, err := ImportSubfinderConfig(cfg, filepath.Join(t.TempDir(), "missing.yaml")); err == nil {
+		t.Error("the missing file did not return an error")
+	}
+
+	path := filepath.Join(t.TempDir(), "provider-config.yaml")
+	if err := os.WriteFile(path, []byte("securitytrails: {key: value"), 0600); err != nil {
+		t.Fatalf("failed to write the subfinder configuration: %v", err)
+	}
+	if _, err := ImportSubfinderConfig(cfg, path); err == nil {
+		t.Error("the malformed file did not return an error")
+	}
+}
@@ -0,0 +1,108 @@
+// Copyright © by Jeff Foley 2017-2023. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+// SPDX-License-Identifier: Apache-2.0
+
+package datasrcs
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/owasp-amass/amass/v4/requests"
+)
+
+func TestWhoisXMLAPIReverseWhois(t *testing.T) {
+	tests := []struct {
+		label     string
+		options   string
+		exhausted bool
+		terms     string
+		names     string
+	}{
+		{"Domain", "", false, "owasp.org", "owasp.org:owasp.net,owasp.org:owasptest.org"},
+		{
+			label:   "Search_Terms",
+			options: "      search_terms:\n        - OWASP Foundation\n        - admin@owasp.org\n",
+			terms:   "owasp.org,OWASP Foundation,admin@owasp.org",
+			names:   "owasp.org:appsecusa.org,owasp.org:owasp.net,owasp.org:owasp.net,owasp.org:owasptest.org",
+		},
+		{
+			label:     "Exhausted",
+			options:   "      search_terms:\n        - OWASP Foundation\n",
+			exhausted: true,
+			terms:     "owasp.org",
+		},
+	}
+
+	for _, test := range tests {
+		var lock sync.Mutex
+		var terms []string
+
+		res := scriptRun{
+			path:  "scripts/api/whoisxmlapi.ads",
+			setup: withSetup(withKeys("WhoisXMLAPI", []string{"testing"}), withOptions(t, "WhoisXMLAPI", test.options)),
+			overrides: func(url string) string {
+				return `
+function reverse_url()
+    return "` + url + `/api/v2"
+end
+`
+			},
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				var body struct {
+					Key   string `json:"apiKey"`
+					Terms struct {
+						Include []string `json:"include"`
+					} `json:"basicSearchTerms"`
+				}
+				if r.Method != http.MethodPost || json.NewDecoder(r.Body).Decode(&body) != nil ||
+					body.Key != "testing" || len(body.Terms.Include) != 1 {
+					w.WriteHeader(http.StatusBadRequest)
+					return
+				}
+
+				term := body.Terms.Include[0]
+				if term == "example.com" {
+					_, _ = w.Write([]byte(`{"domainsCount":0,"domainsList":[]}`))
+					return
+				}
+				lock.Lock()
+				terms = append(terms, term)
+				lock.Unlock()
+
+				if test.exhausted {
+					w.WriteHeader(http.StatusForbidden)
+					_, _ = w.Write([]byte(`{"code":403,"messages":"Access restricted. Check the DRS credits balance or enter the correct API key."}`))
+					return
+				}
+				switch term {
+				case "owasp.org":
+					_, _ = w.Write([]byte(`{"domainsCount":2,"domainsList":["owasp.net","owasptest.org"]}`))
+				case "OWASP Foundation":
+					_, _ = w.Write([]byte(`{"domainsCount":2,"domainsList":["owasp.net","appsecusa.org"]}`))
+				default:
+					_, _ = w.Write([]byte(`{"domainsCount":0,"domainsList":[]}`))
+				}
+			},
+			inputs: []interface{}{
+				&requests.WhoisRequest{Domain: "owasp.org"},
+				&requests.WhoisRequest{Domain: "example.com"},
+			},
+		}.run(t)
+
+		if got := strings.Join(res.associated(), ","); got != test.names {
+			t.Errorf("%s: expected the associated domains %s and got %s", test.label, test.names, got)
+		}
+		lock.Lock()
+		if got := strings.Join(terms, ","); got != test.terms {
+			t.Errorf("%s: expected the search terms %s and got %s", test.label, test.terms, got)
+		}
+		lock.Unlock()
+		if test.exhausted != strings.Contains(res.logs, "credits balance of the key was exhausted") {
+			t.Errorf("%s: the logs did not report the exhausted balance correctly: %s", test.label, res.logs)
+		}
+	}
+}
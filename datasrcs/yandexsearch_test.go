@@ -0,0 +1,111 @@
+// Copyright © by Jeff Foley 2017-2023. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+// SPDX-License-Identifier: Apache-2.0
+
+package datasrcs
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// Saved from the Yandex search results, with the result entries trimmed
+const yandexSearchPage = `<ul class="serp-list serp-list_left_yes" id="search-result" role="main">
+<li class="serp-item serp-item_card" data-cid="0">
+<div class="Organic organic Typo Typo_text_m Typo_line_s">
+<a class="Link Link_theme_normal OrganicTitle-Link" href="https://%[1]s/about/" target="_blank">
+<h2 class="OrganicTitle-LinkText"><span class="OrganicTitleContentSpan">About - <b>OWASP</b></span></h2></a>
+<div class="Path Organic-Path"><a class="Link Link_theme_outer Path-Item" href="https://%[1]s/"><b>%[1]s</b></a></div>
+</div></li>
+<li class="serp-item serp-item_card" data-cid="1">
+<div class="Organic organic Typo Typo_text_m Typo_line_s">
+<a class="Link Link_theme_normal OrganicTitle-Link" href="https://www.example.com/owasp" target="_blank">
+<h2 class="OrganicTitle-LinkText"><span class="OrganicTitleContentSpan">Example</span></h2></a>
+</div></li>
+</ul>
+<div class="pager" role="navigation"><a class="Pager-Item" href="/search/?text=site%%3Aowasp.org&amp;p=%[2]d">Next</a></div>`
+
+// Saved from the interstitial page Yandex serves to suspected robots
+const yandexCaptchaPage = `<!DOCTYPE html><html><head><title>Are you not a robot?</title></head>
+<body><div class="CheckboxCaptcha"><form method="POST" action="/checkcaptcha?key=d0f1e2&amp;retpath=https%3A%2F%2Fyandex.com%2Fsearch">
+<input class="CheckboxCaptcha-Button" type="submit" value="I'm not a robot"></form></div></body></html>`
+
+func TestYandexSearchPages(t *testing.T) {
+	overrides := scriptOverrides(`
+function build_url(domain, pagenum)
+    return "%[1]s/search/?text=" .. url_encode(search_query(domain)) .. "&p=" .. pagenum
+end
+`)
+
+	tests := []struct {
+		desc     string
+		pages    []string
+		expected []string
+		requests int
+		captcha  bool
+	}{
+		{
+			desc: "pages until no new names",
+			pages: []string{
+				fmt.Sprintf(yandexSearchPage, "dev.owasp.org", 1),
+				fmt.Sprintf(yandexSearchPage, "api.owasp.org", 2),
+				fmt.Sprintf(yandexSearchPage, "api.owasp.org", 3),
+				fmt.Sprintf(yandexSearchPage, "ftp.owasp.org", 4),
+			},
+			expected: []string{"dev.owasp.org", "api.owasp.org"},
+			requests: 3,
+		},
+		{
+			desc: "captcha interstitial",
+			pages: []string{
+				fmt.Sprintf(yandexSearchPage, "dev.owasp.org", 1),
+				yandexCaptchaPage,
+				fmt.Sprintf(yandexSearchPage, "api.owasp.org", 3),
+			},
+			expected: []string{"dev.owasp.org"},
+			requests: 2,
+			captcha:  true,
+		},
+	}
+
+	for _, test := range tests {
+		var lock sync.Mutex
+		var queries []string
+
+		names, logs := runScript(t, "scripts/scrape/yandexsearch.ads", overrides, func(w http.ResponseWriter, r *http.Request) {
+			q := r.URL.Query()
+			if strings.Contains(q.Get("text"), "example.com") {
+				return
+			}
+
+			lock.Lock()
+			defer lock.Unlock()
+
+			if n := len(queries); n < len(test.pages) {
+				_, _ = w.Write([]byte(test.pages[n]))
+			}
+			queries = append(queries, q.Get("text")+"&p="+q.Get("p"))
+		})
+
+		if strings.Join(names, ",") != strings.Join(test.expected, ",") {
+			t.Errorf("%s: expected %v and got %v", test.desc, test.expected, names)
+		}
+		if test.captcha != strings.Contains(logs, "CAPTCHA") {
+			t.Errorf("%s: expected the CAPTCHA to be logged %t: %s", test.desc, test.captcha, logs)
+		}
+
+		lock.Lock()
+		if len(queries) != test.requests {
+			t.Errorf("%s: expected %d requests and the service received %d", test.desc, test.requests, len(queries))
+		}
+		for i, q := range queries {
+			if expected := fmt.Sprintf("site:owasp.org -site:www.owasp.org&p=%d", i); q != expected {
+				t.Errorf("%s: expected the query %s and got %s", test.desc, expected, q)
+			}
+		}
+		lock.Unlock()
+	}
+}
@@ -0,0 +1,59 @@
+// Copyright © by Jeff Foley 2017-2023. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+// SPDX-License-Identifier: Apache-2.0
+
+package datasrcs
+
+import (
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestZoomEyeDomainSearch(t *testing.T) {
+	overrides := scriptOverrides(`
+function datasrc_config()
+    return {['credentials']={['key']="testing"}}
+end
+
+function domain_url(domain, pagenum)
+    return "%[1]s/domain/search?q=" .. domain .. "&type=1&page=" .. pagenum
+end
+`)
+
+	var lock sync.Mutex
+	var pages []string
+	runResponseTests(t, "scripts/api/zoomeye.ads", nil, overrides, []responseTest{
+		{"Pages", func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Query().Get("q") != "owasp.org" {
+				_, _ = w.Write([]byte(`{"status":200,"total":0,"list":[]}`))
+				return
+			}
+
+			page := r.URL.Query().Get("page")
+			lock.Lock()
+			pages = append(pages, page)
+			lock.Unlock()
+
+			switch page {
+			case "1":
+				_, _ = w.Write([]byte(`{"status":200,"total":3,"list":[{"name":"www.owasp.org"},{"name":"www.example.com"}]}`))
+			case "2":
+				_, _ = w.Write([]byte(`{"status":200,"total":3,"list":[{"name":"api.owasp.org"}]}`))
+			default:
+				_, _ = w.Write([]byte(`{"status":200,"total":3,"list":[{"name":"ftp.owasp.org"}]}`))
+			}
+		}, "www.owasp.org,api.owasp.org", ""},
+		{"Quota", func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusForbidden)
+			_, _ = w.Write([]byte(`{"error":"credits_insufficent","message":"Your account's credits have been exhausted"}`))
+		}, "", "credits have been exhausted"},
+	})
+
+	lock.Lock()
+	defer lock.Unlock()
+	if strings.Join(pages, ",") != "1,2" {
+		t.Errorf("the script requested the wrong pages: %v", pages)
+	}
+}
@@ -6,6 +6,7 @@ package enum
 
 import (
 	"context"
+	"io"
 	"sync"
 	"time"
 
@@ -14,6 +15,8 @@ import (
 	"github.com/caffix/queue"
 	"github.com/caffix/service"
 	"github.com/owasp-amass/amass/v4/datasrcs"
+	"github.com/owasp-amass/amass/v4/datasrcs/scripting"
+	amassdns "github.com/owasp-amass/amass/v4/net/dns"
 	"github.com/owasp-amass/amass/v4/requests"
 	"github.com/owasp-amass/amass/v4/systems"
 	"github.com/owasp-amass/config/config"
@@ -23,20 +26,29 @@ import (
 
 // Enumeration is the object type used to execute a DNS enumeration.
 type Enumeration struct {
-	Config   *config.Config
-	Sys      systems.System
-	ctx      context.Context
-	graph    *netmap.Graph
-	srcs     []service.Service
-	done     chan struct{}
-	nameSrc  *enumSource
-	subTask  *subdomainTask
-	dnsTask  *dnsTask
-	valTask  *dnsTask
-	store    *dataManager
-	requests queue.Queue
-	plock    sync.Mutex
-	pending  bool
+	Config *config.Config
+	Sys    systems.System
+	// Findings receives a JSON line for each relationship stored during the enumeration, when not nil
+	Findings io.Writer
+	// ApexFirst releases the root domain names and their immediate subdomains before deeper names, when true
+	ApexFirst bool
+	// AllowMalformedNames stores the names that fail the hostname validation, as earlier versions did, when true
+	AllowMalformedNames bool
+	// StoreQueueSize bounds the infrastructure lookups waiting to be stored, when greater than zero
+	StoreQueueSize int
+	flock          sync.Mutex
+	ctx            context.Context
+	graph          *netmap.Graph
+	srcs           []service.Service
+	done           chan struct{}
+	nameSrc        *enumSource
+	subTask        *subdomainTask
+	dnsTask        *dnsTask
+	valTask        *dnsTask
+	store          *dataManager
+	requests       queue.Queue
+	plock          sync.Mutex
+	pending        bool
 }
 
 // NewEnumeration returns an initialized Enumeration that has not been started yet.
@@ -101,9 +113,44 @@ func (e *Enumeration) Start(ctx context.Context) error {
 	return err
 }
 
-// Release the root domain names to the input source and each data source.
+// StoreQueueDepth returns the number of infrastructure lookups waiting to be stored.
+func (e *Enumeration) StoreQueueDepth() int {
+	if e.store == nil {
+		return 0
+	}
+	return e.store.Depth()
+}
+
+// MalformedNames returns the number of names dropped by the hostname validation before storage.
+func (e *Enumeration) MalformedNames() int64 {
+	if e.store == nil {
+		return 0
+	}
+	return e.store.malformed.Load()
+}
+
+// SourceMetrics returns a snapshot of the counters kept by each data source selected for the enumeration.
+func (e *Enumeration) SourceMetrics() map[string]scripting.Metrics {
+	m := make(map[string]scripting.Metrics)
+
+	for _, src := range e.srcs {
+		if s, ok := src.(*scripting.Script); ok {
+			m[s.String()] = s.Metrics()
+		}
+	}
+	return m
+}
+
+// Release the root domain names to the input source and each data source. This is where the
+// root domain names enter the enumeration, so the public suffixes are only rejected here.
 func (e *Enumeration) submitDomainNames() {
 	for _, domain := range e.Config.Domains() {
+		// A public suffix, such as co.uk, would bring every organization below it into the enumeration
+		if amassdns.IsPublicSuffix(domain) {
+			e.Config.Log.Printf("The public suffix %s cannot be used as a root domain name", domain)
+			continue
+		}
+
 		req := &requests.DNSRequest{
 			Name:   domain,
 			Domain: domain,
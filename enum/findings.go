@@ -0,0 +1,47 @@
+// Copyright © by Jeff Foley 2017-2023. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+// SPDX-License-Identifier: Apache-2.0
+
+package enum
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Finding is a relationship between two assets that was stored during the enumeration.
+type Finding struct {
+	From      string    `json:"from"`
+	To        string    `json:"to"`
+	Relation  string    `json:"rel"`
+	Source    string    `json:"source"`
+	Tag       string    `json:"tag,omitempty"`
+	Timestamp time.Time `json:"timestamp"`
+}
+
+// Writes the finding as a JSON line when the enumeration has a findings writer. The tag
+// describes how the data source discovered the name the relationship is outgoing from.
+func (e *Enumeration) emitFinding(from, to, rel, source, tag string) {
+	if e.Findings == nil {
+		return
+	}
+
+	data, err := json.Marshal(&Finding{
+		From:      from,
+		To:        to,
+		Relation:  rel,
+		Source:    source,
+		Tag:       tag,
+		Timestamp: time.Now(),
+	})
+	if err != nil {
+		return
+	}
+
+	e.flock.Lock()
+	defer e.flock.Unlock()
+
+	if _, err := e.Findings.Write(append(data, '\n')); err != nil {
+		e.Config.Log.Printf("Failed to write the finding: %v", err)
+	}
+}
@@ -0,0 +1,98 @@
+// Copyright © by Jeff Foley 2017-2023. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+// SPDX-License-Identifier: Apache-2.0
+
+package enum
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/caffix/netmap"
+	"github.com/caffix/queue"
+	"github.com/miekg/dns"
+	"github.com/owasp-amass/amass/v4/requests"
+	"github.com/owasp-amass/config/config"
+	bf "github.com/tylertreat/BoomFilters"
+)
+
+func TestFindingsEmittedInOrder(t *testing.T) {
+	cfg := config.NewConfig()
+	cfg.AddDomain("owasp.org")
+
+	var buf bytes.Buffer
+	e := &Enumeration{
+		Config:   cfg,
+		graph:    netmap.NewGraph("memory", "", ""),
+		Findings: &buf,
+	}
+	e.nameSrc = &enumSource{
+		enum:    e,
+		queue:   queue.NewQueue(),
+		filter:  bf.NewDefaultStableBloomFilter(1000, 0.01),
+		done:    make(chan struct{}),
+		release: make(chan struct{}, 10),
+	}
+	dm := newDataManager(e)
+	defer func() { <-dm.Stop() }()
+
+	ctx := context.Background()
+	reqs := []*requests.DNSRequest{
+		{
+			Name:   "www.owasp.org",
+			Domain: "owasp.org",
+			Records: []requests.DNSAnswer{
+				{Name: "www.owasp.org", Type: int(dns.TypeA), Data: "192.0.2.1"},
+				{Name: "www.owasp.org", Type: int(dns.TypeAAAA), Data: "2001:db8::1"},
+			},
+		},
+		{
+			Name:    "owasp.org",
+			Domain:  "owasp.org",
+			Records: []requests.DNSAnswer{{Name: "owasp.org", Type: int(dns.TypeMX), Data: "mail.owasp.org."}},
+		},
+		{
+			Name:    "dev.owasp.org",
+			Domain:  "owasp.org",
+			Records: []requests.DNSAnswer{{Name: "dev.owasp.org", Type: int(dns.TypeCNAME), Data: "www.owasp.org."}},
+		},
+	}
+	for _, req := range reqs {
+		if err := dm.dnsRequest(ctx, req, nil); err != nil {
+			t.Fatalf("failed to store the %s request: %v", req.Name, err)
+		}
+	}
+
+	expected := []Finding{
+		{From: "www.owasp.org", To: "192.0.2.1", Relation: "a_record"},
+		{From: "www.owasp.org", To: "2001:db8::1", Relation: "aaaa_record"},
+		{From: "owasp.org", To: "mail.owasp.org", Relation: "mx_record"},
+		{From: "dev.owasp.org", To: "www.owasp.org", Relation: "cname_record"},
+	}
+
+	var got []Finding
+	scanner := bufio.NewScanner(&buf)
+	for scanner.Scan() {
+		var f Finding
+		if err := json.Unmarshal(scanner.Bytes(), &f); err != nil {
+			t.Fatalf("failed to unmarshal the finding %s: %v", scanner.Text(), err)
+		}
+		got = append(got, f)
+	}
+	if len(got) != len(expected) {
+		t.Fatalf("expected %d findings and got %d", len(expected), len(got))
+	}
+
+	for i, f := range got {
+		exp := expected[i]
+		if f.From != exp.From || f.To != exp.To || f.Relation != exp.Relation || f.Source != "DNS" {
+			t.Errorf("finding %d: expected %v and got %v", i, exp, f)
+		}
+		if f.Timestamp.IsZero() || (i > 0 && f.Timestamp.Before(got[i-1].Timestamp)) {
+			t.Errorf("finding %d has the timestamp %v out of order", i, f.Timestamp)
+		}
+	}
+}
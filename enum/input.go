@@ -6,6 +6,7 @@ package enum
 
 import (
 	"context"
+	"strings"
 	"sync"
 	"time"
 
@@ -85,8 +86,17 @@ func (r *enumSource) newName(req *requests.DNSRequest) {
 		r.releaseOutput(1)
 		return
 	}
-	// Clean up the newly discovered name and domain
-	requests.SanitizeDNSRequest(req)
+	// Clean up the newly discovered name and domain, and drop the malformed names
+	if err := requests.SanitizeDNSRequest(req); err != nil && !r.enum.AllowMalformedNames {
+		if r.enum.store != nil {
+			r.enum.store.malformed.Add(1)
+		}
+		if r.enum.Config.Verbose {
+			r.enum.Config.Log.Printf("Dropped a malformed name: %v", err)
+		}
+		r.releaseOutput(1)
+		return
+	}
 
 	if r.enum.Config.Blacklisted(req.Name) {
 		r.releaseOutput(1)
@@ -96,7 +106,23 @@ func (r *enumSource) newName(req *requests.DNSRequest) {
 		r.releaseOutput(1)
 		return
 	}
-	r.queue.Append(req)
+	r.queue.AppendPriority(req, r.namePriority(req))
+}
+
+// namePriority returns the queue priority of the name, which depends on the number of labels
+// below the root domain name when the enumeration releases the shallow names first.
+func (r *enumSource) namePriority(req *requests.DNSRequest) int {
+	if !r.enum.ApexFirst {
+		return queue.PriorityNormal
+	}
+
+	switch strings.Count(req.Name, ".") - strings.Count(req.Domain, ".") {
+	case 0:
+		return queue.PriorityCritical
+	case 1:
+		return queue.PriorityHigh
+	}
+	return queue.PriorityNormal
 }
 
 func (r *enumSource) newAddr(req *requests.AddrRequest) {
@@ -0,0 +1,205 @@
+// Copyright © by Jeff Foley 2017-2023. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+// SPDX-License-Identifier: Apache-2.0
+
+package enum
+
+import (
+	"bytes"
+	"context"
+	"log"
+	"net/netip"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/caffix/queue"
+	"github.com/owasp-amass/amass/v4/datasrcs/scripting"
+	"github.com/owasp-amass/amass/v4/requests"
+	"github.com/owasp-amass/amass/v4/systems"
+	"github.com/owasp-amass/config/config"
+	"github.com/owasp-amass/open-asset-model/network"
+	bf "github.com/tylertreat/BoomFilters"
+)
+
+func TestApexFirstOrdering(t *testing.T) {
+	names := []string{"a.b.www.owasp.org", "dev.owasp.org", "owasp.org", "x.dev.owasp.org", "example.com", "www.example.com"}
+
+	for _, apexFirst := range []bool{false, true} {
+		r := &enumSource{
+			enum:   &Enumeration{Config: config.NewConfig(), ApexFirst: apexFirst},
+			queue:  queue.NewQueue(),
+			filter: bf.NewDefaultStableBloomFilter(1000, 0.01),
+			done:   make(chan struct{}),
+		}
+
+		for _, name := range names {
+			domain := "owasp.org"
+			if strings.HasSuffix(name, "example.com") {
+				domain = "example.com"
+			}
+			r.newName(&requests.DNSRequest{Name: name, Domain: domain})
+		}
+
+		var depths []int
+		for r.queue.Len() > 0 {
+			req := r.Data().(*requests.DNSRequest)
+			depths = append(depths, strings.Count(req.Name, ".")-strings.Count(req.Domain, "."))
+		}
+		if len(depths) != len(names) {
+			t.Fatalf("apex first %t: expected %d names and got %d", apexFirst, len(names), len(depths))
+		}
+		if !apexFirst {
+			continue
+		}
+
+		// Both root domain names, then both immediate subdomains, then the deeper names in any order
+		for i := 1; i < len(depths); i++ {
+			if depths[i] < depths[i-1] && depths[i] < 2 {
+				t.Errorf("apex first: the names were released in the wrong order of depths: %v", depths)
+				break
+			}
+		}
+	}
+}
+
+func TestMalformedInputNames(t *testing.T) {
+	for _, allow := range []bool{false, true} {
+		var logs bytes.Buffer
+		cfg := config.NewConfig()
+		cfg.Log = log.New(&logs, "", 0)
+		cfg.Verbose = true
+
+		r := &enumSource{
+			enum: &Enumeration{
+				Config:              cfg,
+				AllowMalformedNames: allow,
+				store:               &dataManager{},
+			},
+			queue:   queue.NewQueue(),
+			filter:  bf.NewDefaultStableBloomFilter(1000, 0.01),
+			done:    make(chan struct{}),
+			release: make(chan struct{}, 10),
+		}
+
+		r.newName(&requests.DNSRequest{Name: "Bad_.owasp.org", Domain: "owasp.org"})
+		r.newName(&requests.DNSRequest{Name: "WWW.owasp.org", Domain: "owasp.org"})
+
+		var names []string
+		for r.queue.Len() > 0 {
+			names = append(names, r.Data().(*requests.DNSRequest).Name)
+		}
+
+		expected := "www.owasp.org"
+		if allow {
+			expected = "bad_.owasp.org,www.owasp.org"
+		}
+		if got := strings.Join(names, ","); got != expected {
+			t.Errorf("allow malformed %t: expected the names %s and got %s", allow, expected, got)
+		}
+		// The names dropped before storage are counted for the summary of the enumeration
+		var dropped int64
+		if !allow {
+			dropped = 1
+		}
+		if n := r.enum.MalformedNames(); n != dropped {
+			t.Errorf("allow malformed %t: expected %d malformed names and got %d", allow, dropped, n)
+		}
+		if logged := strings.Contains(logs.String(), "Dropped a malformed name"); logged == allow {
+			t.Errorf("allow malformed %t: the dropped name was logged: %t", allow, logged)
+		}
+	}
+}
+
+func TestPublicSuffixDomains(t *testing.T) {
+	cfg := config.NewConfig()
+	cfg.AddDomains("co.uk", "com", "owasp.org", "owasp.github.io", "github.io")
+
+	e := &Enumeration{Config: cfg, requests: queue.NewQueue()}
+	e.nameSrc = &enumSource{
+		enum:   e,
+		queue:  queue.NewQueue(),
+		filter: bf.NewDefaultStableBloomFilter(1000, 0.01),
+		done:   make(chan struct{}),
+	}
+	e.submitDomainNames()
+
+	var got []string
+	for {
+		element, ok := e.requests.Next()
+		if !ok {
+			break
+		}
+		if req, ok := element.(*requests.DNSRequest); ok {
+			got = append(got, req.Domain)
+		}
+	}
+	sort.Strings(got)
+
+	// Only the ICANN suffixes are rejected, since the private suffixes can be enumerated
+	if expected := "github.io,owasp.github.io,owasp.org"; strings.Join(got, ",") != expected {
+		t.Errorf("expected the root domain names %s to be released and got %v", expected, got)
+	}
+	if n := e.nameSrc.queue.Len(); n != 3 {
+		t.Errorf("expected 3 root domain names in the input source and got %d", n)
+	}
+}
+
+func TestScriptAddressesReachStore(t *testing.T) {
+	cfg := config.NewConfig()
+	cfg.AddDomain("owasp.org")
+
+	dm := newTestDataManager(cfg, nil)
+	defer func() { <-dm.Stop() }()
+
+	sys := &systems.SimpleSystem{Cfg: cfg, ASNCache: requests.NewASNCache()}
+	sys.ASNCache.Update(&requests.ASNRequest{ASN: 15133, Prefix: "93.184.216.0/24", Description: "EDGECAST"})
+	dm.enum.Sys = sys
+
+	// Only the address marked as in scope by the script is stored
+	s := scripting.NewScript(`
+		name="addrs"
+		type="testing"
+
+		function vertical(ctx, domain)
+			new_addr(ctx, "93.184.216.77", "www." .. domain, true)
+			new_addr(ctx, "93.184.216.78", "www." .. domain)
+		end
+	`, sys)
+	if s == nil {
+		t.Fatal("failed to load the script")
+	}
+	if err := s.Start(); err != nil {
+		t.Fatalf("failed to start the script: %v", err)
+	}
+	defer func() { _ = s.Stop() }()
+
+	s.Input() <- &requests.DNSRequest{Name: "owasp.org", Domain: "owasp.org"}
+	// The script ignores the sentinel, which is only accepted once the vertical callback returned
+	s.Input() <- struct{}{}
+
+	// The addresses sent by the script are released by the enumeration and processed by the store stage
+	src := dm.enum.nameSrc
+	for len(s.Output()) > 0 {
+		if req, ok := (<-s.Output()).(*requests.AddrRequest); ok {
+			src.newAddr(req)
+		}
+	}
+	ctx := context.Background()
+	for src.queue.Len() > 0 {
+		if _, err := dm.Process(ctx, src.Data(), nil); err != nil {
+			t.Fatalf("failed to store the address: %v", err)
+		}
+	}
+
+	for addr, stored := range map[string]bool{"93.184.216.77": true, "93.184.216.78": false} {
+		assets, err := dm.enum.graph.DB.FindByContent(network.IPAddress{
+			Address: netip.MustParseAddr(addr),
+			Type:    "IPv4",
+		}, time.Time{})
+		if found := err == nil && len(assets) > 0; found != stored {
+			t.Errorf("expected the address %s to be stored: %t", addr, stored)
+		}
+	}
+}
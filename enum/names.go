@@ -176,6 +176,9 @@ func (r *subdomainTask) timesManager() {
 	}
 }
 
+// seedDomainRelation is the relation of the findings linking a name to the seed domain it was discovered under.
+const seedDomainRelation = "seed_domain"
+
 func (r *subdomainTask) linkNodesToApexes() {
 	apexes := make(map[string]*types.Asset)
 
@@ -215,6 +218,23 @@ func (r *subdomainTask) linkNodesToApexes() {
 			if apex != nil {
 				_, _ = r.enum.graph.DB.Create(apex, "node", n)
 			}
+			// the taxonomy only has the node relation between names, so the seed domain
+			// the name was discovered under is reported as a finding of its own relation
+			if n.Name != d && r.seedDomain(n.Name) == d {
+				r.enum.emitFinding(n.Name, d, seedDomainRelation, "Scope", "")
+			}
+		}
+	}
+}
+
+// seedDomain returns the most specific domain name in scope that the provided name falls under.
+func (r *subdomainTask) seedDomain(name string) string {
+	var seed string
+
+	for _, d := range r.enum.Config.Domains() {
+		if (name == d || strings.HasSuffix(name, "."+d)) && len(d) > len(seed) {
+			seed = d
 		}
 	}
+	return seed
 }
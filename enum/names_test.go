@@ -0,0 +1,74 @@
+// Copyright © by Jeff Foley 2017-2023. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+// SPDX-License-Identifier: Apache-2.0
+
+package enum
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/caffix/netmap"
+	"github.com/owasp-amass/config/config"
+)
+
+func TestLinkNodesToSeedDomain(t *testing.T) {
+	cfg := config.NewConfig()
+	cfg.AddDomains("owasp.org", "dev.owasp.org")
+	cfg.CollectionStartTime = time.Now().Add(-time.Hour)
+
+	var buf bytes.Buffer
+	g := netmap.NewGraph("memory", "", "")
+	e := &Enumeration{Config: cfg, Findings: &buf, graph: g}
+	r := newSubdomainTask(e)
+	defer r.Stop()
+
+	ctx := context.Background()
+	for _, name := range []string{"www.owasp.org", "api.dev.owasp.org", "dev.owasp.org"} {
+		if _, err := g.UpsertFQDN(ctx, name); err != nil {
+			t.Fatalf("failed to insert %s: %v", name, err)
+		}
+	}
+	r.linkNodesToApexes()
+
+	expected := map[string]string{
+		"www.owasp.org":     "owasp.org",
+		"api.dev.owasp.org": "dev.owasp.org",
+	}
+	// The memory graphs can share a database with the graphs of other tests, so
+	// only the names inserted by this test are checked
+	inserted := map[string]struct{}{"www.owasp.org": {}, "api.dev.owasp.org": {}, "dev.owasp.org": {}}
+
+	got := make(map[string]string)
+	scanner := bufio.NewScanner(&buf)
+	for scanner.Scan() {
+		var f Finding
+		if err := json.Unmarshal(scanner.Bytes(), &f); err != nil {
+			t.Fatalf("failed to unmarshal the finding %s: %v", scanner.Text(), err)
+		}
+		if _, found := inserted[f.From]; !found {
+			continue
+		}
+		if f.Relation != "seed_domain" {
+			t.Errorf("%s was linked to %s with the %s relation", f.From, f.To, f.Relation)
+			continue
+		}
+		if seed, dup := got[f.From]; dup {
+			t.Errorf("%s was linked to both %s and %s", f.From, seed, f.To)
+		}
+		got[f.From] = f.To
+	}
+
+	if len(got) != len(expected) {
+		t.Errorf("%d names were linked to a seed domain, expected %d", len(got), len(expected))
+	}
+	for name, seed := range expected {
+		if got[name] != seed {
+			t.Errorf("%s was linked to the seed domain %q, expected %s", name, got[name], seed)
+		}
+	}
+}
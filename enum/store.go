@@ -5,12 +5,16 @@
 package enum
 
 import (
+	"container/list"
 	"context"
 	"errors"
 	"fmt"
 	"net"
 	"regexp"
+	"strconv"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/caffix/pipeline"
@@ -31,6 +35,12 @@ type dataManager struct {
 	signalDone  chan struct{}
 	confirmDone chan struct{}
 	filter      *bf.StableBloomFilter
+	// slots bounds the queue when the enumeration sets a StoreQueueSize
+	slots chan struct{}
+	// stored holds the DNS records recently written to the graph during the enumeration
+	stored *storedRecords
+	// malformed counts the names dropped by the hostname validation
+	malformed atomic.Int64
 }
 
 // newDataManager returns a dataManager specific to the provided Enumeration.
@@ -41,6 +51,10 @@ func newDataManager(e *Enumeration) *dataManager {
 		signalDone:  make(chan struct{}, 2),
 		confirmDone: make(chan struct{}, 2),
 		filter:      bf.NewDefaultStableBloomFilter(1000000, 0.01),
+		stored:      newStoredRecords(maxStoredRecords),
+	}
+	if e.StoreQueueSize > 0 {
+		dm.slots = make(chan struct{}, e.StoreQueueSize)
 	}
 
 	go dm.processASNRequests()
@@ -49,10 +63,16 @@ func newDataManager(e *Enumeration) *dataManager {
 
 func (dm *dataManager) Stop() chan struct{} {
 	dm.filter.Reset()
+	dm.stored.Clear()
 	close(dm.signalDone)
 	return dm.confirmDone
 }
 
+// Depth returns the number of requests waiting in the queue.
+func (dm *dataManager) Depth() int {
+	return dm.queue.Len()
+}
+
 // Process implements the pipeline Task interface.
 func (dm *dataManager) Process(ctx context.Context, data pipeline.Data, tp pipeline.TaskParams) (pipeline.Data, error) {
 	select {
@@ -90,7 +110,7 @@ func (dm *dataManager) Process(ctx context.Context, data pipeline.Data, tp pipel
 }
 
 func (dm *dataManager) dnsRequest(ctx context.Context, req *requests.DNSRequest, tp pipeline.TaskParams) error {
-	if dm.enum.Config.Blacklisted(req.Name) {
+	if dm.enum.Config.Blacklisted(req.Name) || !dm.validName(req.Name) {
 		return nil
 	}
 	// Check for CNAME records first
@@ -99,8 +119,14 @@ func (dm *dataManager) dnsRequest(ctx context.Context, req *requests.DNSRequest,
 		req.Records[i].Data = strings.Trim(strings.ToLower(r.Data), ".")
 
 		if uint16(r.Type) == dns.TypeCNAME {
+			key := storedKey(req.Name, req.Records[i])
+			if dm.stored.Has(key) {
+				return nil
+			}
 			// Do not enter more than the CNAME record
-			return dm.insertCNAME(ctx, req, i, tp)
+			err := dm.insertCNAME(ctx, req, i, tp)
+			dm.markStored(key, err)
+			return err
 		}
 	}
 
@@ -111,6 +137,11 @@ func (dm *dataManager) dnsRequest(ctx context.Context, req *requests.DNSRequest,
 			return nil
 		default:
 		}
+		// The graph already has the assets and relation created for this record
+		key := storedKey(req.Name, r)
+		if dm.stored.Has(key) {
+			continue
+		}
 
 		var e error
 		switch uint16(r.Type) {
@@ -133,6 +164,7 @@ func (dm *dataManager) dnsRequest(ctx context.Context, req *requests.DNSRequest,
 		case dns.TypeSPF:
 			e = dm.insertSPF(ctx, req, i, tp)
 		}
+		dm.markStored(key, e)
 		if err == nil {
 			err = e
 		}
@@ -140,11 +172,121 @@ func (dm *dataManager) dnsRequest(ctx context.Context, req *requests.DNSRequest,
 	return err
 }
 
+// validName returns true when the name can be written to the graph. Malformed names, such as those
+// with empty labels or underscores ending a label, are dropped and counted, unless the enumeration allows them.
+func (dm *dataManager) validName(name string) bool {
+	if dm.enum.AllowMalformedNames {
+		return true
+	}
+
+	if err := amassdns.ValidateHostname(strings.ToLower(resolve.RemoveLastDot(name))); err != nil {
+		dm.malformed.Add(1)
+		// The dropped names are counted, so each name is only logged for the verbose output
+		if dm.enum.Config.Verbose {
+			dm.enum.Config.Log.Printf("Dropped a malformed name: %v", err)
+		}
+		return false
+	}
+	return true
+}
+
+// maxStoredRecords bounds the number of DNS records remembered as written to the graph
+const maxStoredRecords = 100000
+
+// storedRecords is a bounded set of the DNS records written to the graph. The least recently used
+// records are removed when the set is full, which only causes another find-or-create for a duplicate.
+type storedRecords struct {
+	sync.Mutex
+	size    int
+	order   *list.List
+	entries map[string]*list.Element
+}
+
+func newStoredRecords(size int) *storedRecords {
+	return &storedRecords{
+		size:    size,
+		order:   list.New(),
+		entries: make(map[string]*list.Element),
+	}
+}
+
+// Has returns true when the key is in the set and marks it as recently used.
+func (sr *storedRecords) Has(key string) bool {
+	sr.Lock()
+	defer sr.Unlock()
+
+	e, found := sr.entries[key]
+	if found {
+		sr.order.MoveToFront(e)
+	}
+	return found
+}
+
+// Insert adds the key to the set and removes the least recently used key when the set is full.
+func (sr *storedRecords) Insert(key string) {
+	sr.Lock()
+	defer sr.Unlock()
+
+	if e, found := sr.entries[key]; found {
+		sr.order.MoveToFront(e)
+		return
+	}
+
+	sr.entries[key] = sr.order.PushFront(key)
+	if sr.order.Len() > sr.size {
+		last := sr.order.Back()
+		sr.order.Remove(last)
+		delete(sr.entries, last.Value.(string))
+	}
+}
+
+// Len returns the number of keys in the set.
+func (sr *storedRecords) Len() int {
+	sr.Lock()
+	defer sr.Unlock()
+
+	return sr.order.Len()
+}
+
+// Clear removes all the keys from the set.
+func (sr *storedRecords) Clear() {
+	sr.Lock()
+	defer sr.Unlock()
+
+	sr.order.Init()
+	sr.entries = make(map[string]*list.Element)
+}
+
+// recordSource returns the data source that provided the DNS record, or DNS when it was resolved.
+func recordSource(r requests.DNSAnswer) string {
+	if r.Source != "" {
+		return r.Source
+	}
+	return "DNS"
+}
+
+// storedKey includes the source of the record, so each data source providing it still emits a finding.
+func storedKey(name string, r requests.DNSAnswer) string {
+	return name + "|" + strconv.Itoa(r.Type) + "|" + r.Data + "|" + recordSource(r)
+}
+
+// markStored records that the DNS record was written, so the duplicates received later
+// during the enumeration do not repeat the find-or-create work in the graph database.
+// Failed writes are not recorded, which allows a duplicate to try again.
+func (dm *dataManager) markStored(key string, err error) {
+	if err == nil {
+		dm.stored.Insert(key)
+	}
+}
+
 func (dm *dataManager) insertCNAME(ctx context.Context, req *requests.DNSRequest, recidx int, tp pipeline.TaskParams) error {
 	target := resolve.RemoveLastDot(req.Records[recidx].Data)
 	if target == "" {
 		return errors.New("failed to extract a FQDN from the DNS answer data")
 	}
+	if !dm.validName(target) {
+		return nil
+	}
 
 	domain, err := publicsuffix.EffectiveTLDPlusOne(target)
 	if err != nil || domain == "" {
@@ -158,6 +300,7 @@ func (dm *dataManager) insertCNAME(ctx context.Context, req *requests.DNSRequest
 	if err := dm.enum.graph.UpsertCNAME(ctx, req.Name, target); err != nil {
 		return fmt.Errorf("failed to insert CNAME: %v", err)
 	}
+	dm.enum.emitFinding(req.Name, target, "cname_record", recordSource(req.Records[recidx]), req.Tag)
 	return nil
 }
 
@@ -175,6 +318,7 @@ func (dm *dataManager) insertA(ctx context.Context, req *requests.DNSRequest, re
 	if err := dm.enum.graph.UpsertA(ctx, req.Name, addr); err != nil {
 		return fmt.Errorf("failed to insert A record: %v", err)
 	}
+	dm.enum.emitFinding(req.Name, addr, "a_record", recordSource(req.Records[recidx]), req.Tag)
 	return nil
 }
 
@@ -192,6 +336,7 @@ func (dm *dataManager) insertAAAA(ctx context.Context, req *requests.DNSRequest,
 	if err := dm.enum.graph.UpsertAAAA(ctx, req.Name, addr); err != nil {
 		return fmt.Errorf("failed to insert AAAA record: %v", err)
 	}
+	dm.enum.emitFinding(req.Name, addr, "aaaa_record", recordSource(req.Records[recidx]), req.Tag)
 	return nil
 }
 
@@ -202,7 +347,7 @@ func (dm *dataManager) insertPTR(ctx context.Context, req *requests.DNSRequest,
 	}
 	// Do not go further if the target is not in scope
 	domain := strings.ToLower(dm.enum.Config.WhichDomain(target))
-	if domain == "" {
+	if domain == "" || !dm.validName(target) {
 		return nil
 	}
 	// Important - Allows the target DNS name to be resolved in the forward direction
@@ -213,6 +358,7 @@ func (dm *dataManager) insertPTR(ctx context.Context, req *requests.DNSRequest,
 	if err := dm.enum.graph.UpsertPTR(ctx, req.Name, target); err != nil {
 		return fmt.Errorf("failed to insert PTR record: %v", err)
 	}
+	dm.enum.emitFinding(req.Name, target, "ptr_record", recordSource(req.Records[recidx]), req.Tag)
 	return nil
 }
 
@@ -222,6 +368,9 @@ func (dm *dataManager) insertSRV(ctx context.Context, req *requests.DNSRequest,
 	if target == "" || service == "" {
 		return errors.New("failed to extract service info from the DNS answer data")
 	}
+	if !dm.validName(service) || !dm.validName(target) {
+		return nil
+	}
 	if domain := dm.enum.Config.WhichDomain(target); domain != "" {
 		dm.enum.nameSrc.newName(&requests.DNSRequest{
 			Name:   target,
@@ -231,6 +380,7 @@ func (dm *dataManager) insertSRV(ctx context.Context, req *requests.DNSRequest,
 	if err := dm.enum.graph.UpsertSRV(ctx, service, target); err != nil {
 		return fmt.Errorf("failed to insert SRV record: %v", err)
 	}
+	dm.enum.emitFinding(service, target, "srv_record", recordSource(req.Records[recidx]), req.Tag)
 	return nil
 }
 
@@ -239,6 +389,9 @@ func (dm *dataManager) insertNS(ctx context.Context, req *requests.DNSRequest, r
 	if target == "" {
 		return errors.New("failed to extract NS info from the DNS answer data")
 	}
+	if !dm.validName(target) {
+		return nil
+	}
 
 	domain, err := publicsuffix.EffectiveTLDPlusOne(target)
 	if err != nil || domain == "" {
@@ -253,6 +406,7 @@ func (dm *dataManager) insertNS(ctx context.Context, req *requests.DNSRequest, r
 	if err := dm.enum.graph.UpsertNS(ctx, req.Name, target); err != nil {
 		return fmt.Errorf("failed to insert NS record: %v", err)
 	}
+	dm.enum.emitFinding(req.Name, target, "ns_record", recordSource(req.Records[recidx]), req.Tag)
 	return nil
 }
 
@@ -261,6 +415,9 @@ func (dm *dataManager) insertMX(ctx context.Context, req *requests.DNSRequest, r
 	if target == "" {
 		return errors.New("failed to extract a FQDN from the DNS answer data")
 	}
+	if !dm.validName(target) {
+		return nil
+	}
 
 	domain, err := publicsuffix.EffectiveTLDPlusOne(target)
 	if err != nil || domain == "" {
@@ -275,6 +432,7 @@ func (dm *dataManager) insertMX(ctx context.Context, req *requests.DNSRequest, r
 	if err := dm.enum.graph.UpsertMX(ctx, req.Name, target); err != nil {
 		return fmt.Errorf("failed to insert MX record: %v", err)
 	}
+	dm.enum.emitFinding(req.Name, target, "mx_record", recordSource(req.Records[recidx]), req.Tag)
 	return nil
 }
 
@@ -343,6 +501,14 @@ func (dm *dataManager) addrRequest(ctx context.Context, req *requests.AddrReques
 		}
 		return err
 	}
+	// Block the producer while a bounded queue is full
+	if dm.slots != nil {
+		select {
+		case <-ctx.Done():
+			return nil
+		case dm.slots <- struct{}{}:
+		}
+	}
 
 	dm.queue.Append(req)
 	return nil
@@ -369,6 +535,9 @@ func (dm *dataManager) nextInfraInfo() {
 	if !ok {
 		return
 	}
+	if dm.slots != nil {
+		<-dm.slots
+	}
 
 	ctx := context.Background()
 	req := e.(*requests.AddrRequest)
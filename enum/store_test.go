@@ -0,0 +1,411 @@
+// Copyright © by Jeff Foley 2017-2023. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+// SPDX-License-Identifier: Apache-2.0
+
+package enum
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/caffix/netmap"
+	"github.com/caffix/queue"
+	"github.com/miekg/dns"
+	"github.com/owasp-amass/amass/v4/requests"
+	"github.com/owasp-amass/amass/v4/systems"
+	"github.com/owasp-amass/config/config"
+	bf "github.com/tylertreat/BoomFilters"
+)
+
+func TestBoundedStoreQueue(t *testing.T) {
+	cfg := config.NewConfig()
+	e := &Enumeration{
+		Config:         cfg,
+		Sys:            &systems.SimpleSystem{Cfg: cfg, ASNCache: requests.NewASNCache()},
+		graph:          netmap.NewGraph("memory", "", ""),
+		StoreQueueSize: 2,
+	}
+	// The processing goroutine is not started, so the queue only drains when the test allows it
+	dm := &dataManager{
+		enum:   e,
+		queue:  queue.NewQueue(),
+		filter: bf.NewDefaultStableBloomFilter(1000, 0.01),
+		slots:  make(chan struct{}, e.StoreQueueSize),
+	}
+
+	ctx := context.Background()
+	addrs := []string{"93.184.216.1", "93.184.216.2", "93.184.216.3"}
+	for _, addr := range addrs[:2] {
+		if err := dm.addrRequest(ctx, &requests.AddrRequest{Address: addr, InScope: true}, nil); err != nil {
+			t.Fatalf("failed to queue the %s request: %v", addr, err)
+		}
+	}
+	if d := dm.Depth(); d != 2 {
+		t.Fatalf("expected a queue depth of 2 and got %d", d)
+	}
+
+	done := make(chan struct{})
+	go func() {
+		_ = dm.addrRequest(ctx, &requests.AddrRequest{Address: addrs[2], InScope: true}, nil)
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		t.Fatal("the producer did not block on the full queue")
+	case <-time.After(250 * time.Millisecond):
+	}
+	if d := dm.Depth(); d != 2 {
+		t.Errorf("expected the queue depth to remain 2 and got %d", d)
+	}
+	// Provide the infrastructure info so the queued request is drained without lookups
+	e.Sys.Cache().Update(&requests.ASNRequest{
+		Address:     "93.184.216.0",
+		ASN:         15133,
+		Prefix:      "93.184.216.0/24",
+		Description: "EDGECAST-NETBLK-03",
+	})
+	dm.nextInfraInfo()
+
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("the producer remained blocked after the queue was drained")
+	}
+	if d := dm.Depth(); d != 2 {
+		t.Errorf("expected a queue depth of 2 after the producer was released and got %d", d)
+	}
+}
+
+func TestBoundedStoreQueueCancel(t *testing.T) {
+	cfg := config.NewConfig()
+	e := &Enumeration{
+		Config: cfg,
+		Sys:    &systems.SimpleSystem{Cfg: cfg, ASNCache: requests.NewASNCache()},
+		graph:  netmap.NewGraph("memory", "", ""),
+	}
+	dm := &dataManager{
+		enum:   e,
+		queue:  queue.NewQueue(),
+		filter: bf.NewDefaultStableBloomFilter(1000, 0.01),
+		slots:  make(chan struct{}, 1),
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	_ = dm.addrRequest(ctx, &requests.AddrRequest{Address: "93.184.216.1", InScope: true}, nil)
+
+	done := make(chan struct{})
+	go func() {
+		_ = dm.addrRequest(ctx, &requests.AddrRequest{Address: "93.184.216.2", InScope: true}, nil)
+		close(done)
+	}()
+	cancel()
+
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("the producer remained blocked after the context was cancelled")
+	}
+	if d := dm.Depth(); d != 1 {
+		t.Errorf("expected a queue depth of 1 and got %d", d)
+	}
+}
+
+func newTestDataManager(cfg *config.Config, findings *bytes.Buffer) *dataManager {
+	e := &Enumeration{
+		Config: cfg,
+		graph:  netmap.NewGraph("memory", "", ""),
+	}
+	if findings != nil {
+		e.Findings = findings
+	}
+	e.nameSrc = &enumSource{
+		enum:    e,
+		queue:   queue.NewQueue(),
+		filter:  bf.NewDefaultStableBloomFilter(1000, 0.01),
+		done:    make(chan struct{}),
+		release: make(chan struct{}, 10),
+	}
+	return newDataManager(e)
+}
+
+func TestDuplicateRecordsStoredOnce(t *testing.T) {
+	cfg := config.NewConfig()
+	cfg.AddDomain("owasp.org")
+
+	var buf bytes.Buffer
+	dm := newTestDataManager(cfg, &buf)
+	defer func() { <-dm.Stop() }()
+
+	ctx := context.Background()
+	newReq := func(name string, rtype uint16, data string) *requests.DNSRequest {
+		return &requests.DNSRequest{
+			Name:    name,
+			Domain:  "owasp.org",
+			Records: []requests.DNSAnswer{{Name: name, Type: int(rtype), Data: data}},
+		}
+	}
+	for _, req := range []*requests.DNSRequest{
+		newReq("www.owasp.org", dns.TypeA, "93.184.216.34"),
+		newReq("www.owasp.org", dns.TypeA, "93.184.216.34"),
+		newReq("www.owasp.org", dns.TypeA, "93.184.216.34."),
+		newReq("www.owasp.org", dns.TypeA, "93.184.216.35"),
+		newReq("dev.owasp.org", dns.TypeCNAME, "www.owasp.org."),
+		newReq("dev.owasp.org", dns.TypeCNAME, "www.owasp.org"),
+	} {
+		if err := dm.dnsRequest(ctx, req, nil); err != nil {
+			t.Fatalf("failed to store the request: %v", err)
+		}
+	}
+
+	var lines []string
+	scanner := bufio.NewScanner(&buf)
+	for scanner.Scan() {
+		var f Finding
+		if err := json.Unmarshal(scanner.Bytes(), &f); err != nil {
+			t.Fatalf("failed to unmarshal the finding %s: %v", scanner.Text(), err)
+		}
+		lines = append(lines, f.Relation+":"+f.To)
+	}
+	if got := strings.Join(lines, ","); got != "a_record:93.184.216.34,a_record:93.184.216.35,cname_record:www.owasp.org" {
+		t.Errorf("the duplicate records were stored again: %s", got)
+	}
+
+	pairs, err := dm.enum.graph.NamesToAddrs(ctx, time.Time{}, "www.owasp.org")
+	if err != nil || len(pairs) != 2 {
+		t.Errorf("expected the graph to have two addresses for the name and got %v: %v", pairs, err)
+	}
+}
+
+func TestDuplicateRecordsFromSources(t *testing.T) {
+	cfg := config.NewConfig()
+	cfg.AddDomain("owasp.org")
+
+	var buf bytes.Buffer
+	dm := newTestDataManager(cfg, &buf)
+	defer func() { <-dm.Stop() }()
+
+	ctx := context.Background()
+	// The same record is provided by the resolver and two data sources, and again by one of them
+	for _, source := range []string{"", "SecurityTrails", "Robtex", "SecurityTrails"} {
+		req := &requests.DNSRequest{
+			Name:    "www.owasp.org",
+			Domain:  "owasp.org",
+			Records: []requests.DNSAnswer{{Name: "www.owasp.org", Type: int(dns.TypeA), Data: "93.184.216.34", Source: source}},
+		}
+		if err := dm.dnsRequest(ctx, req, nil); err != nil {
+			t.Fatalf("failed to store the request: %v", err)
+		}
+	}
+
+	var sources []string
+	scanner := bufio.NewScanner(&buf)
+	for scanner.Scan() {
+		var f Finding
+		if err := json.Unmarshal(scanner.Bytes(), &f); err != nil {
+			t.Fatalf("failed to unmarshal the finding %s: %v", scanner.Text(), err)
+		}
+		sources = append(sources, f.Source)
+	}
+	if got := strings.Join(sources, ","); got != "DNS,SecurityTrails,Robtex" {
+		t.Errorf("expected a finding from each source of the record and got %s", got)
+	}
+}
+
+func TestFindingSources(t *testing.T) {
+	cfg := config.NewConfig()
+	cfg.AddDomain("owasp.org")
+
+	var buf bytes.Buffer
+	dm := newTestDataManager(cfg, &buf)
+	defer func() { <-dm.Stop() }()
+
+	req := &requests.DNSRequest{
+		Name:   "records.owasp.org",
+		Domain: "owasp.org",
+		Records: []requests.DNSAnswer{
+			{Name: "records.owasp.org", Type: int(dns.TypeA), Data: "93.184.216.34", Source: "SecurityTrails"},
+			{Name: "records.owasp.org", Type: int(dns.TypeAAAA), Data: "2606:2800:220:1::1"},
+		},
+		Tag: "expired_certificate",
+	}
+	if err := dm.dnsRequest(context.Background(), req, nil); err != nil {
+		t.Fatalf("failed to store the request: %v", err)
+	}
+
+	var lines []string
+	scanner := bufio.NewScanner(&buf)
+	for scanner.Scan() {
+		var f Finding
+		if err := json.Unmarshal(scanner.Bytes(), &f); err != nil {
+			t.Fatalf("failed to unmarshal the finding %s: %v", scanner.Text(), err)
+		}
+		lines = append(lines, f.Relation+":"+f.Source+":"+f.Tag)
+	}
+	if got := strings.Join(lines, ","); got != "a_record:SecurityTrails:expired_certificate,aaaa_record:DNS:expired_certificate" {
+		t.Errorf("the findings did not provide the sources and the tag of the records: %s", got)
+	}
+}
+
+func TestStoredRecordsBounded(t *testing.T) {
+	sr := newStoredRecords(2)
+
+	sr.Insert("a")
+	sr.Insert("b")
+	// Checking the first record makes the second record the least recently used
+	if !sr.Has("a") {
+		t.Fatal("the first record was not found")
+	}
+	sr.Insert("c")
+
+	if sr.Has("b") {
+		t.Error("the least recently used record was not removed")
+	}
+	if !sr.Has("a") || !sr.Has("c") {
+		t.Error("the recently used records were removed")
+	}
+	if l := sr.Len(); l != 2 {
+		t.Errorf("expected the set to hold 2 records and it holds %d", l)
+	}
+}
+
+// Stores a session of 50,000 DNS answers, where each of the 500 names is received 100 times.
+func BenchmarkDuplicateRecords(b *testing.B) {
+	cfg := config.NewConfig()
+	cfg.AddDomain("owasp.org")
+
+	var reqs []*requests.DNSRequest
+	for i := 0; i < 50000; i++ {
+		name := fmt.Sprintf("host%d.owasp.org", i%500)
+		reqs = append(reqs, &requests.DNSRequest{
+			Name:   name,
+			Domain: "owasp.org",
+			Records: []requests.DNSAnswer{{
+				Name: name,
+				Type: int(dns.TypeA),
+				Data: fmt.Sprintf("93.184.%d.%d", 216+(i%500)/250, i%250),
+			}},
+		})
+	}
+
+	ctx := context.Background()
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		b.StopTimer()
+		dm := newTestDataManager(cfg, nil)
+		b.StartTimer()
+
+		for _, req := range reqs {
+			_ = dm.dnsRequest(ctx, req, nil)
+		}
+
+		b.StopTimer()
+		b.ReportMetric(float64(dm.stored.Len()), "writes/op")
+		<-dm.Stop()
+		b.StartTimer()
+	}
+}
+
+func TestMalformedNamesDropped(t *testing.T) {
+	cfg := config.NewConfig()
+	cfg.AddDomain("owasp.org")
+
+	tests := []struct {
+		name   string
+		rtype  uint16
+		data   string
+		stored bool
+		// The graph database also rejects some of the names when they are allowed
+		allowed bool
+	}{
+		{"www.owasp.org", dns.TypeA, "93.184.216.34", true, true},
+		{"_dmarc.owasp.org", dns.TypeCNAME, "dmarc.owasp.org", true, true},
+		{".owasp.org", dns.TypeA, "93.184.216.35", false, false},
+		{"dev..owasp.org", dns.TypeA, "93.184.216.36", false, false},
+		{"dev_1.owasp.org", dns.TypeA, "93.184.216.37", true, true},
+		{"dev_.owasp.org", dns.TypeA, "93.184.216.39", false, true},
+		{strings.Repeat("a", 70) + ".owasp.org", dns.TypeA, "93.184.216.38", false, true},
+		{"mail.owasp.org", dns.TypeCNAME, "mail..owasp.org", false, false},
+		{"owasp.org", dns.TypeMX, "mx_1.owasp.org", true, true},
+		{"owasp.org", dns.TypeMX, "mx_.owasp.org", false, true},
+	}
+
+	for _, allow := range []bool{false, true} {
+		var buf bytes.Buffer
+		dm := newTestDataManager(cfg, &buf)
+		dm.enum.AllowMalformedNames = allow
+
+		ctx := context.Background()
+		for _, test := range tests {
+			req := &requests.DNSRequest{
+				Name:    test.name,
+				Domain:  "owasp.org",
+				Records: []requests.DNSAnswer{{Name: test.name, Type: int(test.rtype), Data: test.data}},
+			}
+			if err := dm.dnsRequest(ctx, req, nil); err != nil && !allow {
+				t.Errorf("%s: failed to store the request: %v", test.name, err)
+			}
+		}
+		<-dm.Stop()
+
+		var expected []string
+		for _, test := range tests {
+			if test.stored || (allow && test.allowed) {
+				expected = append(expected, test.name+"->"+test.data)
+			}
+		}
+
+		var got []string
+		scanner := bufio.NewScanner(&buf)
+		for scanner.Scan() {
+			var f Finding
+			if err := json.Unmarshal(scanner.Bytes(), &f); err != nil {
+				t.Fatalf("failed to unmarshal the finding %s: %v", scanner.Text(), err)
+			}
+			got = append(got, f.From+"->"+f.To)
+		}
+		if strings.Join(got, ",") != strings.Join(expected, ",") {
+			t.Errorf("allow %t: expected the findings %v and got %v", allow, expected, got)
+		}
+
+		var dropped int64
+		for _, test := range tests {
+			if !test.stored && !allow {
+				dropped++
+			}
+		}
+		if n := dm.malformed.Load(); n != dropped {
+			t.Errorf("allow %t: expected %d malformed names and got %d", allow, dropped, n)
+		}
+	}
+}
+
+func TestMalformedNamesLogged(t *testing.T) {
+	for _, verbose := range []bool{false, true} {
+		var logs bytes.Buffer
+		cfg := config.NewConfig()
+		cfg.AddDomain("owasp.org")
+		cfg.Log = log.New(&logs, "", 0)
+		cfg.Verbose = verbose
+
+		dm := newTestDataManager(cfg, nil)
+		if dm.validName("dev..owasp.org") {
+			t.Errorf("verbose %t: the malformed name was valid", verbose)
+		}
+		<-dm.Stop()
+
+		if logged := strings.Contains(logs.String(), "Dropped a malformed name"); logged != verbose {
+			t.Errorf("verbose %t: expected the dropped name to be logged only for the verbose output: %s", verbose, logs.String())
+		}
+		if n := dm.malformed.Load(); n != 1 {
+			t.Errorf("verbose %t: expected 1 malformed name and got %d", verbose, n)
+		}
+	}
+}
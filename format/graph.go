@@ -0,0 +1,342 @@
+// Copyright © by Jeff Foley 2017-2023. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+// SPDX-License-Identifier: Apache-2.0
+
+package format
+
+import (
+	"encoding/csv"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/caffix/netmap"
+	"github.com/owasp-amass/asset-db/types"
+	oam "github.com/owasp-amass/open-asset-model"
+	"github.com/owasp-amass/open-asset-model/domain"
+	"github.com/owasp-amass/open-asset-model/network"
+	"golang.org/x/net/publicsuffix"
+)
+
+// GraphFormat identifies the file format used to export the graph.
+type GraphFormat string
+
+// The graph formats supported by WriteGraph.
+const (
+	GraphDOT     GraphFormat = "dot"
+	GraphGraphML GraphFormat = "graphml"
+	GraphMaltego GraphFormat = "maltego"
+)
+
+// GraphAssetTypes are the asset types exported when no node type filter is provided.
+var GraphAssetTypes = []oam.AssetType{oam.FQDN, oam.IPAddress, oam.Netblock, oam.ASN, oam.RIROrg}
+
+// GraphFormatFromPath returns the graph format identified by the file extension.
+func GraphFormatFromPath(path string) (GraphFormat, error) {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".dot", ".gv":
+		return GraphDOT, nil
+	case ".graphml":
+		return GraphGraphML, nil
+	case ".csv":
+		return GraphMaltego, nil
+	}
+	return "", fmt.Errorf("the file extension of %s does not identify a DOT (.dot, .gv), GraphML (.graphml) or Maltego CSV (.csv) file", path)
+}
+
+// ParseAssetTypes returns the asset types named in the list, ignoring the case of the names.
+func ParseAssetTypes(names []string) ([]oam.AssetType, error) {
+	var atypes []oam.AssetType
+
+	for _, name := range names {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+
+		var found bool
+		for _, atype := range GraphAssetTypes {
+			if strings.EqualFold(name, string(atype)) {
+				atypes = append(atypes, atype)
+				found = true
+				break
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("%s is not a supported asset type", name)
+		}
+	}
+	return atypes, nil
+}
+
+type graphNode struct {
+	ID        string
+	Type      oam.AssetType
+	Name      string
+	FirstSeen time.Time
+	LastSeen  time.Time
+}
+
+type graphEdge struct {
+	From     string
+	To       string
+	Relation string
+}
+
+// WriteGraph writes the assets of the requested types, seen since the provided time, and the relations
+// between them to the writer in the graph format. All the asset types are exported when atypes is empty.
+func WriteGraph(w io.Writer, g *netmap.Graph, f GraphFormat, since time.Time, atypes []oam.AssetType) error {
+	nodes, edges := collectGraph(g, since, atypes)
+
+	switch f {
+	case GraphDOT:
+		return writeDOT(w, nodes, edges)
+	case GraphGraphML:
+		return writeGraphML(w, nodes, edges)
+	case GraphMaltego:
+		return writeMaltegoCSV(w, nodes, edges)
+	}
+	return fmt.Errorf("the graph format %q is not supported", f)
+}
+
+func collectGraph(g *netmap.Graph, since time.Time, atypes []oam.AssetType) ([]*graphNode, []*graphEdge) {
+	if len(atypes) == 0 {
+		atypes = GraphAssetTypes
+	}
+
+	var assets []*types.Asset
+	for _, atype := range atypes {
+		if a, err := g.DB.FindByType(atype, since.UTC()); err == nil {
+			assets = append(assets, a...)
+		}
+	}
+
+	var nodes []*graphNode
+	included := make(map[string]bool, len(assets))
+	for _, a := range assets {
+		if included[a.ID] {
+			continue
+		}
+
+		included[a.ID] = true
+		nodes = append(nodes, &graphNode{
+			ID:        a.ID,
+			Type:      a.Asset.AssetType(),
+			Name:      assetName(a.Asset),
+			FirstSeen: a.CreatedAt,
+			LastSeen:  a.LastSeen,
+		})
+	}
+
+	var edges []*graphEdge
+	for _, a := range assets {
+		rels, err := g.DB.OutgoingRelations(a, since.UTC())
+		if err != nil {
+			continue
+		}
+
+		for _, rel := range rels {
+			// Relations to assets filtered from the graph are not exported
+			if rel.ToAsset != nil && included[rel.ToAsset.ID] {
+				edges = append(edges, &graphEdge{
+					From:     a.ID,
+					To:       rel.ToAsset.ID,
+					Relation: rel.Type,
+				})
+			}
+		}
+	}
+	return nodes, edges
+}
+
+func assetName(a oam.Asset) string {
+	switch v := a.(type) {
+	case domain.FQDN:
+		return v.Name
+	case network.IPAddress:
+		return v.Address.String()
+	case network.Netblock:
+		return v.Cidr.String()
+	case network.AutonomousSystem:
+		return strconv.Itoa(v.Number)
+	case network.RIROrganization:
+		return v.Name
+	}
+	return ""
+}
+
+func writeDOT(w io.Writer, nodes []*graphNode, edges []*graphEdge) error {
+	var b strings.Builder
+
+	b.WriteString("digraph amass {\n")
+	for _, n := range nodes {
+		fmt.Fprintf(&b, "  %s [label=%s, type=%s, first_seen=%s, last_seen=%s];\n", dotQuote(n.ID),
+			dotQuote(n.Name), dotQuote(string(n.Type)), dotQuote(graphTime(n.FirstSeen)), dotQuote(graphTime(n.LastSeen)))
+	}
+	for _, e := range edges {
+		fmt.Fprintf(&b, "  %s -> %s [label=%s];\n", dotQuote(e.From), dotQuote(e.To), dotQuote(e.Relation))
+	}
+	b.WriteString("}\n")
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+func dotQuote(s string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(s) + `"`
+}
+
+func graphTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.UTC().Format(time.RFC3339)
+}
+
+type graphML struct {
+	XMLName xml.Name     `xml:"graphml"`
+	XMLNS   string       `xml:"xmlns,attr"`
+	Keys    []graphMLKey `xml:"key"`
+	Graph   graphMLGraph `xml:"graph"`
+}
+
+type graphMLKey struct {
+	ID       string `xml:"id,attr"`
+	For      string `xml:"for,attr"`
+	AttrName string `xml:"attr.name,attr"`
+	AttrType string `xml:"attr.type,attr"`
+}
+
+type graphMLGraph struct {
+	ID          string        `xml:"id,attr"`
+	EdgeDefault string        `xml:"edgedefault,attr"`
+	Nodes       []graphMLNode `xml:"node"`
+	Edges       []graphMLEdge `xml:"edge"`
+}
+
+type graphMLNode struct {
+	ID   string        `xml:"id,attr"`
+	Data []graphMLData `xml:"data"`
+}
+
+type graphMLEdge struct {
+	ID     string        `xml:"id,attr"`
+	Source string        `xml:"source,attr"`
+	Target string        `xml:"target,attr"`
+	Data   []graphMLData `xml:"data"`
+}
+
+type graphMLData struct {
+	Key   string `xml:"key,attr"`
+	Value string `xml:",chardata"`
+}
+
+func writeGraphML(w io.Writer, nodes []*graphNode, edges []*graphEdge) error {
+	doc := &graphML{
+		XMLNS: "http://graphml.graphdrawing.org/xmlns",
+		Keys: []graphMLKey{
+			{ID: "label", For: "node", AttrName: "label", AttrType: "string"},
+			{ID: "type", For: "node", AttrName: "type", AttrType: "string"},
+			{ID: "first_seen", For: "node", AttrName: "first_seen", AttrType: "string"},
+			{ID: "last_seen", For: "node", AttrName: "last_seen", AttrType: "string"},
+			{ID: "relation", For: "edge", AttrName: "label", AttrType: "string"},
+		},
+		Graph: graphMLGraph{ID: "amass", EdgeDefault: "directed"},
+	}
+
+	for _, n := range nodes {
+		doc.Graph.Nodes = append(doc.Graph.Nodes, graphMLNode{
+			ID: "n" + n.ID,
+			Data: []graphMLData{
+				{Key: "label", Value: n.Name},
+				{Key: "type", Value: string(n.Type)},
+				{Key: "first_seen", Value: graphTime(n.FirstSeen)},
+				{Key: "last_seen", Value: graphTime(n.LastSeen)},
+			},
+		})
+	}
+	for i, e := range edges {
+		doc.Graph.Edges = append(doc.Graph.Edges, graphMLEdge{
+			ID:     "e" + strconv.Itoa(i),
+			Source: "n" + e.From,
+			Target: "n" + e.To,
+			Data:   []graphMLData{{Key: "relation", Value: e.Relation}},
+		})
+	}
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(doc); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+// maltegoColumns are the columns of the Maltego CSV file, which has a row for each relation
+// and a row without the target entity for each asset not involved in a relation.
+var maltegoColumns = []string{"Source Entity Type", "Source Value", "Relation", "Target Entity Type", "Target Value"}
+
+// maltegoEntityType returns the Maltego standard entity type representing the asset.
+func maltegoEntityType(n *graphNode) string {
+	switch n.Type {
+	case oam.FQDN:
+		if d, err := publicsuffix.EffectiveTLDPlusOne(n.Name); err == nil && d == n.Name {
+			return "maltego.Domain"
+		}
+		return "maltego.DNSName"
+	case oam.IPAddress:
+		if strings.Contains(n.Name, ":") {
+			return "maltego.IPv6Address"
+		}
+		return "maltego.IPv4Address"
+	case oam.Netblock:
+		return "maltego.Netblock"
+	case oam.ASN:
+		return "maltego.AS"
+	case oam.RIROrg:
+		return "maltego.Organization"
+	}
+	return "maltego.Phrase"
+}
+
+func writeMaltegoCSV(w io.Writer, nodes []*graphNode, edges []*graphEdge) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write(maltegoColumns); err != nil {
+		return err
+	}
+
+	byID := make(map[string]*graphNode, len(nodes))
+	for _, n := range nodes {
+		byID[n.ID] = n
+	}
+
+	linked := make(map[string]bool)
+	for _, e := range edges {
+		from, to := byID[e.From], byID[e.To]
+
+		linked[e.From], linked[e.To] = true, true
+		if err := cw.Write([]string{maltegoEntityType(from), from.Name,
+			e.Relation, maltegoEntityType(to), to.Name}); err != nil {
+			return err
+		}
+	}
+	for _, n := range nodes {
+		if !linked[n.ID] {
+			if err := cw.Write([]string{maltegoEntityType(n), n.Name, "", "", ""}); err != nil {
+				return err
+			}
+		}
+	}
+
+	cw.Flush()
+	return cw.Error()
+}
@@ -0,0 +1,235 @@
+// Copyright © by Jeff Foley 2017-2023. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+// SPDX-License-Identifier: Apache-2.0
+
+package format
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/xml"
+	"regexp"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/caffix/netmap"
+	oam "github.com/owasp-amass/open-asset-model"
+)
+
+func testGraph(t *testing.T) *netmap.Graph {
+	g := netmap.NewGraph("memory", "", "")
+	if g == nil {
+		t.Fatal("failed to create the graph")
+	}
+
+	ctx := context.Background()
+	// The root domain name is also inserted as an FQDN asset
+	if err := g.UpsertA(ctx, "www.owasp.org", "93.184.216.34"); err != nil {
+		t.Fatalf("failed to insert the A record: %v", err)
+	}
+	if err := g.UpsertInfrastructure(ctx, 15133, "EDGECAST-NETBLK-03", "93.184.216.34", "93.184.216.0/24"); err != nil {
+		t.Fatalf("failed to insert the infrastructure: %v", err)
+	}
+	return g
+}
+
+var (
+	dotNodeRE = regexp.MustCompile(`^  "([^"]+)" \[label="([^"]*)", type="([^"]*)", first_seen="([^"]*)", last_seen="([^"]*)"\];$`)
+	dotEdgeRE = regexp.MustCompile(`^  "([^"]+)" -> "([^"]+)" \[label="([^"]*)"\];$`)
+)
+
+// parseDOT returns the nodes as "label (type)" and the edges as "from -relation-> to",
+// after checking that each line is valid and that the edges only reference defined nodes.
+func parseDOT(t *testing.T, data string) ([]string, []string) {
+	scanner := bufio.NewScanner(strings.NewReader(data))
+	if !scanner.Scan() || scanner.Text() != "digraph amass {" {
+		t.Fatalf("the DOT output does not start with the digraph statement: %s", data)
+	}
+
+	var closed bool
+	var nodes, edges []string
+	labels := make(map[string]string)
+	for scanner.Scan() {
+		line := scanner.Text()
+
+		if closed {
+			t.Fatalf("the DOT output continues after the closing brace: %q", line)
+		} else if line == "}" {
+			closed = true
+		} else if m := dotNodeRE.FindStringSubmatch(line); m != nil {
+			if _, err := time.Parse(time.RFC3339, m[4]); err != nil {
+				t.Errorf("the node %s has an invalid first_seen attribute: %v", m[2], err)
+			}
+			labels[m[1]] = m[2]
+			nodes = append(nodes, m[2]+" ("+m[3]+")")
+		} else if m := dotEdgeRE.FindStringSubmatch(line); m != nil {
+			from, ok1 := labels[m[1]]
+			to, ok2 := labels[m[2]]
+			if !ok1 || !ok2 {
+				t.Fatalf("the edge references an undefined node: %q", line)
+			}
+			edges = append(edges, from+" -"+m[3]+"-> "+to)
+		} else {
+			t.Fatalf("the DOT output contains an invalid line: %q", line)
+		}
+	}
+	if !closed {
+		t.Fatal("the DOT output is missing the closing brace")
+	}
+
+	sort.Strings(nodes)
+	sort.Strings(edges)
+	return nodes, edges
+}
+
+func TestWriteGraphDOT(t *testing.T) {
+	g := testGraph(t)
+	defer g.Remove()
+
+	tests := []struct {
+		label  string
+		atypes []oam.AssetType
+		nodes  string
+		edges  string
+	}{
+		{
+			label: "All_Types",
+			nodes: "15133 (ASN),93.184.216.0/24 (Netblock),93.184.216.34 (IPAddress),EDGECAST-NETBLK-03 (RIROrg),owasp.org (FQDN),www.owasp.org (FQDN)",
+			edges: "15133 -announces-> 93.184.216.0/24,15133 -managed_by-> EDGECAST-NETBLK-03," +
+				"93.184.216.0/24 -contains-> 93.184.216.34,www.owasp.org -a_record-> 93.184.216.34",
+		},
+		{
+			label:  "FQDN_And_IPAddress",
+			atypes: []oam.AssetType{oam.FQDN, oam.IPAddress},
+			nodes:  "93.184.216.34 (IPAddress),owasp.org (FQDN),www.owasp.org (FQDN)",
+			edges:  "www.owasp.org -a_record-> 93.184.216.34",
+		},
+		{
+			label:  "ASN_Only",
+			atypes: []oam.AssetType{oam.ASN},
+			nodes:  "15133 (ASN)",
+		},
+	}
+
+	for _, test := range tests {
+		var buf bytes.Buffer
+		if err := WriteGraph(&buf, g, GraphDOT, time.Time{}, test.atypes); err != nil {
+			t.Fatalf("%s: failed to write the graph: %v", test.label, err)
+		}
+
+		nodes, edges := parseDOT(t, buf.String())
+		if got := strings.Join(nodes, ","); got != test.nodes {
+			t.Errorf("%s: expected the nodes %s and got %s", test.label, test.nodes, got)
+		}
+		if got := strings.Join(edges, ","); got != test.edges {
+			t.Errorf("%s: expected the edges %s and got %s", test.label, test.edges, got)
+		}
+	}
+}
+
+func TestWriteGraphML(t *testing.T) {
+	g := testGraph(t)
+	defer g.Remove()
+
+	var buf bytes.Buffer
+	if err := WriteGraph(&buf, g, GraphGraphML, time.Time{}, []oam.AssetType{oam.FQDN, oam.IPAddress}); err != nil {
+		t.Fatalf("failed to write the graph: %v", err)
+	}
+
+	var doc graphML
+	if err := xml.Unmarshal(buf.Bytes(), &doc); err != nil {
+		t.Fatalf("the GraphML output could not be parsed: %v", err)
+	}
+	if doc.Graph.EdgeDefault != "directed" || len(doc.Graph.Nodes) != 3 || len(doc.Graph.Edges) != 1 {
+		t.Fatalf("the GraphML output has the wrong structure: %s", buf.String())
+	}
+
+	labels := make(map[string]string)
+	for _, n := range doc.Graph.Nodes {
+		labels[n.ID] = n.Data[0].Value + " (" + n.Data[1].Value + ")"
+	}
+	e := doc.Graph.Edges[0]
+	if got := labels[e.Source] + " -" + e.Data[0].Value + "-> " + labels[e.Target]; got != "www.owasp.org (FQDN) -a_record-> 93.184.216.34 (IPAddress)" {
+		t.Errorf("the GraphML output has the wrong edge: %s", got)
+	}
+}
+
+func TestWriteMaltegoCSV(t *testing.T) {
+	g := testGraph(t)
+	defer g.Remove()
+
+	var buf bytes.Buffer
+	if err := WriteGraph(&buf, g, GraphMaltego, time.Time{}, nil); err != nil {
+		t.Fatalf("failed to write the graph: %v", err)
+	}
+
+	records, err := csv.NewReader(&buf).ReadAll()
+	if err != nil {
+		t.Fatalf("the Maltego CSV output could not be parsed: %v", err)
+	}
+	if len(records) == 0 || strings.Join(records[0], ",") != "Source Entity Type,Source Value,Relation,Target Entity Type,Target Value" {
+		t.Fatalf("the Maltego CSV output is missing the required columns: %v", records)
+	}
+
+	var rows []string
+	for _, r := range records[1:] {
+		rows = append(rows, strings.Join(r, ","))
+	}
+	sort.Strings(rows)
+
+	expected := []string{
+		"maltego.AS,15133,announces,maltego.Netblock,93.184.216.0/24",
+		"maltego.AS,15133,managed_by,maltego.Organization,EDGECAST-NETBLK-03",
+		"maltego.DNSName,www.owasp.org,a_record,maltego.IPv4Address,93.184.216.34",
+		"maltego.Domain,owasp.org,,,",
+		"maltego.Netblock,93.184.216.0/24,contains,maltego.IPv4Address,93.184.216.34",
+	}
+	if got := strings.Join(rows, "\n"); got != strings.Join(expected, "\n") {
+		t.Errorf("the Maltego CSV output has the wrong rows:\n%s", got)
+	}
+
+	// Relations to filtered assets are not exported
+	buf.Reset()
+	if err := WriteGraph(&buf, g, GraphMaltego, time.Time{}, []oam.AssetType{oam.ASN, oam.Netblock}); err != nil {
+		t.Fatalf("failed to write the filtered graph: %v", err)
+	}
+	if got := strings.TrimSpace(buf.String()); !strings.HasSuffix(got, "\nmaltego.AS,15133,announces,maltego.Netblock,93.184.216.0/24") {
+		t.Errorf("the filtered Maltego CSV output has the wrong rows:\n%s", got)
+	}
+}
+
+func TestGraphFormatFromPath(t *testing.T) {
+	cases := []struct {
+		path     string
+		ok       bool
+		expected GraphFormat
+	}{
+		{"amass.dot", true, GraphDOT},
+		{"/tmp/amass.GV", true, GraphDOT},
+		{"amass.graphml", true, GraphGraphML},
+		{"amass.CSV", true, GraphMaltego},
+		{"amass.json", false, ""},
+		{"amass", false, ""},
+	}
+
+	for _, c := range cases {
+		f, err := GraphFormatFromPath(c.path)
+		if (err == nil) != c.ok || f != c.expected {
+			t.Errorf("%s: expected %q and got %q with the error %v", c.path, c.expected, f, err)
+		}
+	}
+}
+
+func TestParseAssetTypes(t *testing.T) {
+	if atypes, err := ParseAssetTypes([]string{"fqdn", " IPAddress", ""}); err != nil ||
+		len(atypes) != 2 || atypes[0] != oam.FQDN || atypes[1] != oam.IPAddress {
+		t.Errorf("the asset types were not parsed: %v, %v", atypes, err)
+	}
+	if _, err := ParseAssetTypes([]string{"FQDN", "ContactRecord"}); err == nil {
+		t.Error("the unsupported asset type was accepted")
+	}
+}
@@ -6,9 +6,14 @@ package dns
 
 import (
 	"encoding/hex"
+	"errors"
+	"fmt"
 	"net"
 	"regexp"
 	"strings"
+
+	"golang.org/x/net/idna"
+	"golang.org/x/net/publicsuffix"
 )
 
 // SUBRE is a regular expression that will match on all subdomains once the domain is appended.
@@ -56,6 +61,70 @@ func RemoveAsteriskLabel(s string) string {
 	return s[startIndex+2:]
 }
 
+// NormalizeFQDN returns the canonical form of the provided DNS name. Surrounding whitespace,
+// asterisk labels and leading or trailing periods are removed, then the name is lowercased
+// and internationalized labels are converted to their ASCII form. The normalized name is
+// returned with an error when it is not a syntactically valid hostname.
+func NormalizeFQDN(raw string) (string, error) {
+	name := strings.TrimSpace(raw)
+	name = RemoveAsteriskLabel(name)
+	name = strings.Trim(name, ".")
+	name = strings.ToLower(name)
+
+	if ascii, err := idna.Punycode.ToASCII(name); err == nil {
+		name = ascii
+	}
+	return name, ValidateHostname(name)
+}
+
+// ValidateHostname returns an error when the name does not follow the RFC 1123 hostname rules.
+// The labels hold lowercase letters, digits and hyphens, and the hyphens cannot start or end
+// a label. Like SUBRE, underscores are allowed in a label except as the last character, so the
+// service labels of names such as _sip._tcp.owasp.org and the labels such as www_1 are accepted.
+func ValidateHostname(name string) error {
+	if name == "" {
+		return errors.New("the hostname is empty")
+	}
+	if len(name) > 253 {
+		return fmt.Errorf("the hostname %s is longer than 253 characters", name)
+	}
+
+	for _, label := range strings.Split(name, ".") {
+		if label == "" {
+			return fmt.Errorf("the hostname %s has an empty label", name)
+		}
+		if len(label) > 63 {
+			return fmt.Errorf("the hostname %s has a label longer than 63 characters", name)
+		}
+		if label[0] == '-' || label[len(label)-1] == '-' {
+			return fmt.Errorf("the hostname %s has a label starting or ending with a hyphen", name)
+		}
+		if label[len(label)-1] == '_' {
+			return fmt.Errorf("the hostname %s has a label ending with an underscore", name)
+		}
+
+		for _, c := range label {
+			if (c < 'a' || c > 'z') && (c < '0' || c > '9') && c != '-' && c != '_' {
+				return fmt.Errorf("the hostname %s contains the invalid character %q", name, c)
+			}
+		}
+	}
+	return nil
+}
+
+// IsPublicSuffix returns true when the name is an ICANN suffix from the public suffix list, such
+// as com or co.uk, which must not be treated as the apex of an organization. The private suffixes,
+// such as github.io, are accepted, since the organizations that operate them can be enumerated.
+func IsPublicSuffix(name string) bool {
+	name = strings.ToLower(strings.Trim(strings.TrimSpace(name), "."))
+	if name == "" {
+		return false
+	}
+
+	suffix, icann := publicsuffix.PublicSuffix(name)
+	return suffix == name && icann
+}
+
 // ReverseString returns the characters of the argument string in reverse order.
 func ReverseString(s string) string {
 	chrs := []rune(s)
@@ -5,6 +5,7 @@
 package dns
 
 import (
+	"strings"
 	"testing"
 )
 
@@ -90,6 +91,104 @@ func TestRemoveAsteriskLabel(t *testing.T) {
 	}
 }
 
+func TestNormalizeFQDN(t *testing.T) {
+	tests := []struct {
+		name     string
+		raw      string
+		expected string
+		valid    bool
+	}{
+		{"Test 1: Asterisk label", "*.www.owasp.org", "www.owasp.org", true},
+		{"Test 2: Surrounding whitespace", " \twww.owasp.org\n", "www.owasp.org", true},
+		{"Test 3: Whitespace before asterisk", "  *.www.owasp.org", "www.owasp.org", true},
+		{"Test 4: Uppercase", "WWW.OWASP.Org", "www.owasp.org", true},
+		{"Test 5: Trailing dot", "www.owasp.org.", "www.owasp.org", true},
+		{"Test 6: Trailing dots", "www.owasp.org..", "www.owasp.org", true},
+		{"Test 7: Internationalized labels", "Bücher.owasp.org", "xn--bcher-kva.owasp.org", true},
+		{"Test 8: Punycode", "XN--BCHER-KVA.owasp.org", "xn--bcher-kva.owasp.org", true},
+		{"Test 9: All transformations", " *.WWW.Bücher.OWASP.org. ", "www.xn--bcher-kva.owasp.org", true},
+		{"Test 10: Underscores", "_dmarc.owasp.org", "_dmarc.owasp.org", true},
+		{"Test 11: Longest label", strings.Repeat("a", 63) + ".owasp.org", strings.Repeat("a", 63) + ".owasp.org", true},
+		{"Test 12: Overlong label", strings.Repeat("a", 64) + ".owasp.org", strings.Repeat("a", 64) + ".owasp.org", false},
+		{"Test 13: Overlong name", strings.Repeat("a.", 125) + "owasp.org", strings.Repeat("a.", 125) + "owasp.org", false},
+		{"Test 14: Empty label", "www..owasp.org", "www..owasp.org", false},
+		{"Test 15: Hyphen at label start", "-www.owasp.org", "-www.owasp.org", false},
+		{"Test 16: Invalid character", "www.ow$sp.org", "www.ow$sp.org", false},
+		{"Test 17: Inner whitespace", "www owasp.org", "www owasp.org", false},
+		{"Test 18: Empty string", "", "", false},
+		{"Test 19: Service labels", "_sip._tcp.owasp.org", "_sip._tcp.owasp.org", true},
+		{"Test 20: Inner underscore", "www_1.owasp.org", "www_1.owasp.org", true},
+		{"Test 21: Underscore at label end", "www_.owasp.org", "www_.owasp.org", false},
+	}
+	for _, tt := range tests {
+		s, err := NormalizeFQDN(tt.raw)
+		if s != tt.expected {
+			t.Errorf("Error Event %s: was expecting %s, got %s", tt.name, tt.expected, s)
+		}
+		if valid := err == nil; valid != tt.valid {
+			t.Errorf("Error Event %s: was expecting valid to be %t, got the error %v", tt.name, tt.valid, err)
+		}
+	}
+}
+
+func TestValidateHostname(t *testing.T) {
+	tests := []struct {
+		name  string
+		valid bool
+	}{
+		{"owasp.org", true},
+		{"www.owasp.org", true},
+		{"www-1.owasp.org", true},
+		{"_dmarc.owasp.org", true},
+		{"_spf.owasp.org", true},
+		{"_sip._tcp.owasp.org", true},
+		{strings.Repeat("a", 63) + ".owasp.org", true},
+		{".owasp.org", false},
+		{"owasp.org.", false},
+		{"www..owasp.org", false},
+		{"www_1.owasp.org", true},
+		{"a_b.c_d.owasp.org", true},
+		{"__dmarc.owasp.org", true},
+		{"www_.owasp.org", false},
+		{"_.owasp.org", false},
+		{strings.Repeat("a", 70) + ".owasp.org", false},
+		{strings.Repeat("a.", 125) + "owasp.org", false},
+		{"www-.owasp.org", false},
+		{"WWW.owasp.org", false},
+		{"*.owasp.org", false},
+		{"", false},
+	}
+	for _, test := range tests {
+		if err := ValidateHostname(test.name); (err == nil) != test.valid {
+			t.Errorf("%q: expected valid to be %t and got the error %v", test.name, test.valid, err)
+		}
+	}
+}
+
+func TestIsPublicSuffix(t *testing.T) {
+	tests := []struct {
+		Value    string
+		Expected bool
+	}{
+		{"com", true},
+		{"co.uk", true},
+		{"CO.UK.", true},
+		{"github.io", false},
+		{"owasp.github.io", false},
+		{"owasp.org", false},
+		{"bbc.co.uk", false},
+		{"www.owasp.org", false},
+		{"internal", false},
+		{"", false},
+	}
+
+	for _, test := range tests {
+		if r := IsPublicSuffix(test.Value); r != test.Expected {
+			t.Errorf("%s caused %t to be returned instead of %t", test.Value, r, test.Expected)
+		}
+	}
+}
+
 func TestReverseString(t *testing.T) {
 	tests := []struct {
 		Value    string
@@ -0,0 +1,196 @@
+// Copyright © by Jeff Foley 2017-2023. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+// SPDX-License-Identifier: Apache-2.0
+
+package http
+
+import (
+	"container/list"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+)
+
+// ResponseCache is implemented by the stores of responses used for conditional requests.
+// Implementations must be safe for concurrent use and can be memory or disk backed.
+type ResponseCache interface {
+	// Get returns the response cached for the key and true when found.
+	Get(key string) (*CachedResponse, bool)
+	// Set stores the response for the key.
+	Set(key string, resp *CachedResponse)
+}
+
+// CachedResponse is a response stored with the validators used in conditional requests.
+type CachedResponse struct {
+	ETag         string
+	LastModified string
+	Response     *Response
+}
+
+// Cache is used by RequestWebPage to send conditional GET requests and return the cached
+// response when the server replies with 304 Not Modified. Caching is disabled when nil.
+var Cache ResponseCache
+
+// MaxCacheBytes is the limit placed on the total size of the response bodies kept by the caches
+// returned from NewMemoryCache. The responses larger than the limit are not cached.
+var MaxCacheBytes int64 = 64 * 1024 * 1024 // 64MB
+
+type cacheEntry struct {
+	key     string
+	expires time.Time
+	resp    *CachedResponse
+	bytes   int64
+}
+
+type memoryCache struct {
+	sync.Mutex
+	size     int
+	maxBytes int64
+	bytes    int64
+	ttl      time.Duration
+	order    *list.List
+	entries  map[string]*list.Element
+}
+
+// NewMemoryCache returns a ResponseCache that keeps up to size responses in memory for the ttl,
+// and no more than MaxCacheBytes of response bodies. The least recently used responses are
+// removed when the cache is full.
+func NewMemoryCache(size int, ttl time.Duration) ResponseCache {
+	return &memoryCache{
+		size:     size,
+		maxBytes: MaxCacheBytes,
+		ttl:      ttl,
+		order:    list.New(),
+		entries:  make(map[string]*list.Element),
+	}
+}
+
+// Get implements the ResponseCache interface.
+func (mc *memoryCache) Get(key string) (*CachedResponse, bool) {
+	mc.Lock()
+	defer mc.Unlock()
+
+	e, found := mc.entries[key]
+	if !found {
+		return nil, false
+	}
+
+	entry := e.Value.(*cacheEntry)
+	if mc.ttl > 0 && time.Now().After(entry.expires) {
+		mc.remove(e)
+		return nil, false
+	}
+	mc.order.MoveToFront(e)
+	return entry.resp, true
+}
+
+// Set implements the ResponseCache interface.
+func (mc *memoryCache) Set(key string, resp *CachedResponse) {
+	mc.Lock()
+	defer mc.Unlock()
+
+	// The previous response is removed, since the new one may not fit in the cache
+	if e, found := mc.entries[key]; found {
+		mc.remove(e)
+	}
+
+	bytes := responseBytes(resp)
+	if mc.maxBytes > 0 && bytes > mc.maxBytes {
+		return
+	}
+
+	entry := &cacheEntry{key: key, expires: time.Now().Add(mc.ttl), resp: resp, bytes: bytes}
+	mc.entries[key] = mc.order.PushFront(entry)
+	mc.bytes += bytes
+	for (mc.size > 0 && mc.order.Len() > mc.size) || (mc.maxBytes > 0 && mc.bytes > mc.maxBytes) {
+		mc.remove(mc.order.Back())
+	}
+}
+
+func (mc *memoryCache) remove(e *list.Element) {
+	entry := e.Value.(*cacheEntry)
+
+	mc.order.Remove(e)
+	delete(mc.entries, entry.key)
+	mc.bytes -= entry.bytes
+}
+
+// Returns the number of bytes held by the body of the cached response.
+func responseBytes(resp *CachedResponse) int64 {
+	if resp == nil || resp.Response == nil {
+		return 0
+	}
+	return int64(len(resp.Response.Body))
+}
+
+// cacheKey identifies the response by the URL and the request headers, so the responses
+// requested with different API keys or authorization headers are never shared.
+func cacheKey(req *http.Request) string {
+	names := make([]string, 0, len(req.Header))
+	for name := range req.Header {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	h := sha256.New()
+	_, _ = io.WriteString(h, req.URL.String())
+	for _, name := range names {
+		for _, v := range req.Header[name] {
+			_, _ = io.WriteString(h, "\n"+name+": "+v)
+		}
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// Adds the validators from the cached response to the request and returns the cached entry.
+func addConditionalHeaders(req *http.Request, key string) *CachedResponse {
+	if Cache == nil || req.Method != "GET" {
+		return nil
+	}
+
+	entry, found := Cache.Get(key)
+	if !found || entry == nil || entry.Response == nil {
+		return nil
+	}
+
+	if entry.ETag != "" && req.Header.Get("If-None-Match") == "" {
+		req.Header.Set("If-None-Match", entry.ETag)
+	}
+	if entry.LastModified != "" && req.Header.Get("If-Modified-Since") == "" {
+		req.Header.Set("If-Modified-Since", entry.LastModified)
+	}
+	return entry
+}
+
+// Stores successful responses that provide validators for later conditional requests.
+func cacheResponse(req *http.Request, key string, resp *Response) {
+	if Cache == nil || req.Method != "GET" || resp.StatusCode != http.StatusOK {
+		return
+	}
+
+	etag := resp.Header["Etag"]
+	modified := resp.Header["Last-Modified"]
+	if etag == "" && modified == "" {
+		return
+	}
+
+	Cache.Set(key, &CachedResponse{
+		ETag:         etag,
+		LastModified: modified,
+		Response:     copyResponse(resp),
+	})
+}
+
+func copyResponse(resp *Response) *Response {
+	c := *resp
+
+	c.Header = make(Header, len(resp.Header))
+	for k, v := range resp.Header {
+		c.Header[k] = v
+	}
+	return &c
+}
@@ -0,0 +1,182 @@
+// Copyright © by Jeff Foley 2017-2023. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+// SPDX-License-Identifier: Apache-2.0
+
+package http
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestConditionalRequests(t *testing.T) {
+	saved := Cache
+	Cache = NewMemoryCache(10, time.Hour)
+	defer func() { Cache = saved }()
+
+	var lock sync.Mutex
+	var matches []string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		lock.Lock()
+		matches = append(matches, r.Header.Get("If-None-Match"))
+		lock.Unlock()
+
+		if r.Header.Get("If-None-Match") == `"v1"` {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("ETag", `"v1"`)
+		_, _ = w.Write([]byte("cached body"))
+	}))
+	defer ts.Close()
+
+	for i := 0; i < 2; i++ {
+		resp, err := RequestWebPage(context.TODO(), &Request{URL: ts.URL})
+		if err != nil {
+			t.Fatalf("Request %d failed: %v", i+1, err)
+		}
+		if resp.StatusCode != http.StatusOK || resp.Body != "cached body" {
+			t.Errorf("Request %d returned status %d and body %q", i+1, resp.StatusCode, resp.Body)
+		}
+	}
+
+	lock.Lock()
+	defer lock.Unlock()
+	if len(matches) != 2 || matches[0] != "" || matches[1] != `"v1"` {
+		t.Errorf("The conditional request headers were not sent as expected: %v", matches)
+	}
+}
+
+func TestConditionalRequestsDisabled(t *testing.T) {
+	saved := Cache
+	Cache = nil
+	defer func() { Cache = saved }()
+
+	var lock sync.Mutex
+	var conditional bool
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		lock.Lock()
+		if r.Header.Get("If-None-Match") != "" {
+			conditional = true
+		}
+		lock.Unlock()
+
+		w.Header().Set("ETag", `"v1"`)
+		_, _ = w.Write([]byte("body"))
+	}))
+	defer ts.Close()
+
+	for i := 0; i < 2; i++ {
+		if _, err := RequestWebPage(context.TODO(), &Request{URL: ts.URL}); err != nil {
+			t.Fatalf("Request %d failed: %v", i+1, err)
+		}
+	}
+
+	lock.Lock()
+	defer lock.Unlock()
+	if conditional {
+		t.Error("A conditional request was sent without a cache")
+	}
+}
+
+func TestConditionalRequestsPerKey(t *testing.T) {
+	saved := Cache
+	Cache = NewMemoryCache(10, time.Hour)
+	defer func() { Cache = saved }()
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key := r.Header.Get("X-Key")
+		if r.Header.Get("If-None-Match") == `"`+key+`"` {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("ETag", `"`+key+`"`)
+		_, _ = w.Write([]byte("body for " + key))
+	}))
+	defer ts.Close()
+
+	for i := 0; i < 2; i++ {
+		for _, key := range []string{"first", "second"} {
+			resp, err := RequestWebPage(context.TODO(), &Request{URL: ts.URL, Header: Header{"X-Key": key}})
+			if err != nil {
+				t.Fatalf("Request %d with the %s key failed: %v", i+1, key, err)
+			}
+			if resp.Body != "body for "+key {
+				t.Errorf("Request %d with the %s key returned the body %q", i+1, key, resp.Body)
+			}
+		}
+	}
+}
+
+func TestMemoryCacheBounded(t *testing.T) {
+	c := NewMemoryCache(2, time.Hour)
+
+	c.Set("a", &CachedResponse{ETag: "a"})
+	c.Set("b", &CachedResponse{ETag: "b"})
+	// Using the first entry makes the second entry the least recently used
+	if _, found := c.Get("a"); !found {
+		t.Fatal("The first entry was not found")
+	}
+	c.Set("c", &CachedResponse{ETag: "c"})
+
+	if _, found := c.Get("b"); found {
+		t.Error("The least recently used entry was not removed")
+	}
+	for _, key := range []string{"a", "c"} {
+		if _, found := c.Get(key); !found {
+			t.Errorf("The entry %s was removed", key)
+		}
+	}
+}
+
+func TestMemoryCacheBoundedBytes(t *testing.T) {
+	saved := MaxCacheBytes
+	MaxCacheBytes = 10
+	defer func() { MaxCacheBytes = saved }()
+
+	c := NewMemoryCache(10, time.Hour)
+	body := func(n int) *CachedResponse {
+		return &CachedResponse{Response: &Response{Body: strings.Repeat("x", n)}}
+	}
+
+	c.Set("a", body(4))
+	c.Set("b", body(4))
+	// The third body does not fit along with the others, so the least recently used is removed
+	c.Set("c", body(4))
+	if _, found := c.Get("a"); found {
+		t.Error("The least recently used entry was not removed")
+	}
+	for _, key := range []string{"b", "c"} {
+		if _, found := c.Get(key); !found {
+			t.Errorf("The entry %s was removed", key)
+		}
+	}
+
+	c.Set("d", body(11))
+	if _, found := c.Get("d"); found {
+		t.Error("The response larger than the limit was cached")
+	}
+	// Replacing an entry with a response larger than the limit removes the previous response
+	c.Set("b", body(11))
+	if _, found := c.Get("b"); found {
+		t.Error("The previous response of the entry was kept")
+	}
+	if _, found := c.Get("c"); !found {
+		t.Error("The entry c was removed by the responses that were not cached")
+	}
+}
+
+func TestMemoryCacheExpired(t *testing.T) {
+	c := NewMemoryCache(2, time.Millisecond)
+
+	c.Set("a", &CachedResponse{ETag: "a"})
+	time.Sleep(5 * time.Millisecond)
+	if _, found := c.Get("a"); found {
+		t.Error("The expired entry was returned")
+	}
+}
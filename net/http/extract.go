@@ -0,0 +1,112 @@
+// Copyright © by Jeff Foley 2017-2023. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+// SPDX-License-Identifier: Apache-2.0
+
+package http
+
+import (
+	"net"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/owasp-amass/amass/v4/net/dns"
+)
+
+// ExtractedAssets contains the deduplicated and normalized values found in scraped content.
+type ExtractedAssets struct {
+	Names  []string
+	Emails []string
+	Addrs  []string
+	URLs   []string
+}
+
+var (
+	percentRE     = regexp.MustCompile(`%[0-9a-fA-F]{2}`)
+	unicodeRE     = regexp.MustCompile(`\\u[0-9a-fA-F]{4}`)
+	obfuscatedAt  = regexp.MustCompile(`(?i)\s*[\[\(\{]\s*at\s*[\]\)\}]\s*`)
+	obfuscatedDot = regexp.MustCompile(`(?i)\s*[\[\(\{]\s*(dot|\.)\s*[\]\)\}]\s*`)
+	emailRE       = regexp.MustCompile(`[a-zA-Z0-9._%+\-]+@(` + dns.AnySubdomainRegexString() + `)`)
+	urlRE         = regexp.MustCompile(`(?i)https?://[^\s"'<>\\` + "`" + `]+`)
+	ipv4RE        = regexp.MustCompile(`[0-9]{1,3}(?:\.[0-9]{1,3})+`)
+	ipv6RE        = regexp.MustCompile(`(?i)[0-9a-f]{0,4}:[0-9a-f:]*:[0-9a-f]{0,4}(?:(?:\.[0-9]{1,3}){3})?`)
+)
+
+// ExtractAssets returns the DNS names, email addresses, IP addresses and URLs found in the content.
+// URL-encoded text, JSON escape sequences and obfuscated email addresses, such as name[at]domain,
+// are decoded before the values are extracted. The values are provided in the order they appear.
+func ExtractAssets(body string) *ExtractedAssets {
+	text := normalizeContent(body)
+	assets := new(ExtractedAssets)
+
+	seen := make(map[string]struct{})
+	for _, m := range urlRE.FindAllString(text, -1) {
+		if u := normalizeURL(m); u != "" {
+			assets.URLs = appendUnique(assets.URLs, seen, u)
+		}
+	}
+
+	seen = make(map[string]struct{})
+	// The local parts of email addresses would otherwise be extracted as names
+	text = emailRE.ReplaceAllStringFunc(text, func(m string) string {
+		assets.Emails = appendUnique(assets.Emails, seen, strings.ToLower(m))
+		return emailRE.FindStringSubmatch(m)[1]
+	})
+
+	seen = make(map[string]struct{})
+	for _, m := range subRE.FindAllString(text, -1) {
+		if name := strings.Trim(strings.ToLower(m), "-."); name != "" {
+			assets.Names = appendUnique(assets.Names, seen, name)
+		}
+	}
+
+	seen = make(map[string]struct{})
+	// Sequences with more than four octets, such as version numbers, are not addresses
+	for _, m := range ipv4RE.FindAllString(text, -1) {
+		if ip := net.ParseIP(m); ip != nil {
+			assets.Addrs = appendUnique(assets.Addrs, seen, ip.String())
+		}
+	}
+	for _, m := range ipv6RE.FindAllString(text, -1) {
+		if ip := net.ParseIP(m); ip != nil && ip.To4() == nil && !ip.IsUnspecified() {
+			assets.Addrs = appendUnique(assets.Addrs, seen, ip.String())
+		}
+	}
+	return assets
+}
+
+func normalizeContent(body string) string {
+	text := percentRE.ReplaceAllStringFunc(body, func(m string) string {
+		b, _ := strconv.ParseUint(m[1:], 16, 8)
+		return string([]byte{byte(b)})
+	})
+	text = unicodeRE.ReplaceAllStringFunc(text, func(m string) string {
+		r, _ := strconv.ParseUint(m[2:], 16, 16)
+		return string(rune(r))
+	})
+	text = strings.ReplaceAll(text, `\/`, "/")
+	text = obfuscatedAt.ReplaceAllString(text, "@")
+	return obfuscatedDot.ReplaceAllString(text, ".")
+}
+
+func normalizeURL(s string) string {
+	u, err := url.Parse(strings.TrimRight(s, ".,;:!?)]}"))
+	if err != nil || u.Hostname() == "" {
+		return ""
+	}
+
+	u.Scheme = strings.ToLower(u.Scheme)
+	u.Host = strings.ToLower(u.Host)
+	u.Fragment = ""
+	return u.String()
+}
+
+func appendUnique(list []string, seen map[string]struct{}, s string) []string {
+	if _, found := seen[s]; found {
+		return list
+	}
+
+	seen[s] = struct{}{}
+	return append(list, s)
+}
@@ -0,0 +1,78 @@
+// Copyright © by Jeff Foley 2017-2023. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+// SPDX-License-Identifier: Apache-2.0
+
+package http
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestExtractAssets(t *testing.T) {
+	tests := []struct {
+		name   string
+		body   string
+		names  string
+		emails string
+		addrs  string
+		urls   string
+	}{
+		{
+			name:  "Plain text",
+			body:  "Visit WWW.OWASP.org or www.owasp.org, hosted at 93.184.216.34 and 93.184.216.34.",
+			names: "www.owasp.org",
+			addrs: "93.184.216.34",
+		},
+		{
+			name:   "Obfuscated emails",
+			body:   "Contact admin[at]owasp[dot]org or security (AT) owasp.org, not jeff {at} owasp",
+			names:  "owasp.org",
+			emails: "admin@owasp.org,security@owasp.org",
+		},
+		{
+			name:  "Bracketed addresses",
+			body:  "Reached via http://[2001:DB8::1]:8080/status and [2001:db8:0:0:0:0:0:1], also 10.0.0[.]1",
+			addrs: "10.0.0.1,2001:db8::1",
+			urls:  "http://[2001:db8::1]:8080/status",
+		},
+		{
+			name:   "JSON",
+			body:   `{"host":"api.owasp.org","link":"https:\/\/dev.owasp.org\/path","mail":"info@owasp.org","ip":"2001:db8::2"}`,
+			names:  "api.owasp.org,dev.owasp.org,owasp.org",
+			emails: "info@owasp.org",
+			addrs:  "2001:db8::2",
+			urls:   "https://dev.owasp.org/path",
+		},
+		{
+			name:   "URL-encoded",
+			body:   "q=site%3Amail.owasp.org%20contact%3Dweb%40owasp.org&next=https%3A%2F%2Fdocs.owasp.org%2Fa%3Fb%3Dc",
+			names:  "mail.owasp.org,owasp.org,docs.owasp.org",
+			emails: "web@owasp.org",
+			urls:   "https://docs.owasp.org/a?b=c",
+		},
+		{
+			name: "No assets",
+			body: "version 1.2.3.4.5 released at 12:30:45 by 00:1a:2b:3c:4d:5e",
+		},
+	}
+
+	for _, test := range tests {
+		a := ExtractAssets(test.body)
+
+		for _, c := range []struct {
+			kind     string
+			got      []string
+			expected string
+		}{
+			{"names", a.Names, test.names},
+			{"emails", a.Emails, test.emails},
+			{"addresses", a.Addrs, test.addrs},
+			{"URLs", a.URLs, test.urls},
+		} {
+			if got := strings.Join(c.got, ","); got != c.expected {
+				t.Errorf("%s: expected the %s %q and got %q", test.name, c.kind, c.expected, got)
+			}
+		}
+	}
+}
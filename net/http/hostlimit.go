@@ -0,0 +1,80 @@
+// Copyright © by Jeff Foley 2017-2023. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+// SPDX-License-Identifier: Apache-2.0
+
+package http
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+)
+
+// HostLimiters keeps the limiters shared by the requests sent to the same host during an enumeration.
+type HostLimiters struct {
+	sync.Mutex
+	hosts map[string]*HostLimiter
+}
+
+// HostLimiter spaces the requests sent to a host.
+type HostLimiter struct {
+	sync.Mutex
+	next time.Time
+}
+
+// NewHostLimiters returns an empty set of host limiters.
+func NewHostLimiters() *HostLimiters {
+	return &HostLimiters{hosts: make(map[string]*HostLimiter)}
+}
+
+// Limiter returns the limiter shared by the requests sent to the host, or nil when no host was provided.
+func (h *HostLimiters) Limiter(host string) *HostLimiter {
+	host = strings.ToLower(strings.TrimSuffix(host, "."))
+	if host == "" {
+		return nil
+	}
+
+	h.Lock()
+	defer h.Unlock()
+
+	l, found := h.hosts[host]
+	if !found {
+		l = new(HostLimiter)
+		h.hosts[host] = l
+	}
+	return l
+}
+
+// Take blocks until the next request can be sent to the host, and keeps the following request from
+// being sent before the interval passes. The error of the context is returned when it expires first,
+// and the time reserved by the aborted take is given back.
+func (l *HostLimiter) Take(ctx context.Context, interval time.Duration) error {
+	l.Lock()
+	slot := time.Now()
+	if l.next.After(slot) {
+		slot = l.next
+	}
+	l.next = slot.Add(interval)
+	l.Unlock()
+
+	wait := time.Until(slot)
+	if wait <= 0 {
+		return nil
+	}
+
+	t := time.NewTimer(wait)
+	defer t.Stop()
+
+	select {
+	case <-ctx.Done():
+		l.Lock()
+		if l.next.Equal(slot.Add(interval)) {
+			l.next = slot
+		}
+		l.Unlock()
+		return ctx.Err()
+	case <-t.C:
+	}
+	return nil
+}
@@ -0,0 +1,50 @@
+// Copyright © by Jeff Foley 2017-2023. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+// SPDX-License-Identifier: Apache-2.0
+
+package http
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestHostLimitersShared(t *testing.T) {
+	hosts := NewHostLimiters()
+	if hosts.Limiter("") != nil {
+		t.Error("a limiter was returned without a host")
+	}
+	if hosts.Limiter("crt.sh") != hosts.Limiter("CRT.sh.") {
+		t.Error("the limiter of the host was not shared")
+	}
+	if hosts.Limiter("crt.sh") == hosts.Limiter("api.securitytrails.com") {
+		t.Error("the limiter was shared by different hosts")
+	}
+	if NewHostLimiters().Limiter("crt.sh") == hosts.Limiter("crt.sh") {
+		t.Error("the limiter was shared by different enumerations")
+	}
+}
+
+func TestHostLimiterTakeCancelled(t *testing.T) {
+	l := new(HostLimiter)
+	if err := l.Take(context.Background(), time.Hour); err != nil {
+		t.Fatalf("the first take failed: %v", err)
+	}
+	next := l.next
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	if err := l.Take(ctx, time.Hour); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected the error of the context and got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("the take returned %v after the context expired", elapsed)
+	}
+	if !l.next.Equal(next) {
+		t.Error("the time reserved by the cancelled take was not given back")
+	}
+}
@@ -50,6 +50,13 @@ var (
 	nameStripRE = regexp.MustCompile(`^(u[0-9a-f]{4}|20|22|25|27|2b|2f|3d|3a|40)`)
 )
 
+// MaxBodyBytes is the default limit placed on the size of response bodies read by RequestWebPage.
+// The enum command sets it from the max_body_size option or the -max-body-size flag.
+var MaxBodyBytes int64 = 50 * 1024 * 1024 // 50MB
+
+// ErrBodyTooLarge is returned when a response body exceeds the maximum number of bytes allowed.
+var ErrBodyTooLarge = errors.New("the HTTP response body exceeded the maximum size allowed")
+
 // DefaultClient is the same HTTP client used by the package methods.
 var DefaultClient *http.Client
 
@@ -63,6 +70,8 @@ type Request struct {
 	Header Header
 	Body   string
 	Auth   *BasicAuth
+	// MaxBodyBytes overrides the package default limit when greater than zero
+	MaxBodyBytes int64
 }
 
 // Response represents the HTTP response in the Amass preferred format.
@@ -200,6 +209,99 @@ func RequestWebPage(ctx context.Context, r *Request) (*Response, error) {
 		return nil, errors.New("failed to provide a valid Amass HTTP request")
 	}
 
+	req, err := newHTTPRequest(ctx, r)
+	if err != nil {
+		return nil, err
+	}
+	key := cacheKey(req)
+	cached := addConditionalHeaders(req, key)
+
+	resp, err := DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if cached != nil && resp.StatusCode == http.StatusNotModified {
+		_ = resp.Body.Close()
+		return copyResponse(cached.Response), nil
+	}
+
+	max := MaxBodyBytes
+	if r.MaxBodyBytes > 0 {
+		max = r.MaxBodyBytes
+	}
+
+	body, err := readLimitedBody(throttle(ctx, resp.Body), max)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", r.URL, err)
+	}
+	resp.Body = io.NopCloser(strings.NewReader(body))
+
+	aresp := RespToAmassResponse(resp)
+	cacheResponse(req, key, aresp)
+	return aresp, nil
+}
+
+// OpenWebPage sends the request and returns the response with the body left unread, so large bodies
+// can be processed as they arrive instead of being held in memory. The caller must close the body.
+func OpenWebPage(ctx context.Context, r *Request) (*Response, io.ReadCloser, error) {
+	if r == nil {
+		return nil, nil, errors.New("failed to provide a valid Amass HTTP request")
+	}
+
+	req, err := newHTTPRequest(ctx, r)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	resp, err := DefaultClient.Do(req)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	body := throttle(ctx, resp.Body)
+	resp.Body = nil
+	return RespToAmassResponse(resp), body, nil
+}
+
+// DownloadFile streams the response body for the provided request into w, without holding it in memory.
+// Responses without a successful status code and bodies larger than max bytes return an error.
+func DownloadFile(ctx context.Context, r *Request, w io.Writer, max int64) error {
+	if r == nil {
+		return errors.New("failed to provide a valid Amass HTTP request")
+	}
+
+	req, err := newHTTPRequest(ctx, r)
+	if err != nil {
+		return err
+	}
+
+	resp, err := DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("%s: the request returned with status: %s", r.URL, resp.Status)
+	}
+
+	body := io.Reader(throttle(ctx, resp.Body))
+	if max > 0 {
+		body = io.LimitReader(body, max+1)
+	}
+
+	n, err := io.Copy(w, body)
+	if err != nil {
+		return fmt.Errorf("%s: %w", r.URL, err)
+	}
+	if max > 0 && n > max {
+		return fmt.Errorf("%s: %w", r.URL, ErrBodyTooLarge)
+	}
+	return nil
+}
+
+func newHTTPRequest(ctx context.Context, r *Request) (*http.Request, error) {
 	if r.Method == "" {
 		r.Method = "GET"
 	} else if r.Method != "GET" && r.Method != "POST" {
@@ -222,12 +324,26 @@ func RequestWebPage(ctx context.Context, r *Request) (*Response, error) {
 	for k, v := range r.Header {
 		req.Header.Set(k, v)
 	}
+	return req, nil
+}
 
-	resp, err := DefaultClient.Do(req)
+// Reads no more than max bytes from the body and returns ErrBodyTooLarge when the limit is exceeded.
+func readLimitedBody(body io.ReadCloser, max int64) (string, error) {
+	defer body.Close()
+
+	if max <= 0 {
+		b, err := io.ReadAll(body)
+		return string(b), err
+	}
+
+	b, err := io.ReadAll(io.LimitReader(body, max+1))
 	if err != nil {
-		return nil, err
+		return "", err
+	}
+	if int64(len(b)) > max {
+		return "", ErrBodyTooLarge
 	}
-	return RespToAmassResponse(resp), nil
+	return string(b), nil
 }
 
 // Crawl will spider the web page at the URL argument looking while staying within the scope provided.
@@ -393,14 +509,12 @@ func NamesFromCert(cert *x509.Certificate) []string {
 	subdomains := stringset.New()
 	defer subdomains.Close()
 	// Add the subject common name to the list of subdomain names
-	commonName := dns.RemoveAsteriskLabel(cn)
-	if commonName != "" {
+	if commonName, err := dns.NormalizeFQDN(cn); err == nil {
 		subdomains.Insert(commonName)
 	}
 	// Add the cert DNS names to the list of subdomain names
 	for _, name := range cert.DNSNames {
-		n := dns.RemoveAsteriskLabel(name)
-		if n != "" {
+		if n, err := dns.NormalizeFQDN(name); err == nil {
 			subdomains.Insert(n)
 		}
 	}
@@ -5,7 +5,9 @@
 package http
 
 import (
+	"bytes"
 	"context"
+	"errors"
 	"fmt"
 	"io"
 	"net"
@@ -147,6 +149,74 @@ func TestRequestWebPage(t *testing.T) {
 	}
 }
 
+func TestRequestWebPageMaxBodyBytes(t *testing.T) {
+	chunk := strings.Repeat("A", 1024)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Stream well beyond the limits used below
+		for i := 0; i < 64; i++ {
+			if _, err := fmt.Fprint(w, chunk); err != nil {
+				return
+			}
+		}
+	}))
+	defer ts.Close()
+
+	resp, err := RequestWebPage(context.TODO(), &Request{
+		URL:          ts.URL,
+		MaxBodyBytes: 16 * 1024,
+	})
+	if !errors.Is(err, ErrBodyTooLarge) || resp != nil {
+		t.Errorf("Failed to detect the response body exceeding the limit: %v", err)
+	}
+
+	resp, err = RequestWebPage(context.TODO(), &Request{
+		URL:          ts.URL,
+		MaxBodyBytes: 64 * 1024,
+	})
+	if err != nil || len(resp.Body) != 64*1024 {
+		t.Errorf("Failed to read a response body within the limit: %v", err)
+	}
+
+	saved := MaxBodyBytes
+	MaxBodyBytes = 1024
+	defer func() { MaxBodyBytes = saved }()
+
+	if _, err := RequestWebPage(context.TODO(), &Request{URL: ts.URL}); !errors.Is(err, ErrBodyTooLarge) {
+		t.Errorf("Failed to enforce the default response body limit: %v", err)
+	}
+}
+
+func TestDownloadFile(t *testing.T) {
+	chunk := strings.Repeat("A", 1024)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/missing" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		for i := 0; i < 64; i++ {
+			if _, err := fmt.Fprint(w, chunk); err != nil {
+				return
+			}
+		}
+	}))
+	defer ts.Close()
+
+	var buf bytes.Buffer
+	if err := DownloadFile(context.TODO(), &Request{URL: ts.URL}, &buf, 64*1024); err != nil || buf.Len() != 64*1024 {
+		t.Errorf("Failed to download a file within the limit: %v", err)
+	}
+
+	buf.Reset()
+	if err := DownloadFile(context.TODO(), &Request{URL: ts.URL}, &buf, 16*1024); !errors.Is(err, ErrBodyTooLarge) {
+		t.Errorf("Failed to detect the file exceeding the limit: %v", err)
+	}
+
+	buf.Reset()
+	if err := DownloadFile(context.TODO(), &Request{URL: ts.URL + "/missing"}, &buf, 0); err == nil {
+		t.Error("Failed to return an error for the unsuccessful status code")
+	}
+}
+
 func TestCrawl(t *testing.T) {
 	re, err := regexp.Compile(amassdns.AnySubdomainRegexString())
 	if err != nil {
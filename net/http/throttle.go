@@ -0,0 +1,71 @@
+// Copyright © by Jeff Foley 2017-2023. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+// SPDX-License-Identifier: Apache-2.0
+
+package http
+
+import (
+	"context"
+	"io"
+	"sync"
+
+	"golang.org/x/time/rate"
+)
+
+var (
+	downloadLock    sync.Mutex
+	downloadLimiter *rate.Limiter
+)
+
+// SetDownloadRate limits the number of response body bytes read per second across all the requests.
+// A rate less than or equal to zero removes the limit.
+func SetDownloadRate(bytesPerSec int) {
+	downloadLock.Lock()
+	defer downloadLock.Unlock()
+
+	if bytesPerSec <= 0 {
+		downloadLimiter = nil
+		return
+	}
+	// The small burst keeps the reads spread across the second
+	burst := bytesPerSec / 10
+	if burst < 1 {
+		burst = 1
+	}
+	downloadLimiter = rate.NewLimiter(rate.Limit(bytesPerSec), burst)
+}
+
+func currentDownloadLimiter() *rate.Limiter {
+	downloadLock.Lock()
+	defer downloadLock.Unlock()
+
+	return downloadLimiter
+}
+
+type throttledReader struct {
+	io.ReadCloser
+	ctx context.Context
+	lim *rate.Limiter
+}
+
+// throttle returns the body wrapped so that reads consume from the download rate limit, when one was set.
+func throttle(ctx context.Context, body io.ReadCloser) io.ReadCloser {
+	if lim := currentDownloadLimiter(); lim != nil {
+		return &throttledReader{ReadCloser: body, ctx: ctx, lim: lim}
+	}
+	return body
+}
+
+func (t *throttledReader) Read(p []byte) (int, error) {
+	if burst := t.lim.Burst(); len(p) > burst {
+		p = p[:burst]
+	}
+
+	n, err := t.ReadCloser.Read(p)
+	if n > 0 {
+		if werr := t.lim.WaitN(t.ctx, n); werr != nil && err == nil {
+			err = werr
+		}
+	}
+	return n, err
+}
@@ -0,0 +1,80 @@
+// Copyright © by Jeff Foley 2017-2023. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+// SPDX-License-Identifier: Apache-2.0
+
+package http
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestDownloadRate(t *testing.T) {
+	page := bytes.Repeat([]byte("a"), 512*1024)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write(page)
+	}))
+	defer ts.Close()
+
+	rate := 1024 * 1024
+	SetDownloadRate(rate)
+	defer SetDownloadRate(0)
+
+	downloads := 4
+	var wg sync.WaitGroup
+	errs := make(chan error, downloads)
+	start := time.Now()
+	for i := 0; i < downloads; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+
+			resp, err := RequestWebPage(context.Background(), &Request{URL: fmt.Sprintf("%s/%d", ts.URL, i)})
+			if err == nil && len(resp.Body) != len(page) {
+				err = fmt.Errorf("download %d only provided %d bytes", i, len(resp.Body))
+			}
+			errs <- err
+		}(i)
+	}
+	wg.Wait()
+	close(errs)
+	elapsed := time.Since(start)
+
+	for err := range errs {
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	// Only the burst of the limiter can be read without waiting
+	total := downloads * len(page)
+	if min := time.Duration(float64(total-rate/10) / float64(rate) * float64(time.Second)); elapsed < min {
+		t.Errorf("the %d bytes were downloaded in %v, which exceeds the rate of %d bytes per second", total, elapsed, rate)
+	}
+}
+
+func TestDownloadRateCancelled(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write(bytes.Repeat([]byte("a"), 64*1024))
+	}))
+	defer ts.Close()
+
+	SetDownloadRate(1024)
+	defer SetDownloadRate(0)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 500*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	if _, err := RequestWebPage(ctx, &Request{URL: ts.URL}); err == nil {
+		t.Error("the download completed faster than the rate allows")
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("the cancelled download kept waiting for %v", elapsed)
+	}
+}
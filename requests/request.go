@@ -6,7 +6,6 @@ package requests
 
 import (
 	"net"
-	"strings"
 	"time"
 
 	"github.com/caffix/pipeline"
@@ -33,6 +32,8 @@ type DNSAnswer struct {
 	Type int    `json:"type"`
 	TTL  int    `json:"TTL"`
 	Data string `json:"data"`
+	// The data source that provided the record, which is empty for the records obtained through DNS
+	Source string `json:"source,omitempty"`
 }
 
 // DNSRequest handles data needed throughout Service processing of a DNS name.
@@ -40,6 +41,8 @@ type DNSRequest struct {
 	Name    string
 	Domain  string
 	Records []DNSAnswer
+	// Tag describes how the data source discovered the name, such as only on expired certificates
+	Tag string
 }
 
 // Clone implements pipeline Data.
@@ -48,6 +51,7 @@ func (d *DNSRequest) Clone() pipeline.Data {
 		Name:    d.Name,
 		Domain:  d.Domain,
 		Records: append([]DNSAnswer(nil), d.Records...),
+		Tag:     d.Tag,
 	}
 }
 
@@ -289,13 +293,14 @@ type AddressInfo struct {
 }
 
 // SanitizeDNSRequest cleans the Name and Domain elements of the receiver.
-func SanitizeDNSRequest(req *DNSRequest) {
-	req.Name = strings.ToLower(req.Name)
-	req.Name = strings.TrimSpace(req.Name)
-	req.Name = amassdns.RemoveAsteriskLabel(req.Name)
-	req.Name = strings.Trim(req.Name, ".")
-
-	req.Domain = strings.ToLower(req.Domain)
-	req.Domain = strings.TrimSpace(req.Domain)
-	req.Domain = strings.Trim(req.Domain, ".")
+// An error is returned when either element is not a valid hostname after the cleanup.
+func SanitizeDNSRequest(req *DNSRequest) error {
+	var nerr, derr error
+
+	req.Name, nerr = amassdns.NormalizeFQDN(req.Name)
+	req.Domain, derr = amassdns.NormalizeFQDN(req.Domain)
+	if nerr != nil {
+		return nerr
+	}
+	return derr
 }
@@ -320,12 +320,16 @@ func TestSanitizeDNSRequest(t *testing.T) {
 	}
 	for _, test := range tests {
 		t.Run(test.name, func(t *testing.T) {
-			SanitizeDNSRequest(&test.req)
+			require.NoError(t, SanitizeDNSRequest(&test.req))
 			require.Equal(t, "example.com", test.req.Name)
 			require.Equal(t, "example.com", test.req.Domain)
 		})
 	}
 
+	req := DNSRequest{Name: " Bad_.Example.com", Domain: "Example.com"}
+	require.Error(t, SanitizeDNSRequest(&req))
+	require.Equal(t, "bad_.example.com", req.Name)
+	require.Equal(t, "example.com", req.Domain)
 }
 
 func TestASNRequestClone(t *testing.T) {
@@ -18,12 +18,21 @@ import (
 	"github.com/caffix/netmap"
 	"github.com/caffix/service"
 	amassnet "github.com/owasp-amass/amass/v4/net"
+	amasshttp "github.com/owasp-amass/amass/v4/net/http"
 	"github.com/owasp-amass/amass/v4/requests"
 	"github.com/owasp-amass/amass/v4/resources"
 	"github.com/owasp-amass/config/config"
 	"github.com/owasp-amass/resolve"
 )
 
+// Maximum time that the shutdown waits for the data sources to stop
+const stopTimeout = 10 * time.Second
+
+// stopDeadliner is implemented by the data sources that wait for their work to end when stopped.
+type stopDeadliner interface {
+	SetStopDeadline(deadline time.Time)
+}
+
 // LocalSystem implements a System to be executed within a single process.
 type LocalSystem struct {
 	Cfg               *config.Config
@@ -31,6 +40,7 @@ type LocalSystem struct {
 	trusted           *resolve.Resolvers
 	graphs            []*netmap.Graph
 	cache             *requests.ASNCache
+	hosts             *amasshttp.HostLimiters
 	done              chan struct{}
 	doneAlreadyClosed bool
 	addSource         chan service.Service
@@ -67,6 +77,7 @@ func NewLocalSystem(cfg *config.Config) (*LocalSystem, error) {
 		pool:       pool,
 		trusted:    trusted,
 		cache:      requests.NewASNCache(),
+		hosts:      amasshttp.NewHostLimiters(),
 		done:       make(chan struct{}, 2),
 		addSource:  make(chan service.Service),
 		allSources: make(chan chan []service.Service, 10),
@@ -112,6 +123,11 @@ func (l *LocalSystem) Cache() *requests.ASNCache {
 	return l.cache
 }
 
+// HostLimiters implements the System interface.
+func (l *LocalSystem) HostLimiters() *amasshttp.HostLimiters {
+	return l.hosts
+}
+
 // AddSource implements the System interface.
 func (l *LocalSystem) AddSource(src service.Service) error {
 	l.addSource <- src
@@ -174,8 +190,14 @@ func (l *LocalSystem) Shutdown() error {
 	}
 	l.doneAlreadyClosed = true
 
+	// The data sources are stopped in parallel and share a single deadline
+	deadline := time.Now().Add(stopTimeout)
+
 	var wg sync.WaitGroup
 	for _, src := range l.DataSources() {
+		if sd, ok := src.(stopDeadliner); ok {
+			sd.SetStopDeadline(deadline)
+		}
 		wg.Add(1)
 
 		go func(s service.Service, w *sync.WaitGroup) {
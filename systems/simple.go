@@ -9,6 +9,7 @@ import (
 
 	"github.com/caffix/netmap"
 	"github.com/caffix/service"
+	amasshttp "github.com/owasp-amass/amass/v4/net/http"
 	"github.com/owasp-amass/amass/v4/requests"
 	"github.com/owasp-amass/config/config"
 	"github.com/owasp-amass/resolve"
@@ -20,6 +21,7 @@ type SimpleSystem struct {
 	Trusted  *resolve.Resolvers
 	Graph    *netmap.Graph
 	ASNCache *requests.ASNCache
+	Hosts    *amasshttp.HostLimiters
 	Service  service.Service
 }
 
@@ -35,6 +37,9 @@ func (ss *SimpleSystem) TrustedResolvers() *resolve.Resolvers { return ss.Truste
 // Cache implements the System interface.
 func (ss *SimpleSystem) Cache() *requests.ASNCache { return ss.ASNCache }
 
+// HostLimiters implements the System interface.
+func (ss *SimpleSystem) HostLimiters() *amasshttp.HostLimiters { return ss.Hosts }
+
 // AddSource implements the System interface.
 func (ss *SimpleSystem) AddSource(src service.Service) error { ss.Service = src; return nil }
 
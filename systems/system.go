@@ -10,6 +10,7 @@ import (
 
 	"github.com/caffix/netmap"
 	"github.com/caffix/service"
+	amasshttp "github.com/owasp-amass/amass/v4/net/http"
 	"github.com/owasp-amass/amass/v4/requests"
 	"github.com/owasp-amass/config/config"
 	"github.com/owasp-amass/resolve"
@@ -29,6 +30,9 @@ type System interface {
 	// Returns the cache populated by the system
 	Cache() *requests.ASNCache
 
+	// Returns the limiters shared by the data sources sending requests to the same host
+	HostLimiters() *amasshttp.HostLimiters
+
 	// AddSource appends the provided data source to the slice of sources managed by the System
 	AddSource(srv service.Service) error
 